@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/sevigo/shugosha/pkg/catalog"
+	"github.com/sevigo/shugosha/pkg/db"
+	"github.com/sevigo/shugosha/pkg/migrate"
+)
+
+// importCatalog rebuilds a fresh catalog from a catalog export, e.g. one
+// downloaded from a provider after the original machine was lost.
+func importCatalog(opts *Options) error {
+	file, err := os.Open(opts.Import)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	storage, err := db.Open(opts.DBBackend, opts.DBPath)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer storage.Close()
+
+	if err := catalog.Import(storage, file); err != nil {
+		return err
+	}
+
+	if err := migrate.Run(storage, ""); err != nil {
+		return fmt.Errorf("failed to migrate imported catalog: %w", err)
+	}
+
+	slog.Info("Catalog imported", "file", opts.Import, "db", opts.DBPath)
+	return nil
+}
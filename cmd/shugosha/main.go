@@ -8,6 +8,7 @@ import (
 	"os/signal"
 	"syscall"
 
+	"github.com/sevigo/shugosha/pkg/api"
 	"github.com/sevigo/shugosha/pkg/backupmanager"
 	"github.com/sevigo/shugosha/pkg/logger"
 )
@@ -16,9 +17,24 @@ const version = 0.3
 
 func main() {
 	logger.Setup()
+
+	opts, err := parseOptions()
+	if err != nil {
+		slog.Error("Invalid options", "error", err)
+		os.Exit(2)
+	}
+
+	if opts.Import != "" {
+		if err := importCatalog(opts); err != nil {
+			slog.Error("Failed to import catalog", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	slog.Info("Starting 「Shugosha」 service", "version", version)
 
-	app, err := InitializeApp()
+	app, err := InitializeApp(opts)
 	if err != nil {
 		slog.Error("Failed to initialize application", "error", err)
 		return
@@ -38,13 +54,29 @@ func main() {
 		}
 	}()
 
+	// Check provider health, resuming paused backups once a provider recovers
+	go app.Health.Run(ctx)
+
+	// Reload the config file when it changes, if it manages the configuration
+	go func() {
+		if err := app.ConfigManager.Watch(ctx); err != nil {
+			slog.Error("Failed to watch config file", "error", err)
+		}
+	}()
+
 	// Process backup results
 	go processBackupResults(ctx, app.BackupManager)
 
 	// Start the API server with context
 	go func() {
-		log.Println("Starting API server on port 8080...")
-		if err := app.Server.Start(ctx, ":8080"); err != nil {
+		log.Printf("Starting API server on %s...", opts.Listen)
+		listen := api.ListenConfig{
+			Address:    opts.Listen,
+			CertFile:   opts.TLSCert,
+			KeyFile:    opts.TLSKey,
+			SelfSigned: opts.SelfSigned,
+		}
+		if err := app.Server.Start(ctx, listen); err != nil {
 			slog.Error("Failed to start API server", "error", err)
 			return
 		}
@@ -61,9 +93,16 @@ func processBackupResults(ctx context.Context, manager *backupmanager.BackupMana
 	for {
 		select {
 		case result := <-manager.Results():
-			if result.Status == "Failed" {
+			switch result.Status {
+			case "Failed":
 				log.Printf("Backup failed for %s: %v", result.Path, result.Error)
-			} else {
+			case "Deferred":
+				log.Printf("Backup of %s deferred: %v", result.Path, result.Error)
+			case "Paused":
+				log.Printf("Backup of %s paused: %v", result.Path, result.Error)
+			case "Inconsistent":
+				log.Printf("Backup of %s is inconsistent after %d attempts: %v", result.Path, result.Attempts, result.Error)
+			default:
 				log.Printf("Backup successful for %s", result.Path)
 			}
 		case <-ctx.Done():
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sevigo/shugosha/pkg/config"
+)
+
+// legacyDBPath is the catalog directory used by earlier versions, relative to
+// the working directory.
+const legacyDBPath = ".db/"
+
+// Options holds the command line options.
+type Options struct {
+	Config     string // Config file, searched in the default locations if empty
+	ConfigMode string // Whether the config file or the API manages the configuration
+	DBBackend  string // Catalog backend: badger, bolt, sqlite or memory
+	DBPath     string // Directory of the catalog
+	BackupDir  string // Directory of database backups and catalog exports
+	Listen     string // Address of the API server, or unix:/path for a Unix socket
+	TLSCert    string // Certificate of the API server, TLS is enabled if set
+	TLSKey     string // Private key of the certificate
+	SelfSigned bool   // Generate a self-signed certificate if TLSCert does not exist
+	Import     string // Catalog export to import into an empty catalog before exiting
+	Keystore   string // Encrypted keystore holding the secrets referred to as keystore:NAME
+
+	// KeystorePassphrase unlocks the keystore, it stays locked if empty
+	KeystorePassphrase string
+}
+
+// parseOptions reads the command line flags, falling back to environment
+// variables for anything not given on the command line.
+func parseOptions() (*Options, error) {
+	opts := &Options{}
+	flag.StringVar(&opts.Config, "config", envOr(config.EnvConfig, ""), "config file, defaults to the first one found in the user and system config directories")
+	flag.StringVar(&opts.ConfigMode, "config-mode", envOr("SHUGOSHA_CONFIG_MODE", string(config.ModeAPI)), "api to manage the configuration through the API, file to reload it from the config file; provider and directory changes need a restart in both")
+	flag.StringVar(&opts.DBBackend, "db-backend", envOr("SHUGOSHA_DB_BACKEND", "badger"), "catalog backend: badger, bolt, sqlite or memory")
+	flag.StringVar(&opts.DBPath, "db-path", envOr("SHUGOSHA_DB_PATH", ""), "directory of the catalog, defaults to the user data directory")
+	flag.StringVar(&opts.BackupDir, "backup-dir", envOr("SHUGOSHA_BACKUP_DIR", ""), "directory of database backups and catalog exports, defaults to backups next to the catalog directory")
+	flag.StringVar(&opts.Listen, "listen", envOr("SHUGOSHA_LISTEN", "127.0.0.1:8080"), "address of the API server, e.g. :8080 for all interfaces or unix:/run/shugosha.sock")
+	flag.StringVar(&opts.TLSCert, "tls-cert", envOr("SHUGOSHA_TLS_CERT", ""), "PEM certificate of the API server, enables TLS")
+	flag.StringVar(&opts.TLSKey, "tls-key", envOr("SHUGOSHA_TLS_KEY", ""), "PEM private key of the TLS certificate")
+	flag.BoolVar(&opts.SelfSigned, "tls-self-signed", envOr("SHUGOSHA_TLS_SELF_SIGNED", "") == "true", "serve TLS with a self-signed certificate, created in the user data directory unless -tls-cert is given")
+	flag.StringVar(&opts.Import, "import", "", "import a catalog export into an empty catalog and exit")
+	flag.StringVar(&opts.Keystore, "keystore", envOr("SHUGOSHA_KEYSTORE", ""), "encrypted keystore file, defaults to keystore.json in the user data directory")
+	passphraseFile := flag.String("keystore-passphrase-file", envOr("SHUGOSHA_KEYSTORE_PASSPHRASE_FILE", ""), "file holding the master passphrase of the keystore, instead of SHUGOSHA_KEYSTORE_PASSPHRASE")
+	flag.Parse()
+
+	opts.KeystorePassphrase = os.Getenv("SHUGOSHA_KEYSTORE_PASSPHRASE")
+	if *passphraseFile != "" {
+		data, err := os.ReadFile(*passphraseFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read keystore passphrase: %w", err)
+		}
+		opts.KeystorePassphrase = strings.TrimRight(string(data), "\r\n")
+	}
+	// Keep the passphrase from child processes
+	os.Unsetenv("SHUGOSHA_KEYSTORE_PASSPHRASE")
+
+	switch config.Mode(opts.ConfigMode) {
+	case config.ModeAPI, config.ModeFile:
+	default:
+		return nil, fmt.Errorf("unknown config mode %q, use api or file", opts.ConfigMode)
+	}
+
+	if opts.SelfSigned && opts.TLSCert == "" {
+		dir, err := config.DataDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine the certificate location, use -tls-cert: %w", err)
+		}
+		opts.TLSCert = filepath.Join(dir, "tls", "cert.pem")
+		opts.TLSKey = filepath.Join(dir, "tls", "key.pem")
+	}
+	if (opts.TLSCert == "") != (opts.TLSKey == "") {
+		return nil, fmt.Errorf("-tls-cert and -tls-key must be given together")
+	}
+
+	if opts.Keystore == "" {
+		dir, err := config.DataDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine the keystore location, use -keystore: %w", err)
+		}
+		opts.Keystore = filepath.Join(dir, "keystore.json")
+	}
+
+	if opts.DBPath == "" {
+		path, err := defaultDBPath()
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine the catalog directory, use -db-path: %w", err)
+		}
+		opts.DBPath = path
+	}
+	if opts.BackupDir == "" {
+		// Kept out of the catalog directory, which belongs to the database
+		opts.BackupDir = filepath.Join(filepath.Dir(filepath.Clean(opts.DBPath)), "backups")
+	}
+
+	return opts, nil
+}
+
+// defaultDBPath keeps using the catalog of earlier versions if there is one
+// in the working directory, and the data directory otherwise.
+func defaultDBPath() (string, error) {
+	if info, err := os.Stat(legacyDBPath); err == nil && info.IsDir() {
+		return legacyDBPath, nil
+	}
+
+	dir, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "db"), nil
+}
+
+func envOr(name, fallback string) string {
+	if value, ok := os.LookupEnv(name); ok && value != "" {
+		return value
+	}
+	return fallback
+}
@@ -5,16 +5,26 @@ package main
 
 import (
 	"fmt"
+	"log/slog"
 
 	"github.com/google/wire"
 
+	"github.com/sevigo/shugosha/pkg/activity"
 	"github.com/sevigo/shugosha/pkg/api"
+	"github.com/sevigo/shugosha/pkg/auth"
 	"github.com/sevigo/shugosha/pkg/backupmanager"
+	"github.com/sevigo/shugosha/pkg/compliance"
 	"github.com/sevigo/shugosha/pkg/config"
 	"github.com/sevigo/shugosha/pkg/db"
 	"github.com/sevigo/shugosha/pkg/fsmonitor"
+	"github.com/sevigo/shugosha/pkg/health"
+	"github.com/sevigo/shugosha/pkg/metrics"
+	"github.com/sevigo/shugosha/pkg/migrate"
 	"github.com/sevigo/shugosha/pkg/model"
 	"github.com/sevigo/shugosha/pkg/provider"
+	"github.com/sevigo/shugosha/pkg/quota"
+	"github.com/sevigo/shugosha/pkg/secrets"
+	"github.com/sevigo/shugosha/pkg/throttle"
 )
 
 // App contains all dependencies of the application.
@@ -22,20 +32,22 @@ type App struct {
 	ConfigManager model.ConfigManager
 	BackupManager *backupmanager.BackupManager
 	Monitor       *fsmonitor.Monitor
+	Health        *health.Checker
 	Server        *api.Server
 }
 
 // NewApp creates a new instance of your application
-func NewApp(configManager model.ConfigManager, backupManager *backupmanager.BackupManager, monitor *fsmonitor.Monitor, server *api.Server) *App {
+func NewApp(configManager model.ConfigManager, backupManager *backupmanager.BackupManager, monitor *fsmonitor.Monitor, checker *health.Checker, server *api.Server) *App {
 	return &App{
 		ConfigManager: configManager,
 		BackupManager: backupManager,
 		Monitor:       monitor,
+		Health:        checker,
 		Server:        server,
 	}
 }
 
-func InitializeApp() (*App, error) {
+func InitializeApp(opts *Options) (*App, error) {
 	wire.Build(
 		NewApp,
 		configManagerProvider,
@@ -46,6 +58,26 @@ func InitializeApp() (*App, error) {
 		dbProvider,
 		backupConfigProvider,
 		providerMetaInfoGetterProvider,
+		restoreManagerProvider,
+		throttleManagerProvider,
+		throttleControllerProvider,
+		fileSearcherProvider,
+		catalogRebuilderProvider,
+		verifierProvider,
+		complianceCheckerProvider,
+		health.NewChecker,
+		healthReporterProvider,
+		quotaManagerProvider,
+		configValidatorProvider,
+		configHistoryProvider,
+		keystoreProvider,
+		secretResolverProvider,
+		secretStoreProvider,
+		configRedactorProvider,
+		authenticatorProvider,
+		activityBrokerProvider,
+		activityStreamProvider,
+		serviceHealthProvider,
 	)
 	return &App{}, nil
 }
@@ -58,28 +90,82 @@ func fsMonitorProvider() (*fsmonitor.Monitor, error) {
 	return monitor, nil
 }
 
-func backupManagerProvider(storage model.DB, monitor *fsmonitor.Monitor, providers map[string]model.Provider) (*backupmanager.BackupManager, error) {
-	backupManager, err := backupmanager.NewBackupManager(storage, monitor, providers)
+func backupManagerProvider(opts *Options, storage model.DB, monitor *fsmonitor.Monitor, providers map[string]model.Provider, throttles *throttle.Manager, checker *health.Checker, quotas *quota.Manager, broker *activity.Broker) (*backupmanager.BackupManager, error) {
+	backupManager, err := backupmanager.NewBackupManager(storage, monitor, providers, throttles, checker, quotas, broker)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create backup manager: %w", err)
 	}
+
+	if err := backupManager.EnableCatalogExport(opts.BackupDir); err != nil {
+		return nil, fmt.Errorf("failed to enable catalog export: %w", err)
+	}
+
+	metrics.Registry.MustRegister(metrics.NewCollector(backupManager, storage))
 	return backupManager, nil
 }
 
-func dbProvider() (model.DB, error) {
-	storage, err := db.NewBadgerDB(".db/")
+func dbProvider(opts *Options) (model.DB, error) {
+	storage, err := db.Open(opts.DBBackend, opts.DBPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
+
+	if err := migrate.Run(storage, opts.BackupDir); err != nil {
+		storage.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
 	return storage, nil
 }
 
-func apiServiceProvider(cm model.ConfigManager, g model.ProviderMetaInfoGetter) *api.Server {
-	return api.NewServer(cm, g)
+func apiServiceProvider(cm model.ConfigManager, g model.ProviderMetaInfoGetter, rm model.RestoreManager, tc model.ThrottleController, fs model.FileSearcher, cr model.CatalogRebuilder, v model.Verifier, cc model.ComplianceChecker, hr model.HealthReporter, cv model.ConfigValidator, ch model.ConfigHistory, rd model.ConfigRedactor, ss model.SecretStore, a *auth.Authenticator, as model.ActivityStream, sh model.ServiceHealthReporter) *api.Server {
+	return api.NewServer(api.Deps{
+		Config:        cm,
+		Providers:     g,
+		Restore:       rm,
+		Throttles:     tc,
+		Files:         fs,
+		Rebuilder:     cr,
+		Verifier:      v,
+		Compliance:    cc,
+		Health:        hr,
+		Validator:     cv,
+		History:       ch,
+		Redactor:      rd,
+		Secrets:       ss,
+		Auth:          a,
+		Activity:      as,
+		ServiceHealth: sh,
+	})
+}
+
+func activityBrokerProvider() *activity.Broker {
+	return activity.NewBroker(0)
+}
+
+func activityStreamProvider(broker *activity.Broker) model.ActivityStream {
+	return broker
 }
 
-func configManagerProvider(storage model.DB) (model.ConfigManager, error) {
-	configManager, err := config.NewConfigManager(storage)
+func authenticatorProvider(configManager model.ConfigManager, backupConfig *model.BackupConfig, resolver *secrets.Resolver) (*auth.Authenticator, error) {
+	authenticator, err := auth.NewAuthenticator(backupConfig, resolver)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup API authentication: %w", err)
+	}
+
+	configManager.OnChange(authenticator.ApplyConfig)
+	return authenticator, nil
+}
+
+func configValidatorProvider() model.ConfigValidator {
+	return config.NewValidator(provider.SettingsSchemas())
+}
+
+func configManagerProvider(opts *Options, storage model.DB, validator model.ConfigValidator) (model.ConfigManager, error) {
+	configManager, err := config.NewConfigManager(storage, config.Options{
+		Path:      opts.Config,
+		Mode:      config.Mode(opts.ConfigMode),
+		Validator: validator,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("Config manager initialization failed: %w", err)
 	}
@@ -87,14 +173,113 @@ func configManagerProvider(storage model.DB) (model.ConfigManager, error) {
 	return configManager, nil
 }
 
-func backupProviders(backupConfig *model.BackupConfig, monitor *fsmonitor.Monitor) map[string]model.Provider {
-	return provider.InitializeProviders(backupConfig, monitor)
+func backupProviders(backupConfig *model.BackupConfig, monitor *fsmonitor.Monitor, checker *health.Checker, resolver *secrets.Resolver) map[string]model.Provider {
+	return provider.InitializeProviders(backupConfig, monitor, checker, resolver)
 }
 
-func backupConfigProvider(configManager model.ConfigManager) (*model.BackupConfig, error) {
-	return configManager.LoadConfig()
+// keystoreProvider unlocks the keystore, which stays locked (nil) without a
+// passphrase. A wrong passphrase stops the start.
+func keystoreProvider(opts *Options) (*secrets.Keystore, error) {
+	if opts.KeystorePassphrase == "" {
+		slog.Info("No keystore passphrase set, keystore references cannot be resolved", "keystore", opts.Keystore)
+		return nil, nil
+	}
+
+	keystore, err := secrets.OpenKeystore(opts.Keystore, opts.KeystorePassphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unlock keystore: %w", err)
+	}
+	return keystore, nil
+}
+
+func secretResolverProvider(keystore *secrets.Keystore) *secrets.Resolver {
+	return secrets.NewResolver(keystore)
+}
+
+func secretStoreProvider(keystore *secrets.Keystore) model.SecretStore {
+	if keystore == nil {
+		return nil
+	}
+	return keystore
+}
+
+func configRedactorProvider() model.ConfigRedactor {
+	return secrets.NewRedactor(provider.SettingsSchemas())
+}
+
+func backupConfigProvider(configManager model.ConfigManager, validator model.ConfigValidator) (*model.BackupConfig, error) {
+	backupConfig, err := configManager.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// Configurations stored by earlier versions were never validated
+	if err := validator.ValidateConfig(backupConfig); err != nil {
+		slog.Warn("Stored configuration is invalid", "error", err)
+	}
+	return backupConfig, nil
 }
 
 func providerMetaInfoGetterProvider(bm *backupmanager.BackupManager) model.ProviderMetaInfoGetter {
 	return bm
 }
+
+func restoreManagerProvider(bm *backupmanager.BackupManager) model.RestoreManager {
+	return bm
+}
+
+func fileSearcherProvider(bm *backupmanager.BackupManager) model.FileSearcher {
+	return bm
+}
+
+func catalogRebuilderProvider(bm *backupmanager.BackupManager) model.CatalogRebuilder {
+	return bm
+}
+
+func verifierProvider(bm *backupmanager.BackupManager) model.Verifier {
+	return bm
+}
+
+func complianceCheckerProvider(cm model.ConfigManager, bm *backupmanager.BackupManager) model.ComplianceChecker {
+	return compliance.NewChecker(cm, bm)
+}
+
+func healthReporterProvider(checker *health.Checker) model.HealthReporter {
+	return checker
+}
+
+func serviceHealthProvider(monitor *fsmonitor.Monitor, storage model.DB, checker *health.Checker) model.ServiceHealthReporter {
+	return health.NewService(monitor, storage, checker)
+}
+
+func throttleManagerProvider(configManager model.ConfigManager, backupConfig *model.BackupConfig) (*throttle.Manager, error) {
+	throttles, err := throttle.NewManager(backupConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup throttling: %w", err)
+	}
+
+	configManager.OnChange(throttles.ApplyConfig)
+	return throttles, nil
+}
+
+func quotaManagerProvider(configManager model.ConfigManager, backupConfig *model.BackupConfig) (*quota.Manager, error) {
+	quotas, err := quota.NewManager(backupConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup quotas: %w", err)
+	}
+
+	configManager.OnChange(quotas.ApplyConfig)
+	return quotas, nil
+}
+
+func configHistoryProvider(configManager model.ConfigManager) (model.ConfigHistory, error) {
+	history, ok := configManager.(model.ConfigHistory)
+	if !ok {
+		return nil, fmt.Errorf("config manager does not keep a revision history")
+	}
+	return history, nil
+}
+
+func throttleControllerProvider(tm *throttle.Manager) model.ThrottleController {
+	return tm
+}
@@ -17,13 +17,13 @@ if "%1" == "init" (
 REM Run the project
 if "%1"=="run" (
     cd cmd/shugosha
-    go run main.go wire_gen.go
+    go run main.go options.go import.go wire_gen.go -config ..\..\config.win.json
     goto end
 )
 
 REM Build the project
 if "%1"=="build" (
-    %GO_CMD% build -o %BUILD_DIR%\%BINARY_NAME% .\cmd\%SERVICE_NAME%\main.go
+    %GO_CMD% build -o %BUILD_DIR%\%BINARY_NAME% .\cmd\%SERVICE_NAME%
     goto end
 )
 
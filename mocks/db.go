@@ -2,7 +2,10 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	model "github.com/sevigo/shugosha/pkg/model"
+	mock "github.com/stretchr/testify/mock"
+)
 
 // DB is an autogenerated mock type for the DB type
 type DB struct {
@@ -27,6 +30,24 @@ func (_m *DB) Close() error {
 	return r0
 }
 
+// Delete provides a mock function with given fields: key
+func (_m *DB) Delete(key string) error {
+	ret := _m.Called(key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Delete")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Get provides a mock function with given fields: key
 func (_m *DB) Get(key string) ([]byte, error) {
 	ret := _m.Called(key)
@@ -57,6 +78,42 @@ func (_m *DB) Get(key string) ([]byte, error) {
 	return r0, r1
 }
 
+// Iterate provides a mock function with given fields: prefix, fn
+func (_m *DB) Iterate(prefix string, fn func(string, []byte) error) error {
+	ret := _m.Called(prefix, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Iterate")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, func(string, []byte) error) error); ok {
+		r0 = rf(prefix, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// IterateFrom provides a mock function with given fields: prefix, start, fn
+func (_m *DB) IterateFrom(prefix string, start string, fn func(string, []byte) error) error {
+	ret := _m.Called(prefix, start, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IterateFrom")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, func(string, []byte) error) error); ok {
+		r0 = rf(prefix, start, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Set provides a mock function with given fields: key, value
 func (_m *DB) Set(key string, value []byte) error {
 	ret := _m.Called(key, value)
@@ -75,6 +132,60 @@ func (_m *DB) Set(key string, value []byte) error {
 	return r0
 }
 
+// Update provides a mock function with given fields: fn
+func (_m *DB) Update(fn func(model.Txn) error) error {
+	ret := _m.Called(fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Update")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(func(model.Txn) error) error); ok {
+		r0 = rf(fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// View provides a mock function with given fields: fn
+func (_m *DB) View(fn func(model.Txn) error) error {
+	ret := _m.Called(fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for View")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(func(model.Txn) error) error); ok {
+		r0 = rf(fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// WriteBatch provides a mock function with given fields: batch
+func (_m *DB) WriteBatch(batch *model.Batch) error {
+	ret := _m.Called(batch)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WriteBatch")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*model.Batch) error); ok {
+		r0 = rf(batch)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewDB creates a new instance of DB. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewDB(t interface {
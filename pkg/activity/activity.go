@@ -0,0 +1,181 @@
+// Package activity distributes backup activity events to API clients.
+package activity
+
+import (
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+const (
+	// defaultReplaySize is the number of recent events kept for reconnecting clients.
+	defaultReplaySize = 256
+	// subscriberBuffer is the number of events a subscriber may fall behind
+	// before it is disconnected. It can resume from the replay buffer.
+	subscriberBuffer = 64
+)
+
+// Ensure Broker satisfies the ActivityStream interface
+var _ model.ActivityStream = (*Broker)(nil)
+
+// Broker publishes activity events to all subscribers and keeps the most
+// recent ones for clients that reconnect. Progress events are frequent, so
+// only the latest of each upload is kept, outside of the replay buffer, and
+// it is dropped once the upload finished.
+type Broker struct {
+	mu          sync.Mutex
+	lastID      uint64
+	replay      []model.ActivityEvent          // Ring buffer of the most recent events
+	next        int                            // Position of the next event in replay
+	progress    map[string]model.ActivityEvent // Latest progress by provider and path
+	subscribers map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	filter model.ActivityFilter
+	events chan model.ActivityEvent
+}
+
+// NewBroker creates a broker keeping the last replaySize events, or a
+// default number if replaySize is 0.
+func NewBroker(replaySize int) *Broker {
+	if replaySize <= 0 {
+		replaySize = defaultReplaySize
+	}
+	return &Broker{
+		replay:      make([]model.ActivityEvent, 0, replaySize),
+		progress:    map[string]model.ActivityEvent{},
+		subscribers: map[*subscriber]struct{}{},
+	}
+}
+
+// Publish assigns the event an ID and delivers it to all matching
+// subscribers. A nil Broker drops the event.
+func (b *Broker) Publish(event model.ActivityEvent) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event.ID = b.lastID
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.keep(event)
+
+	for sub := range b.subscribers {
+		if !matches(sub.filter, event) {
+			continue
+		}
+
+		select {
+		case sub.events <- event:
+		default:
+			slog.Debug("[activity] subscriber fell behind, disconnecting")
+			b.remove(sub)
+		}
+	}
+}
+
+// Subscribe registers a subscriber, returning the buffered events after lastID.
+func (b *Broker) Subscribe(filter model.ActivityFilter, lastID uint64) ([]model.ActivityEvent, <-chan model.ActivityEvent, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var replay []model.ActivityEvent
+	for i := 0; i < len(b.replay); i++ {
+		// Oldest first, starting at the position that is overwritten next
+		event := b.replay[(b.next+i)%len(b.replay)]
+		if event.ID > lastID && matches(filter, event) {
+			replay = append(replay, event)
+		}
+	}
+	for _, event := range b.progress {
+		if event.ID > lastID && matches(filter, event) {
+			replay = append(replay, event)
+		}
+	}
+	sort.Slice(replay, func(i, j int) bool { return replay[i].ID < replay[j].ID })
+
+	sub := &subscriber{filter: filter, events: make(chan model.ActivityEvent, subscriberBuffer)}
+	b.subscribers[sub] = struct{}{}
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.remove(sub)
+	}
+	return replay, sub.events, cancel
+}
+
+// keep stores the event for replay. Progress replaces the previous progress
+// of the same upload instead of filling the replay buffer.
+func (b *Broker) keep(event model.ActivityEvent) {
+	key := event.Provider + "\x00" + event.Path
+	switch event.Type {
+	case model.ActivityProgress:
+		b.progress[key] = event
+		return
+	case model.ActivitySucceeded, model.ActivityFailed, model.ActivityPaused:
+		delete(b.progress, key)
+	}
+
+	if len(b.replay) < cap(b.replay) {
+		b.replay = append(b.replay, event)
+	} else {
+		b.replay[b.next] = event
+	}
+	b.next = (b.next + 1) % cap(b.replay)
+}
+
+func (b *Broker) remove(sub *subscriber) {
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+func matches(filter model.ActivityFilter, event model.ActivityEvent) bool {
+	if len(filter.Providers) > 0 && !contains(filter.Providers, event.Provider) {
+		return false
+	}
+	if len(filter.Types) > 0 && !contains(filter.Types, event.Type) {
+		return false
+	}
+	if len(filter.Roots) > 0 {
+		for _, root := range filter.Roots {
+			if isUnder(event.Path, root) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func contains[T comparable](list []T, value T) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// isUnder reports whether path is root or inside it.
+func isUnder(path, root string) bool {
+	root = filepath.Clean(root)
+	if path == root {
+		return true
+	}
+	return strings.HasPrefix(path, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator))
+}
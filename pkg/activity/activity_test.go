@@ -0,0 +1,77 @@
+package activity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+func TestReplayAndFilter(t *testing.T) {
+	broker := NewBroker(3)
+	for _, path := range []string{"/a/1", "/b/2", "/a/3", "/a/4"} {
+		broker.Publish(model.ActivityEvent{Type: model.ActivityDetected, Provider: "Local", Path: path})
+	}
+
+	// The oldest event dropped out of the replay buffer
+	replay, _, cancel := broker.Subscribe(model.ActivityFilter{}, 0)
+	defer cancel()
+	assert.Equal(t, []uint64{2, 3, 4}, ids(replay))
+
+	replay, events, cancel := broker.Subscribe(model.ActivityFilter{Roots: []string{"/a"}}, 3)
+	defer cancel()
+	assert.Equal(t, []uint64{4}, ids(replay))
+
+	broker.Publish(model.ActivityEvent{Type: model.ActivityStarted, Provider: "Local", Path: "/b/5"})
+	broker.Publish(model.ActivityEvent{Type: model.ActivityStarted, Provider: "Local", Path: "/a/6"})
+	event := <-events
+	assert.Equal(t, uint64(6), event.ID)
+	assert.Equal(t, "/a/6", event.Path)
+}
+
+func TestProgressIsCoalesced(t *testing.T) {
+	broker := NewBroker(3)
+	broker.Publish(model.ActivityEvent{Type: model.ActivityStarted, Provider: "Local", Path: "/a/1"})
+	broker.Publish(model.ActivityEvent{Type: model.ActivityStarted, Provider: "Local", Path: "/a/2"})
+	for i := 0; i < 10; i++ {
+		broker.Publish(model.ActivityEvent{Type: model.ActivityProgress, Provider: "Local", Path: "/a/1"})
+		broker.Publish(model.ActivityEvent{Type: model.ActivityProgress, Provider: "Local", Path: "/a/2"})
+	}
+
+	// Only the latest progress of each upload is replayed, the other events
+	// stay in the buffer
+	replay, _, cancel := broker.Subscribe(model.ActivityFilter{}, 0)
+	defer cancel()
+	assert.Equal(t, []uint64{1, 2, 21, 22}, ids(replay))
+
+	broker.Publish(model.ActivityEvent{Type: model.ActivitySucceeded, Provider: "Local", Path: "/a/1"})
+	replay, _, cancel = broker.Subscribe(model.ActivityFilter{}, 2)
+	defer cancel()
+	assert.Equal(t, []uint64{22, 23}, ids(replay))
+}
+
+func TestSlowSubscriberIsDisconnected(t *testing.T) {
+	broker := NewBroker(0)
+	_, events, cancel := broker.Subscribe(model.ActivityFilter{Providers: []string{"Local"}}, 0)
+	defer cancel()
+
+	for i := 0; i < subscriberBuffer+1; i++ {
+		broker.Publish(model.ActivityEvent{Type: model.ActivityProgress, Provider: "Local"})
+		broker.Publish(model.ActivityEvent{Type: model.ActivityProgress, Provider: "Other"})
+	}
+
+	received := 0
+	for range events {
+		received++
+	}
+	assert.Equal(t, subscriberBuffer, received)
+}
+
+func ids(events []model.ActivityEvent) []uint64 {
+	result := []uint64{}
+	for _, event := range events {
+		result = append(result, event.ID)
+	}
+	return result
+}
@@ -1,31 +1,59 @@
 package api
 
 import (
-	"context"
-	"net/http"
-
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 
+	"github.com/sevigo/shugosha/pkg/api/compliance"
 	"github.com/sevigo/shugosha/pkg/api/config"
+	"github.com/sevigo/shugosha/pkg/api/events"
+	"github.com/sevigo/shugosha/pkg/api/files"
+	"github.com/sevigo/shugosha/pkg/api/health"
+	"github.com/sevigo/shugosha/pkg/api/openapi"
 	"github.com/sevigo/shugosha/pkg/api/provider"
+	"github.com/sevigo/shugosha/pkg/api/restore"
+	"github.com/sevigo/shugosha/pkg/api/secrets"
+	"github.com/sevigo/shugosha/pkg/api/throttle"
+	"github.com/sevigo/shugosha/pkg/api/verify"
+	"github.com/sevigo/shugosha/pkg/auth"
+	"github.com/sevigo/shugosha/pkg/metrics"
 	"github.com/sevigo/shugosha/pkg/model"
 )
 
+// Deps are the services the API server exposes. Services a test does not
+// use may be left nil, apart from Auth, Validator and ServiceHealth, which
+// every server needs.
+type Deps struct {
+	Config        model.ConfigManager
+	Providers     model.ProviderMetaInfoGetter
+	Restore       model.RestoreManager
+	Throttles     model.ThrottleController
+	Files         model.FileSearcher
+	Rebuilder     model.CatalogRebuilder
+	Verifier      model.Verifier
+	Compliance    model.ComplianceChecker
+	Health        model.HealthReporter
+	Validator     model.ConfigValidator
+	History       model.ConfigHistory
+	Redactor      model.ConfigRedactor
+	Secrets       model.SecretStore
+	Auth          *auth.Authenticator
+	Activity      model.ActivityStream
+	ServiceHealth model.ServiceHealthReporter
+}
+
 // Server represents the API server.
 type Server struct {
-	providerManger model.ProviderMetaInfoGetter
-	configManager  model.ConfigManager
-	router         *chi.Mux
+	deps   Deps
+	router *chi.Mux
 }
 
 // NewServer creates a new API server.
-func NewServer(cm model.ConfigManager, pm model.ProviderMetaInfoGetter) *Server {
+func NewServer(deps Deps) *Server {
 	s := &Server{
-		providerManger: pm,
-		configManager:  cm,
-		router:         chi.NewRouter(),
+		deps:   deps,
+		router: chi.NewRouter(),
 	}
 
 	s.routes()
@@ -38,26 +66,64 @@ func (s *Server) routes() {
 	s.router.Use(middleware.Logger)
 	s.router.Use(middleware.Recoverer)
 
-	// Basic CORS
+	// Cross-origin requests are only allowed from the origins in the api
+	// section of the configuration.
 	// for more ideas, see: https://developer.github.com/v3/#cross-origin-resource-sharing
 	s.router.Use(cors.Handler(cors.Options{
-		AllowedOrigins: []string{"*"},
-		// AllowOriginFunc:  func(r *http.Request, origin string) bool { return true },
+		AllowOriginFunc:  s.deps.Auth.AllowOrigin,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: false,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	}))
+	s.router.Use(s.deps.Auth.Middleware)
+
+	// Parameters and bodies are checked against the OpenAPI document before
+	// they reach the handlers.
+	operations := openapi.Operations(s.deps.Validator.ConfigSchema())
+	s.router.Use(openapi.NewValidator(s.router, operations).Middleware)
 
-	configHandler := config.NewConfigHandler(s.configManager)
+	s.router.Get("/api/openapi.json", openapi.NewDocumentHandler(openapi.Document(operations)))
+
+	configHandler := config.NewConfigHandler(s.deps.Config, s.deps.Validator, s.deps.Redactor)
 
 	s.router.Get("/api/config", configHandler.ReadConfigHandler)
 	s.router.Post("/api/config", configHandler.UpdateConfigHandler)
-	s.router.Get("/api/providers", provider.NewProviderInfoHandler(s.providerManger))
-}
+	s.router.Get("/api/config/schema", configHandler.ReadSchemaHandler)
+
+	historyHandler := config.NewHistoryHandler(s.deps.History, s.deps.Redactor)
+
+	s.router.Get("/api/config/revisions", historyHandler.ReadRevisionsHandler)
+	s.router.Get("/api/config/revisions/{id}", historyHandler.ReadRevisionHandler)
+	s.router.Get("/api/config/revisions/{id}/diff", historyHandler.DiffRevisionHandler)
+	s.router.Post("/api/config/revisions/{id}/rollback", historyHandler.RollbackHandler)
+
+	s.router.Get("/api/providers", provider.NewProviderInfoHandler(s.deps.Providers, s.deps.Health))
+	s.router.Post("/api/providers/{provider}/rebuild", provider.NewRebuildHandler(s.deps.Rebuilder))
+	s.router.Get("/api/files", files.NewSearchHandler(s.deps.Files))
+	s.router.Post("/api/restore", restore.NewRestoreHandler(s.deps.Restore))
+	s.router.Get("/api/compliance", compliance.NewComplianceHandler(s.deps.Compliance))
+	s.router.Get("/api/events", events.NewEventsHandler(s.deps.Activity))
+
+	throttleHandler := throttle.NewThrottleHandler(s.deps.Throttles, s.deps.Config)
+
+	s.router.Get("/api/throttle", throttleHandler.ReadThrottlesHandler)
+	s.router.Put("/api/throttle/{provider}", throttleHandler.UpdateThrottleHandler)
+
+	secretsHandler := secrets.NewSecretsHandler(s.deps.Secrets)
+
+	s.router.Get("/api/secrets", secretsHandler.ReadSecretsHandler)
+	s.router.Put("/api/secrets/{name}", secretsHandler.UpdateSecretHandler)
+	s.router.Delete("/api/secrets/{name}", secretsHandler.DeleteSecretHandler)
+
+	verifyHandler := verify.NewVerifyHandler(s.deps.Verifier)
+
+	s.router.Get("/api/verify", verifyHandler.ReadReportsHandler)
+	s.router.Post("/api/verify", verifyHandler.StartVerifyHandler)
+
+	s.router.Get("/metrics", metrics.Handler().ServeHTTP)
 
-// Start starts the API server on the specified port.
-func (s *Server) Start(ctx context.Context, port string) error {
-	return http.ListenAndServe(port, s.router)
+	s.router.Get("/healthz", health.NewLivenessHandler(s.deps.ServiceHealth))
+	s.router.Get("/readyz", health.NewReadinessHandler(s.deps.ServiceHealth))
 }
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sevigo/shugosha/pkg/api/openapi"
+	"github.com/sevigo/shugosha/pkg/auth"
+	"github.com/sevigo/shugosha/pkg/config"
+	"github.com/sevigo/shugosha/pkg/db"
+	"github.com/sevigo/shugosha/pkg/health"
+	"github.com/sevigo/shugosha/pkg/model"
+	"github.com/sevigo/shugosha/pkg/secrets"
+)
+
+type testWatcher model.WatcherStatus
+
+func (w testWatcher) WatcherStatus() model.WatcherStatus {
+	return model.WatcherStatus(w)
+}
+
+// newTestServer creates a server with the services of deps, and the ones
+// every request uses if deps lacks them.
+func newTestServer(t *testing.T, deps Deps) *Server {
+	if deps.Validator == nil {
+		deps.Validator = config.NewValidator(nil)
+	}
+	if deps.Auth == nil {
+		authenticator, err := auth.NewAuthenticator(&model.BackupConfig{}, nil)
+		assert.NoError(t, err)
+		deps.Auth = authenticator
+	}
+	if deps.ServiceHealth == nil {
+		deps.ServiceHealth = health.NewService(testWatcher{Running: true}, db.NewMemoryDB(), health.NewChecker())
+	}
+	return NewServer(deps)
+}
+
+func TestRoutesAreDocumented(t *testing.T) {
+	s := newTestServer(t, Deps{})
+
+	documented := map[string]bool{}
+	for _, op := range openapi.Operations(s.deps.Validator.ConfigSchema()) {
+		documented[op.Method+" "+op.Path] = true
+	}
+
+	err := chi.Walk(s.router, func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		assert.True(t, documented[method+" "+route], "%s %s is missing in the OpenAPI document", method, route)
+		delete(documented, method+" "+route)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, documented, "documented operations without route")
+}
+
+func TestValidateRequests(t *testing.T) {
+	s := newTestServer(t, Deps{})
+
+	for _, test := range []struct {
+		method, target, body string
+		errors               []string
+	}{
+		{method: "GET", target: "/api/files?minSize=big&order=up&modifiedAfter=yesterday", errors: []string{"query.minSize", "query.modifiedAfter", "query.order"}},
+		{method: "GET", target: "/api/events?type=progress,stalled", errors: []string{"query.type"}},
+		{method: "GET", target: "/api/config/revisions/latest", errors: []string{"path.id"}},
+		{method: "POST", target: "/api/restore", body: `{"path":"/data/a.txt","target":1}`, errors: []string{"body", "body.target"}},
+		{method: "PUT", target: "/api/throttle/Local", body: `{"uploadBytesPerSec":"fast"}`, errors: []string{"body.uploadBytesPerSec"}},
+	} {
+		req := httptest.NewRequest(test.method, test.target, strings.NewReader(test.body))
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, test.target)
+		for _, field := range test.errors {
+			assert.Contains(t, rec.Body.String(), `"field":"`+field+`"`, test.target)
+		}
+	}
+}
+
+func TestUpdateConfigValidationErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"providers": [{"name": "Echo", "type": "Echo"}]}`), 0o600))
+
+	validator := config.NewValidator(map[string]model.SettingsSchema{"Echo": {}})
+	cm, err := config.NewConfigManager(db.NewMemoryDB(), config.Options{Path: path, Validator: validator})
+	assert.NoError(t, err)
+	s := newTestServer(t, Deps{Config: cm, Validator: validator, Redactor: secrets.NewRedactor(nil)})
+
+	// The handler validates the configuration, not the OpenAPI validator
+	body := `{"providers": [{"name": "Broken", "type": "Nope"}]}`
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/config", strings.NewReader(body)))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"field":"providers[0].type"`)
+}
+
+func TestHealth(t *testing.T) {
+	s := newTestServer(t, Deps{})
+
+	// Alive, but not ready before the initial scan finished
+	for target, code := range map[string]int{"/healthz": http.StatusOK, "/readyz": http.StatusServiceUnavailable} {
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, code, rec.Code, target)
+	}
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Contains(t, rec.Body.String(), `"initialScan":{"status":"degraded","class":"scanning","error":"initial scan in progress"}`)
+
+	// Without credentials only the status and class of the components are shown
+	authenticator, err := auth.NewAuthenticator(&model.BackupConfig{API: &model.APIConfig{
+		Tokens: []model.APIToken{{Name: "monitoring", Token: "read-token", Role: model.RoleRead}},
+	}}, secrets.NewResolver(nil))
+	assert.NoError(t, err)
+	s = newTestServer(t, Deps{Auth: authenticator})
+
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Contains(t, rec.Body.String(), `"initialScan":{"status":"degraded","class":"scanning"}`)
+	assert.NotContains(t, rec.Body.String(), `"details"`)
+
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	req.Header.Set("Authorization", "Bearer read-token")
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	assert.Contains(t, rec.Body.String(), `"error":"initial scan in progress"`)
+	assert.Contains(t, rec.Body.String(), `"details"`)
+}
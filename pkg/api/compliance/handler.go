@@ -0,0 +1,23 @@
+package compliance
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// NewComplianceHandler returns an HTTP handler function that reports the files
+// not meeting the replication rules.
+func NewComplianceHandler(checker model.ComplianceChecker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report, err := checker.Compliance()
+		if err != nil {
+			http.Error(w, "Failed to check compliance: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+	}
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/sevigo/shugosha/pkg/model"
@@ -9,15 +10,20 @@ import (
 
 type configHandler struct {
 	configManager model.ConfigManager
+	validator     model.ConfigValidator
+	redactor      model.ConfigRedactor
 }
 
-func NewConfigHandler(configManger model.ConfigManager) *configHandler {
+func NewConfigHandler(configManger model.ConfigManager, validator model.ConfigValidator, redactor model.ConfigRedactor) *configHandler {
 	return &configHandler{
 		configManager: configManger,
+		validator:     validator,
+		redactor:      redactor,
 	}
 }
 
-// readConfigHandler handles requests to read the configuration.
+// readConfigHandler handles requests to read the configuration, with the
+// values of secret settings redacted.
 func (h *configHandler) ReadConfigHandler(w http.ResponseWriter, r *http.Request) {
 	config, err := h.configManager.LoadConfig()
 	if err != nil {
@@ -26,10 +32,13 @@ func (h *configHandler) ReadConfigHandler(w http.ResponseWriter, r *http.Request
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(config)
+	json.NewEncoder(w).Encode(h.redactor.RedactConfig(config))
 }
 
-// updateConfigHandler handles requests to update the configuration.
+// updateConfigHandler handles requests to update the configuration. Invalid
+// configurations are answered with 422 and the list of invalid fields, and
+// with 409 while the config file manages the configuration. Redacted secret
+// values keep the stored secret.
 func (h *configHandler) UpdateConfigHandler(w http.ResponseWriter, r *http.Request) {
 	var newConfig model.BackupConfig
 	if err := json.NewDecoder(r.Body).Decode(&newConfig); err != nil {
@@ -37,10 +46,43 @@ func (h *configHandler) UpdateConfigHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	current, err := h.configManager.LoadConfig()
+	if err != nil {
+		http.Error(w, "Failed to read config: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.redactor.RestoreRedacted(&newConfig, current)
+
 	if err := h.configManager.SaveConfig(&newConfig); err != nil {
-		http.Error(w, "Failed to update config: "+err.Error(), http.StatusInternalServerError)
+		WriteSaveError(w, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
+
+// ReadSchemaHandler returns the JSON Schema of the configuration.
+func (h *configHandler) ReadSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/schema+json")
+	json.NewEncoder(w).Encode(h.validator.ConfigSchema())
+}
+
+// WriteSaveError answers with 422 and the invalid fields if err holds
+// model.ValidationErrors, with 409 if the config file manages the
+// configuration, and with 500 otherwise.
+func WriteSaveError(w http.ResponseWriter, err error) {
+	if errors.Is(err, model.ErrConfigReadOnly) {
+		http.Error(w, "Failed to update config: "+err.Error(), http.StatusConflict)
+		return
+	}
+
+	var validationErrors model.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		http.Error(w, "Failed to update config: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]any{"errors": validationErrors})
+}
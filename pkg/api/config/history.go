@@ -0,0 +1,115 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+type historyHandler struct {
+	history  model.ConfigHistory
+	redactor model.ConfigRedactor
+}
+
+func NewHistoryHandler(history model.ConfigHistory, redactor model.ConfigRedactor) *historyHandler {
+	return &historyHandler{history: history, redactor: redactor}
+}
+
+// ReadRevisionsHandler lists the stored revisions of the configuration, newest first.
+func (h *historyHandler) ReadRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	revisions, err := h.history.Revisions()
+	if err != nil {
+		http.Error(w, "Failed to read revisions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revisions)
+}
+
+// ReadRevisionHandler returns a revision with its configuration, with the
+// values of secret settings redacted.
+func (h *historyHandler) ReadRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := revisionID(w, chi.URLParam(r, "id"))
+	if !ok {
+		return
+	}
+
+	revision, err := h.history.Revision(id)
+	if err != nil {
+		writeRevisionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.redactor.RedactRevision(revision))
+}
+
+// DiffRevisionHandler lists the changes from the revision given by the
+// "against" query parameter to the revision in the path. Without "against",
+// or with "against=0", the revision is compared to the current configuration.
+func (h *historyHandler) DiffRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := revisionID(w, chi.URLParam(r, "id"))
+	if !ok {
+		return
+	}
+
+	against := 0
+	if value := r.URL.Query().Get("against"); value != "" {
+		if against, ok = revisionID(w, value); !ok {
+			return
+		}
+	}
+
+	changes, err := h.history.DiffRevisions(against, id)
+	if err != nil {
+		writeRevisionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.redactor.RedactChanges(changes))
+}
+
+// RollbackHandler makes the configuration of a revision the current one,
+// stored as a new revision.
+func (h *historyHandler) RollbackHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := revisionID(w, chi.URLParam(r, "id"))
+	if !ok {
+		return
+	}
+
+	revision, err := h.history.Rollback(id)
+	if errors.Is(err, model.ErrDBKeyNotFound) {
+		writeRevisionError(w, err)
+		return
+	} else if err != nil {
+		WriteSaveError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.redactor.RedactRevision(revision))
+}
+
+func revisionID(w http.ResponseWriter, value string) (int, bool) {
+	id, err := strconv.Atoi(value)
+	if err != nil || id < 0 {
+		http.Error(w, "Invalid revision: "+value, http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+func writeRevisionError(w http.ResponseWriter, err error) {
+	if errors.Is(err, model.ErrDBKeyNotFound) {
+		http.Error(w, "Unknown revision: "+err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, "Failed to read revision: "+err.Error(), http.StatusInternalServerError)
+}
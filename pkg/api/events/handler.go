@@ -0,0 +1,125 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// heartbeatInterval keeps idle connections open through proxies.
+const heartbeatInterval = 30 * time.Second
+
+// NewEventsHandler returns an HTTP handler streaming activity events as
+// Server-Sent Events.
+//
+// Supported query parameters: provider, root and type, each repeatable or
+// comma separated. Clients resume after the last received event with the
+// Last-Event-ID header, which browsers send when reconnecting, or the
+// lastEventId query parameter; events still in the replay buffer are sent
+// first, with only the latest progress of each upload.
+func NewEventsHandler(stream model.ActivityStream) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+
+		lastID, err := lastEventID(r)
+		if err != nil {
+			http.Error(w, "Invalid last event ID: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		replay, events, cancel := stream.Subscribe(parseFilter(r.URL.Query()), lastID)
+		defer cancel()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+
+		for _, event := range replay {
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+
+			case event, ok := <-events:
+				if !ok {
+					// Fell behind, the client reconnects and resumes from the replay buffer
+					return
+				}
+				if err := writeEvent(w, event); err != nil {
+					return
+				}
+				flusher.Flush()
+
+			case <-heartbeat.C:
+				if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event model.ActivityEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
+
+func lastEventID(r *http.Request) (uint64, error) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("lastEventId")
+	}
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+func parseFilter(values url.Values) model.ActivityFilter {
+	filter := model.ActivityFilter{
+		Providers: split(values["provider"]),
+		Roots:     split(values["root"]),
+	}
+	for _, activityType := range split(values["type"]) {
+		filter.Types = append(filter.Types, model.ActivityType(activityType))
+	}
+	return filter
+}
+
+// split returns the values of a repeatable, comma separated parameter.
+func split(values []string) []string {
+	var items []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
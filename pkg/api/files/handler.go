@@ -0,0 +1,109 @@
+package files
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// NewSearchHandler returns an HTTP handler function that searches the file catalog.
+//
+// Supported query parameters: provider, root, pathPrefix, checksum, ext,
+// minSize, maxSize, modifiedAfter, modifiedBefore (RFC 3339), sort (path,
+// size, modified), order (asc, desc), limit and cursor.
+func NewSearchHandler(searcher model.FileSearcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query, err := parseQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, "Invalid query: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		result, err := searcher.SearchFiles(*query)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, model.ErrInvalidQuery) {
+				status = http.StatusBadRequest
+			}
+			http.Error(w, "Failed to search files: "+err.Error(), status)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+func parseQuery(values url.Values) (*model.FileQuery, error) {
+	query := &model.FileQuery{
+		Provider:   values.Get("provider"),
+		Root:       values.Get("root"),
+		PathPrefix: values.Get("pathPrefix"),
+		Checksum:   values.Get("checksum"),
+		Extension:  values.Get("ext"),
+		Sort:       values.Get("sort"),
+		Cursor:     values.Get("cursor"),
+	}
+
+	switch values.Get("order") {
+	case "", "asc":
+	case "desc":
+		query.Descending = true
+	default:
+		return nil, fmt.Errorf("order must be asc or desc")
+	}
+
+	var err error
+	if query.MinSize, err = parseInt(values, "minSize"); err != nil {
+		return nil, err
+	}
+	if query.MaxSize, err = parseInt(values, "maxSize"); err != nil {
+		return nil, err
+	}
+	limit, err := parseInt(values, "limit")
+	if err != nil {
+		return nil, err
+	}
+	query.Limit = int(limit)
+
+	if query.ModifiedAfter, err = parseTime(values, "modifiedAfter"); err != nil {
+		return nil, err
+	}
+	if query.ModifiedBefore, err = parseTime(values, "modifiedBefore"); err != nil {
+		return nil, err
+	}
+
+	return query, nil
+}
+
+func parseInt(values url.Values, name string) (int64, error) {
+	value := values.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer", name)
+	}
+	return n, nil
+}
+
+func parseTime(values url.Values, name string) (time.Time, error) {
+	value := values.Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 time", name)
+	}
+	return t, nil
+}
@@ -0,0 +1,36 @@
+package files
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+type failingSearcher struct {
+	err error
+}
+
+func (s failingSearcher) SearchFiles(model.FileQuery) (*model.FileQueryResult, error) {
+	return nil, s.err
+}
+
+func TestSearchErrorStatus(t *testing.T) {
+	for err, code := range map[error]int{
+		fmt.Errorf("%w: unknown sort field", model.ErrInvalidQuery): http.StatusBadRequest,
+		errors.New("database closed"):                               http.StatusInternalServerError,
+	} {
+		rec := httptest.NewRecorder()
+		NewSearchHandler(failingSearcher{err: err})(rec, httptest.NewRequest(http.MethodGet, "/api/files", nil))
+		assert.Equal(t, code, rec.Code, err.Error())
+	}
+
+	rec := httptest.NewRecorder()
+	NewSearchHandler(failingSearcher{})(rec, httptest.NewRequest(http.MethodGet, "/api/files?limit=many", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
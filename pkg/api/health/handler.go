@@ -0,0 +1,48 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/sevigo/shugosha/pkg/auth"
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// NewLivenessHandler returns an HTTP handler reporting whether the service
+// watches files and stores the catalog. Clients without credentials only
+// get the status and class of every component. It answers with 503 only if the
+// service is down, a degraded service is not fixed by a restart.
+func NewLivenessHandler(reporter model.ServiceHealthReporter) http.HandlerFunc {
+	return newHandler(reporter.Liveness, func(status model.HealthStatus) bool {
+		return status != model.HealthDown
+	})
+}
+
+// NewReadinessHandler returns an HTTP handler reporting whether the service
+// also finished the initial scan and reaches every provider. It answers with
+// 503 if the service is degraded or down.
+func NewReadinessHandler(reporter model.ServiceHealthReporter) http.HandlerFunc {
+	return newHandler(reporter.Readiness, func(status model.HealthStatus) bool {
+		return status == model.HealthOK
+	})
+}
+
+func newHandler(check func(ctx context.Context) model.ServiceHealth, passing func(model.HealthStatus) bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		health := check(r.Context())
+		if !auth.Authenticated(r) {
+			health = health.Public()
+		}
+
+		code := http.StatusOK
+		if !passing(health.Status) {
+			code = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(health)
+	}
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// unixPrefix marks listen addresses of Unix sockets, e.g. "unix:/run/shugosha.sock".
+const unixPrefix = "unix:"
+
+const shutdownTimeout = 5 * time.Second
+
+// ListenConfig defines where the API server accepts connections and whether
+// it uses TLS.
+type ListenConfig struct {
+	Address    string // host:port, or unix:/path for a Unix socket
+	CertFile   string // PEM certificate, TLS is enabled if set
+	KeyFile    string // PEM private key of the certificate
+	SelfSigned bool   // Generate a self-signed certificate if CertFile does not exist
+}
+
+// Start serves the API until ctx is cancelled.
+func (s *Server) Start(ctx context.Context, cfg ListenConfig) error {
+	listener, err := listen(cfg.Address)
+	if err != nil {
+		return err
+	}
+
+	if cfg.CertFile != "" {
+		if cfg.SelfSigned {
+			if err := ensureSelfSigned(cfg.CertFile, cfg.KeyFile); err != nil {
+				listener.Close()
+				return fmt.Errorf("failed to create self-signed certificate: %w", err)
+			}
+		}
+
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			listener.Close()
+			return fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		listener = tls.NewListener(listener, &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		})
+	}
+
+	if !s.deps.Auth.Enabled() && !local(cfg.Address) {
+		slog.Warn("The API accepts requests from the network without authentication, add tokens or users to the api section of the config", "address", cfg.Address)
+	}
+
+	server := &http.Server{
+		Handler:           s.router,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// listen opens a TCP listener, or a Unix socket only accessible by the
+// owner. A socket left behind by an earlier run is replaced.
+func listen(address string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(address, unixPrefix)
+	if !ok {
+		return net.Listen("tcp", address)
+	}
+
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	return listenUnix(path)
+}
+
+// local reports whether address only accepts connections from this machine.
+func local(address string) bool {
+	if strings.HasPrefix(address, unixPrefix) {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,14 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// NewDocumentHandler returns an HTTP handler serving the OpenAPI document.
+func NewDocumentHandler(document map[string]any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(document)
+	}
+}
@@ -0,0 +1,252 @@
+// Package openapi describes the HTTP API as an OpenAPI document and
+// validates requests against it.
+package openapi
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sevigo/shugosha/pkg/jsonschema"
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// Version is the version of the API described by the document.
+const Version = "1.0.0"
+
+// Parameter describes a path or query parameter.
+type Parameter struct {
+	Name        string
+	In          string // "path" or "query"
+	Description string
+	Schema      map[string]any
+	Required    bool
+}
+
+// Operation describes a single endpoint.
+type Operation struct {
+	Method      string
+	Path        string // chi route pattern, which matches the OpenAPI path template
+	Summary     string
+	Parameters  []Parameter
+	Request     map[string]any // Schema of the JSON request body, nil without body
+	OwnBody     bool           // The handler validates the body and answers 422 with the invalid fields
+	Response    map[string]any // Schema of the response body, nil without body
+	Status      int            // Status of a successful response, defaults to 200
+	ContentType string         // Content type of the response, defaults to application/json
+	Errors      []int          // Error statuses besides 400 and 401
+	Public      bool           // Allowed without credentials
+}
+
+// Operations lists all endpoints of the API. configSchema is the JSON Schema
+// of the configuration, which depends on the registered provider types.
+func Operations(configSchema map[string]any) []Operation {
+	str := map[string]any{"type": "string"}
+	revisionID := Parameter{Name: "id", In: "path", Description: "ID of the revision", Schema: map[string]any{"type": "integer", "minimum": 0}, Required: true}
+	providerName := Parameter{Name: "provider", In: "path", Description: "Name of the provider", Schema: str, Required: true}
+	secretName := Parameter{Name: "name", In: "path", Description: "Name of the secret", Schema: str, Required: true}
+	list := func(items map[string]any) map[string]any {
+		return map[string]any{"type": "array", "items": items}
+	}
+	activityTypes := jsonschema.Of(model.ActivityType(""))
+
+	return []Operation{
+		{Method: http.MethodGet, Path: "/api/openapi.json", Summary: "OpenAPI document of this API", Response: map[string]any{"type": "object"}},
+
+		{Method: http.MethodGet, Path: "/api/config", Summary: "Current configuration, with secrets redacted", Response: configSchema},
+		{Method: http.MethodPost, Path: "/api/config", Summary: "Replace the configuration; redacted secrets keep their stored value. Provider and directory changes take effect after a restart, listed as restartRequired of the new revision", Request: configSchema, OwnBody: true, Errors: []int{http.StatusConflict, http.StatusUnprocessableEntity}},
+		{Method: http.MethodGet, Path: "/api/config/schema", Summary: "JSON Schema of the configuration", ContentType: "application/schema+json", Response: map[string]any{"type": "object"}},
+		{Method: http.MethodGet, Path: "/api/config/revisions", Summary: "Stored revisions of the configuration, newest first", Response: list(jsonschema.Of(model.ConfigRevision{}))},
+		{Method: http.MethodGet, Path: "/api/config/revisions/{id}", Summary: "A revision with its configuration", Parameters: []Parameter{revisionID}, Response: jsonschema.Of(model.ConfigRevision{}), Errors: []int{http.StatusNotFound}},
+		{Method: http.MethodGet, Path: "/api/config/revisions/{id}/diff", Summary: "Changes from another revision to this one", Parameters: []Parameter{revisionID,
+			{Name: "against", In: "query", Description: "Revision to compare with, 0 or empty for the current configuration", Schema: map[string]any{"type": "integer", "minimum": 0}},
+		}, Response: list(jsonschema.Of(model.ConfigChange{})), Errors: []int{http.StatusNotFound}},
+		{Method: http.MethodPost, Path: "/api/config/revisions/{id}/rollback", Summary: "Make the configuration of a revision the current one", Parameters: []Parameter{revisionID}, Response: jsonschema.Of(model.ConfigRevision{}), Errors: []int{http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity}},
+
+		{Method: http.MethodGet, Path: "/api/providers", Summary: "Stored sizes and health of all providers", Response: jsonschema.Of(map[string]model.ProviderInfo{})},
+		{Method: http.MethodPost, Path: "/api/providers/{provider}/rebuild", Summary: "Rebuild the catalog of a provider from the files it stores", Parameters: []Parameter{providerName}, Response: jsonschema.Of(model.RebuildResult{}), Errors: []int{http.StatusNotFound}},
+
+		{Method: http.MethodGet, Path: "/api/files", Summary: "Search the catalog of backed up files", Parameters: []Parameter{
+			{Name: "provider", In: "query", Schema: str},
+			{Name: "root", In: "query", Description: "Watched root directory", Schema: str},
+			{Name: "pathPrefix", In: "query", Schema: str},
+			{Name: "checksum", In: "query", Schema: str},
+			{Name: "ext", In: "query", Description: "File extension, with or without the leading dot", Schema: str},
+			{Name: "minSize", In: "query", Schema: map[string]any{"type": "integer"}},
+			{Name: "maxSize", In: "query", Schema: map[string]any{"type": "integer"}},
+			{Name: "modifiedAfter", In: "query", Schema: map[string]any{"type": "string", "format": "date-time"}},
+			{Name: "modifiedBefore", In: "query", Schema: map[string]any{"type": "string", "format": "date-time"}},
+			{Name: "sort", In: "query", Schema: map[string]any{"type": "string", "enum": []any{"path", "size", "modified"}}},
+			{Name: "order", In: "query", Schema: map[string]any{"type": "string", "enum": []any{"asc", "desc"}}},
+			{Name: "limit", In: "query", Schema: map[string]any{"type": "integer", "minimum": 0}},
+			{Name: "cursor", In: "query", Description: "nextCursor of the previous page", Schema: str},
+		}, Response: jsonschema.Of(model.FileQueryResult{})},
+
+		{Method: http.MethodPost, Path: "/api/restore", Summary: "Restore a single file", Request: required(jsonschema.Of(model.RestoreRequest{}), "provider", "path")},
+		{Method: http.MethodGet, Path: "/api/compliance", Summary: "Files that do not meet the replication rules", Response: jsonschema.Of(model.ComplianceReport{})},
+		{Method: http.MethodGet, Path: "/api/events", Summary: "Backup activity as Server-Sent Events", Parameters: []Parameter{
+			{Name: "provider", In: "query", Description: "Providers to include, repeatable or comma separated", Schema: list(str)},
+			{Name: "root", In: "query", Description: "Directories to include, repeatable or comma separated", Schema: list(str)},
+			{Name: "type", In: "query", Description: "Event types to include, repeatable or comma separated", Schema: list(activityTypes)},
+			{Name: "lastEventId", In: "query", Description: "Resume after this event, like the Last-Event-ID header", Schema: map[string]any{"type": "integer", "minimum": 0}},
+		}, ContentType: "text/event-stream", Response: jsonschema.Of(model.ActivityEvent{})},
+
+		{Method: http.MethodGet, Path: "/api/throttle", Summary: "Configured and effective limits of all providers", Response: jsonschema.Of(map[string]model.ThrottleStatus{})},
+		{Method: http.MethodPut, Path: "/api/throttle/{provider}", Summary: "Change the limits of a provider", Parameters: []Parameter{providerName}, Request: jsonschema.Of(model.ThrottleConfig{}), Errors: []int{http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity}},
+
+		{Method: http.MethodGet, Path: "/api/secrets", Summary: "Names of the secrets in the keystore", Response: list(str), Errors: []int{http.StatusLocked}},
+		{Method: http.MethodPut, Path: "/api/secrets/{name}", Summary: "Store a secret in the keystore", Parameters: []Parameter{secretName}, Request: required(jsonschema.Of(model.SecretValue{}), "value"), Errors: []int{http.StatusLocked}},
+		{Method: http.MethodDelete, Path: "/api/secrets/{name}", Summary: "Remove a secret from the keystore", Parameters: []Parameter{secretName}, Errors: []int{http.StatusNotFound, http.StatusLocked}},
+
+		{Method: http.MethodGet, Path: "/api/verify", Summary: "Latest verification report of every provider", Response: jsonschema.Of(map[string]model.VerifyReport{})},
+		{Method: http.MethodPost, Path: "/api/verify", Summary: "Start verifying the files stored by a provider", Request: required(jsonschema.Of(model.VerifyRequest{}), "provider"), Status: http.StatusAccepted, Response: jsonschema.Of(model.VerifyReport{})},
+
+		{Method: http.MethodGet, Path: "/metrics", Summary: "Metrics in the Prometheus text format", ContentType: "text/plain", Response: str},
+		{Method: http.MethodGet, Path: "/healthz", Summary: "Liveness of the watcher and the database, 503 if the service is down; errors and details need credentials", Response: jsonschema.Of(model.ServiceHealth{}), Errors: []int{http.StatusServiceUnavailable}, Public: true},
+		{Method: http.MethodGet, Path: "/readyz", Summary: "Readiness including the initial scan and the providers, 503 if degraded; errors and details need credentials", Response: jsonschema.Of(model.ServiceHealth{}), Errors: []int{http.StatusServiceUnavailable}, Public: true},
+	}
+}
+
+// Document returns the OpenAPI document of the operations.
+func Document(operations []Operation) map[string]any {
+	paths := map[string]any{}
+	for _, op := range operations {
+		item, ok := paths[op.Path].(map[string]any)
+		if !ok {
+			item = map[string]any{}
+			paths[op.Path] = item
+		}
+		item[strings.ToLower(op.Method)] = op.document()
+	}
+
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":       "Shugosha API",
+			"description": "Control and monitor the Shugosha backup service.",
+			"version":     Version,
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer"},
+				"basicAuth":  map[string]any{"type": "http", "scheme": "basic"},
+			},
+			"schemas": map[string]any{
+				"ValidationErrors": validationErrors(),
+			},
+		},
+		// Without tokens and users in the api section no credentials are needed
+		"security": []any{
+			map[string]any{"bearerAuth": []string{}},
+			map[string]any{"basicAuth": []string{}},
+			map[string]any{},
+		},
+	}
+}
+
+func (op Operation) document() map[string]any {
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	contentType := op.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	success := map[string]any{"description": http.StatusText(status)}
+	if op.Response != nil {
+		success["content"] = map[string]any{contentType: map[string]any{"schema": op.Response}}
+	}
+
+	responses := map[string]any{
+		strconv.Itoa(status): success,
+		"400": map[string]any{
+			"description": "Invalid request",
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/ValidationErrors"}},
+				"text/plain":       map[string]any{"schema": map[string]any{"type": "string"}},
+			},
+		},
+	}
+	if !op.Public {
+		responses["401"] = map[string]any{"description": "Missing or invalid credentials"}
+	}
+	if op.Method != http.MethodGet {
+		responses["403"] = map[string]any{"description": "The read role cannot change anything"}
+	}
+	for _, code := range op.Errors {
+		response := map[string]any{"description": http.StatusText(code)}
+		switch code {
+		case http.StatusUnprocessableEntity:
+			response["content"] = map[string]any{"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/ValidationErrors"}}}
+		case http.StatusServiceUnavailable:
+			// Health checks describe what is not working
+			response["content"] = success["content"]
+		}
+		responses[strconv.Itoa(code)] = response
+	}
+
+	operation := map[string]any{
+		"summary":     op.Summary,
+		"operationId": operationID(op),
+		"responses":   responses,
+	}
+	if op.Public {
+		operation["security"] = []any{}
+	}
+
+	if len(op.Parameters) > 0 {
+		parameters := make([]any, len(op.Parameters))
+		for i, parameter := range op.Parameters {
+			doc := map[string]any{"name": parameter.Name, "in": parameter.In, "schema": parameter.Schema}
+			if parameter.Description != "" {
+				doc["description"] = parameter.Description
+			}
+			if parameter.Required {
+				doc["required"] = true
+			}
+			if parameter.Schema["type"] == "array" {
+				doc["style"] = "form"
+				doc["explode"] = true
+			}
+			parameters[i] = doc
+		}
+		operation["parameters"] = parameters
+	}
+
+	if op.Request != nil {
+		operation["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": map[string]any{"schema": op.Request}},
+		}
+	}
+
+	return operation
+}
+
+// operationID derives an ID like "getConfigRevisionsIdDiff" from method and path.
+func operationID(op Operation) string {
+	id := strings.ToLower(op.Method)
+	path := strings.TrimPrefix(op.Path, "/api")
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '.' || r == '{' || r == '}' }) {
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+	return id
+}
+
+func validationErrors() map[string]any {
+	return map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"errors": map[string]any{"type": "array", "items": jsonschema.Of(model.FieldError{})}},
+	}
+}
+
+// required marks properties of an object schema as required.
+func required(schema map[string]any, names ...string) map[string]any {
+	sort.Strings(names)
+	schema["required"] = names
+	return schema
+}
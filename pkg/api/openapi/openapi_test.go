@@ -0,0 +1,26 @@
+package openapi
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var pathParameter = regexp.MustCompile(`\{([^}]+)\}`)
+
+func TestPathParametersMatchRoutes(t *testing.T) {
+	for _, op := range Operations(map[string]any{"type": "object"}) {
+		var segments, parameters []string
+		for _, match := range pathParameter.FindAllStringSubmatch(op.Path, -1) {
+			segments = append(segments, match[1])
+		}
+		for _, parameter := range op.Parameters {
+			if parameter.In == "path" {
+				assert.True(t, parameter.Required, "%s %s: path parameter %s must be required", op.Method, op.Path, parameter.Name)
+				parameters = append(parameters, parameter.Name)
+			}
+		}
+		assert.Equal(t, segments, parameters, "%s %s", op.Method, op.Path)
+	}
+}
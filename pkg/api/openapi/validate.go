@@ -0,0 +1,150 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/sevigo/shugosha/pkg/jsonschema"
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// maxBodySize limits the request bodies read for validation.
+const maxBodySize = 10 << 20
+
+// Validator checks requests against the parameters and request bodies of
+// the operations.
+type Validator struct {
+	routes     chi.Routes
+	operations map[string]Operation
+}
+
+// NewValidator creates a validator for the operations served by routes.
+func NewValidator(routes chi.Routes, operations []Operation) *Validator {
+	v := &Validator{routes: routes, operations: map[string]Operation{}}
+	for _, op := range operations {
+		v.operations[op.Method+" "+op.Path] = op
+	}
+	return v
+}
+
+// Middleware answers requests with invalid parameters or bodies with 400 and
+// the list of problems. Requests without a documented operation are passed
+// on unchanged, so that the router answers them, and so are the bodies of
+// operations whose handler validates them.
+func (v *Validator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rctx := chi.NewRouteContext()
+		if !v.routes.Match(rctx, r.Method, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		op, ok := v.operations[r.Method+" "+rctx.RoutePattern()]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		errs := v.validateParameters(op, rctx, r)
+
+		if op.Request != nil && !op.OwnBody {
+			data, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+			if err != nil {
+				http.Error(w, "Failed to read request body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(data))
+
+			decoder := json.NewDecoder(bytes.NewReader(data))
+			decoder.UseNumber()
+			var body any
+			if err := decoder.Decode(&body); err != nil {
+				http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			for _, fieldError := range jsonschema.Validate(op.Request, body) {
+				if fieldError.Field == "(root)" {
+					fieldError.Field = "body"
+				} else {
+					fieldError.Field = "body." + fieldError.Field
+				}
+				errs = append(errs, fieldError)
+			}
+		}
+
+		if len(errs) > 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]any{"errors": errs})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (v *Validator) validateParameters(op Operation, rctx *chi.Context, r *http.Request) model.ValidationErrors {
+	var errs model.ValidationErrors
+	query := r.URL.Query()
+
+	for _, parameter := range op.Parameters {
+		var values []string
+		switch parameter.In {
+		case "path":
+			values = []string{rctx.URLParam(parameter.Name)}
+		case "query":
+			values = query[parameter.Name]
+		}
+
+		field := parameter.In + "." + parameter.Name
+		if len(values) == 0 || values[0] == "" && parameter.In == "query" {
+			if parameter.Required {
+				errs = append(errs, model.FieldError{Field: field, Message: "is required"})
+			}
+			continue
+		}
+
+		// Arrays are repeated or comma separated, everything else is a
+		// single value.
+		schema, parts := parameter.Schema, values[:1]
+		if items, ok := parameter.Schema["items"].(map[string]any); ok {
+			schema, parts = items, nil
+			for _, value := range values {
+				parts = append(parts, strings.Split(value, ",")...)
+			}
+		}
+
+		for _, part := range parts {
+			for _, fieldError := range jsonschema.Validate(schema, parseParameter(schema, part)) {
+				errs = append(errs, model.FieldError{Field: field, Message: fmt.Sprintf("%s: %q", fieldError.Message, part)})
+			}
+		}
+	}
+
+	return errs
+}
+
+// parseParameter converts a parameter to the JSON value its schema expects.
+// Values that cannot be converted stay strings and fail the type check.
+func parseParameter(schema map[string]any, value string) any {
+	switch schema["type"] {
+	case "integer", "number":
+		number := json.Number(value)
+		if _, err := number.Float64(); err == nil {
+			return number
+		}
+	case "boolean":
+		switch value {
+		case "true":
+			return true
+		case "false":
+			return false
+		}
+	}
+	return value
+}
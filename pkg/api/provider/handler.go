@@ -2,13 +2,17 @@ package provider
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
+	"github.com/go-chi/chi/v5"
+
 	"github.com/sevigo/shugosha/pkg/model"
 )
 
-// NewProviderInfoHandler returns an HTTP handler function that uses ProviderMetaInfoGetter.
-func NewProviderInfoHandler(getter model.ProviderMetaInfoGetter) http.HandlerFunc {
+// NewProviderInfoHandler returns an HTTP handler function that uses ProviderMetaInfoGetter
+// and adds the health of every provider.
+func NewProviderInfoHandler(getter model.ProviderMetaInfoGetter, reporter model.HealthReporter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		providers, err := getter.GetProviders()
 		if err != nil {
@@ -16,17 +20,42 @@ func NewProviderInfoHandler(getter model.ProviderMetaInfoGetter) http.HandlerFun
 			return
 		}
 
-		providerInfos := make(map[string]*model.ProviderMetaInfo)
+		health := reporter.Health()
+
+		providerInfos := make(map[string]model.ProviderInfo)
 		for _, providerName := range providers {
 			metaInfo, err := getter.GetMetaInfo(providerName)
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
-			providerInfos[providerName] = metaInfo
+
+			info := model.ProviderInfo{ProviderMetaInfo: metaInfo}
+			if status, ok := health[providerName]; ok {
+				info.Health = &status
+			}
+			providerInfos[providerName] = info
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(providerInfos)
 	}
 }
+
+// NewRebuildHandler returns an HTTP handler function that rebuilds the catalog
+// of a provider from the files it stores.
+func NewRebuildHandler(rebuilder model.CatalogRebuilder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result, err := rebuilder.RebuildCatalog(chi.URLParam(r, "provider"))
+		if errors.Is(err, model.ErrUnknownProvider) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, "Failed to rebuild catalog: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
@@ -0,0 +1,32 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+type failingRebuilder struct {
+	err error
+}
+
+func (r failingRebuilder) RebuildCatalog(string) (*model.RebuildResult, error) {
+	return nil, r.err
+}
+
+func TestRebuildErrorStatus(t *testing.T) {
+	for err, code := range map[error]int{
+		fmt.Errorf("%w %q", model.ErrUnknownProvider, "Nope"): http.StatusNotFound,
+		errors.New("listing failed"):                          http.StatusInternalServerError,
+	} {
+		rec := httptest.NewRecorder()
+		NewRebuildHandler(failingRebuilder{err: err})(rec, httptest.NewRequest(http.MethodPost, "/api/providers/Nope/rebuild", nil))
+		assert.Equal(t, code, rec.Code, err.Error())
+	}
+}
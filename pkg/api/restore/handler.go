@@ -0,0 +1,31 @@
+package restore
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// NewRestoreHandler returns an HTTP handler function that restores a single file.
+func NewRestoreHandler(manager model.RestoreManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req model.RestoreRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if req.Provider == "" || req.Path == "" {
+			http.Error(w, "provider and path are required", http.StatusBadRequest)
+			return
+		}
+
+		if err := manager.Restore(req); err != nil {
+			http.Error(w, "Failed to restore file: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
@@ -0,0 +1,79 @@
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/sevigo/shugosha/pkg/model"
+	"github.com/sevigo/shugosha/pkg/secrets"
+)
+
+type secretsHandler struct {
+	store model.SecretStore
+}
+
+// NewSecretsHandler creates the handlers of the keystore. store is nil while
+// the keystore is locked.
+func NewSecretsHandler(store model.SecretStore) *secretsHandler {
+	return &secretsHandler{store: store}
+}
+
+// ReadSecretsHandler lists the names of the stored secrets, never their values.
+func (h *secretsHandler) ReadSecretsHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.unlocked(w) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.store.Names())
+}
+
+// UpdateSecretHandler stores a secret, read from the "value" field of the body.
+// Providers pick up changed secrets when they are created on the next start.
+func (h *secretsHandler) UpdateSecretHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.unlocked(w) {
+		return
+	}
+
+	var req model.SecretValue
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.store.Set(chi.URLParam(r, "name"), req.Value); err != nil {
+		http.Error(w, "Failed to store secret: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// DeleteSecretHandler removes a secret from the keystore.
+func (h *secretsHandler) DeleteSecretHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.unlocked(w) {
+		return
+	}
+
+	err := h.store.Delete(chi.URLParam(r, "name"))
+	if errors.Is(err, secrets.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to delete secret: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *secretsHandler) unlocked(w http.ResponseWriter) bool {
+	if h.store == nil {
+		http.Error(w, secrets.ErrLocked.Error(), http.StatusLocked)
+		return false
+	}
+	return true
+}
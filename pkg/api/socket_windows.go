@@ -0,0 +1,22 @@
+//go:build windows
+
+package api
+
+import (
+	"net"
+	"os"
+)
+
+// listenUnix creates the socket and limits it to the owner as far as the
+// file mode goes on Windows.
+func listenUnix(path string) (net.Listener, error) {
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
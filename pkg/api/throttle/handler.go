@@ -0,0 +1,77 @@
+package throttle
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/sevigo/shugosha/pkg/api/config"
+	"github.com/sevigo/shugosha/pkg/model"
+	"github.com/sevigo/shugosha/pkg/throttle"
+)
+
+type throttleHandler struct {
+	controller    model.ThrottleController
+	configManager model.ConfigManager
+}
+
+func NewThrottleHandler(controller model.ThrottleController, configManager model.ConfigManager) *throttleHandler {
+	return &throttleHandler{
+		controller:    controller,
+		configManager: configManager,
+	}
+}
+
+// ReadThrottlesHandler returns the configured and effective limits per provider.
+func (h *throttleHandler) ReadThrottlesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.controller.Throttles())
+}
+
+// UpdateThrottleHandler changes the limits of a provider at runtime and
+// stores them in the configuration.
+func (h *throttleHandler) UpdateThrottleHandler(w http.ResponseWriter, r *http.Request) {
+	providerName := chi.URLParam(r, "provider")
+
+	var throttleConfig model.ThrottleConfig
+	if err := json.NewDecoder(r.Body).Decode(&throttleConfig); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	backupConfig, err := h.configManager.LoadConfig()
+	if err != nil {
+		http.Error(w, "Failed to read config: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	found := false
+	for i := range backupConfig.Providers {
+		if backupConfig.Providers[i].Name == providerName {
+			backupConfig.Providers[i].Throttle = &throttleConfig
+			found = true
+		}
+	}
+	if !found {
+		http.Error(w, "Unknown provider: "+providerName, http.StatusNotFound)
+		return
+	}
+
+	if err := throttle.Validate(throttleConfig); err != nil {
+		http.Error(w, "Invalid throttle: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.configManager.SaveConfig(backupConfig); err != nil {
+		config.WriteSaveError(w, err)
+		return
+	}
+
+	if err := h.controller.SetThrottle(providerName, throttleConfig); err != nil {
+		http.Error(w, "Invalid throttle: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
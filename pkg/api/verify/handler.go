@@ -0,0 +1,46 @@
+package verify
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+type verifyHandler struct {
+	verifier model.Verifier
+}
+
+func NewVerifyHandler(verifier model.Verifier) *verifyHandler {
+	return &verifyHandler{verifier: verifier}
+}
+
+// ReadReportsHandler returns the latest verification report of every provider.
+func (h *verifyHandler) ReadReportsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.verifier.VerifyReports())
+}
+
+// StartVerifyHandler starts verifying the files stored by a provider.
+func (h *verifyHandler) StartVerifyHandler(w http.ResponseWriter, r *http.Request) {
+	var req model.VerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Provider == "" {
+		http.Error(w, "provider is required", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.verifier.StartVerify(req)
+	if err != nil {
+		http.Error(w, "Failed to start verification: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(report)
+}
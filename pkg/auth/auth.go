@@ -0,0 +1,250 @@
+// Package auth authenticates clients of the HTTP API and checks their role.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/sevigo/shugosha/pkg/model"
+	"github.com/sevigo/shugosha/pkg/secrets"
+)
+
+const realm = "shugosha"
+
+// publicPaths can be read without credentials, so that service managers and
+// orchestrators can probe the health of the service. Handlers check
+// Authenticated before showing anything beyond the status.
+var publicPaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+}
+
+// Authenticator checks the credentials of API requests against the api
+// section of the configuration.
+type Authenticator struct {
+	mu       sync.RWMutex
+	resolver *secrets.Resolver
+	tokens   map[[sha256.Size]byte]model.APIRole // Roles by the hash of their token
+	users    map[string]model.APIUser
+	origins  []string
+}
+
+// NewAuthenticator creates an authenticator for the configuration. Token
+// secret references are resolved by resolver.
+func NewAuthenticator(backupConfig *model.BackupConfig, resolver *secrets.Resolver) (*Authenticator, error) {
+	a := &Authenticator{resolver: resolver}
+	if err := a.apply(backupConfig.API); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// ApplyConfig applies the api section of a changed configuration. Invalid
+// sections are logged and the previous credentials stay in effect.
+func (a *Authenticator) ApplyConfig(backupConfig *model.BackupConfig) {
+	if err := a.apply(backupConfig.API); err != nil {
+		slog.Error("[auth] keeping the previous API credentials", "error", err)
+	}
+}
+
+func (a *Authenticator) apply(cfg *model.APIConfig) error {
+	if cfg == nil {
+		cfg = &model.APIConfig{}
+	}
+
+	tokens := make(map[[sha256.Size]byte]model.APIRole, len(cfg.Tokens))
+	for _, token := range cfg.Tokens {
+		value, err := a.resolver.Resolve(token.Token)
+		if err != nil {
+			return fmt.Errorf("token %q: %w", token.Name, err)
+		}
+		if value == "" {
+			return fmt.Errorf("token %q is empty", token.Name)
+		}
+		tokens[sha256.Sum256([]byte(value))] = token.Role
+	}
+
+	users := make(map[string]model.APIUser, len(cfg.Users))
+	for _, user := range cfg.Users {
+		users[user.Username] = user
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.tokens, a.users, a.origins = tokens, users, cfg.AllowedOrigins
+	return nil
+}
+
+// Enabled reports whether any credentials are configured. Without them every
+// request is allowed.
+func (a *Authenticator) Enabled() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return len(a.tokens) > 0 || len(a.users) > 0
+}
+
+// AllowOrigin reports whether cross-origin requests from origin are allowed.
+func (a *Authenticator) AllowOrigin(r *http.Request, origin string) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	for _, allowed := range a.origins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticatedKey marks the context of requests with valid credentials.
+type authenticatedKey struct{}
+
+// Authenticated reports whether the request had valid credentials, or no
+// credentials are configured.
+func Authenticated(r *http.Request) bool {
+	authenticated, _ := r.Context().Value(authenticatedKey{}).(bool)
+	return authenticated
+}
+
+// Middleware rejects requests without valid credentials with 401, and
+// requests changing anything without the admin role with 403. The health
+// probes in publicPaths are always allowed.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authenticated := r.WithContext(context.WithValue(r.Context(), authenticatedKey{}, true))
+		if !a.Enabled() {
+			next.ServeHTTP(w, authenticated)
+			return
+		}
+
+		role, ok := a.authenticate(r)
+		if publicPaths[r.URL.Path] && readOnly(r.Method) {
+			if ok {
+				r = authenticated
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="`+realm+`"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if role != model.RoleAdmin && !readOnly(r.Method) {
+			http.Error(w, "Forbidden: the "+string(role)+" role cannot change anything", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, authenticated)
+	})
+}
+
+// authenticate returns the role granted by the token or the basic auth
+// credentials of the request.
+func (a *Authenticator) authenticate(r *http.Request) (model.APIRole, bool) {
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		hash := sha256.Sum256([]byte(strings.TrimSpace(token)))
+
+		a.mu.RLock()
+		defer a.mu.RUnlock()
+		for known, role := range a.tokens {
+			if subtle.ConstantTimeCompare(known[:], hash[:]) == 1 {
+				return role, true
+			}
+		}
+		return "", false
+	}
+
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return "", false
+	}
+
+	a.mu.RLock()
+	user, ok := a.users[username]
+	a.mu.RUnlock()
+	if !ok || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return "", false
+	}
+	return user.Role, true
+}
+
+func readOnly(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// Validate checks the api section of the configuration and returns the
+// problems by field, relative to the section.
+func Validate(cfg *model.APIConfig) map[string]string {
+	problems := map[string]string{}
+
+	names := map[string]bool{}
+	for i, token := range cfg.Tokens {
+		field := fmt.Sprintf("tokens[%d]", i)
+		if token.Name == "" {
+			problems[field+".name"] = "is required"
+		} else if names[token.Name] {
+			problems[field+".name"] = "is not unique"
+		}
+		names[token.Name] = true
+
+		if token.Token == "" {
+			problems[field+".token"] = "is required"
+		} else if secrets.IsReference(token.Token) {
+			if err := secrets.ValidateReference(token.Token); err != nil {
+				problems[field+".token"] = err.Error()
+			}
+		}
+		if err := validateRole(token.Role); err != nil {
+			problems[field+".role"] = err.Error()
+		}
+	}
+
+	usernames := map[string]bool{}
+	for i, user := range cfg.Users {
+		field := fmt.Sprintf("users[%d]", i)
+		if user.Username == "" {
+			problems[field+".username"] = "is required"
+		} else if usernames[user.Username] {
+			problems[field+".username"] = "is not unique"
+		}
+		usernames[user.Username] = true
+
+		if _, err := bcrypt.Cost([]byte(user.PasswordHash)); err != nil {
+			problems[field+".passwordHash"] = "must be a bcrypt hash"
+		}
+		if err := validateRole(user.Role); err != nil {
+			problems[field+".role"] = err.Error()
+		}
+	}
+
+	for i, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			problems[fmt.Sprintf("allowedOrigins[%d]", i)] = `must be "*" or an origin like "https://example.com"`
+		}
+	}
+
+	return problems
+}
+
+func validateRole(role model.APIRole) error {
+	switch role {
+	case model.RoleRead, model.RoleAdmin:
+		return nil
+	default:
+		return fmt.Errorf("must be %q or %q", model.RoleRead, model.RoleAdmin)
+	}
+}
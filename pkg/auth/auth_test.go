@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/sevigo/shugosha/pkg/model"
+	"github.com/sevigo/shugosha/pkg/secrets"
+)
+
+func TestMiddleware(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	t.Setenv("SHUGOSHA_TEST_TOKEN", "admin-token")
+
+	authenticator, err := NewAuthenticator(&model.BackupConfig{API: &model.APIConfig{
+		Tokens: []model.APIToken{
+			{Name: "admin", Token: "env:SHUGOSHA_TEST_TOKEN", Role: model.RoleAdmin},
+			{Name: "monitoring", Token: "read-token", Role: model.RoleRead},
+		},
+		Users: []model.APIUser{{Username: "alice", PasswordHash: string(hash), Role: model.RoleRead}},
+	}}, secrets.NewResolver(nil))
+	assert.NoError(t, err)
+
+	handler := authenticator.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	status := func(method string, setup func(r *http.Request)) int {
+		r := httptest.NewRequest(method, "/api/config", nil)
+		setup(r)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+	bearer := func(token string) func(r *http.Request) {
+		return func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) }
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, status(http.MethodGet, func(r *http.Request) {}))
+	assert.Equal(t, http.StatusUnauthorized, status(http.MethodGet, bearer("wrong")))
+	assert.Equal(t, http.StatusOK, status(http.MethodPost, bearer("admin-token")))
+	assert.Equal(t, http.StatusOK, status(http.MethodGet, bearer("read-token")))
+	assert.Equal(t, http.StatusForbidden, status(http.MethodPost, bearer("read-token")))
+	assert.Equal(t, http.StatusOK, status(http.MethodGet, func(r *http.Request) { r.SetBasicAuth("alice", "secret") }))
+	assert.Equal(t, http.StatusUnauthorized, status(http.MethodGet, func(r *http.Request) { r.SetBasicAuth("alice", "wrong") }))
+
+	// Health probes need no credentials
+	r := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Without credentials the API is open
+	authenticator.ApplyConfig(&model.BackupConfig{})
+	assert.False(t, authenticator.Enabled())
+	assert.Equal(t, http.StatusOK, status(http.MethodPost, func(r *http.Request) {}))
+}
+
+func TestAllowOrigin(t *testing.T) {
+	authenticator, err := NewAuthenticator(&model.BackupConfig{API: &model.APIConfig{
+		AllowedOrigins: []string{"https://backup.example.com"},
+	}}, nil)
+	assert.NoError(t, err)
+
+	assert.True(t, authenticator.AllowOrigin(nil, "https://backup.example.com"))
+	assert.False(t, authenticator.AllowOrigin(nil, "https://evil.example.com"))
+
+	authenticator.ApplyConfig(&model.BackupConfig{})
+	assert.False(t, authenticator.AllowOrigin(nil, "https://backup.example.com"))
+}
+
+func TestValidate(t *testing.T) {
+	problems := Validate(&model.APIConfig{
+		Tokens: []model.APIToken{
+			{Name: "a", Token: "env:", Role: model.RoleAdmin},
+			{Name: "a", Token: "t", Role: "owner"},
+		},
+		Users:          []model.APIUser{{Username: "bob", PasswordHash: "plain", Role: model.RoleRead}},
+		AllowedOrigins: []string{"*", "https://ok.example.com", "example.com"},
+	})
+
+	assert.Equal(t, map[string]string{
+		"tokens[0].token":       "env reference without a name",
+		"tokens[1].name":        "is not unique",
+		"tokens[1].role":        `must be "read" or "admin"`,
+		"users[0].passwordHash": "must be a bcrypt hash",
+		"allowedOrigins[2]":     `must be "*" or an origin like "https://example.com"`,
+	}, problems)
+}
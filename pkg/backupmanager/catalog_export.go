@@ -0,0 +1,155 @@
+package backupmanager
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sevigo/shugosha/pkg/catalog"
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+const (
+	// CatalogExportName is the file name of the local catalog export.
+	CatalogExportName = model.CatalogExportObject
+	// defaultExportDelay collects the changes of a burst of backups into a
+	// single export.
+	defaultExportDelay = time.Minute
+)
+
+// EnableCatalogExport writes a catalog export into dir after the catalog
+// changed and uploads it to every provider that can store objects, so that
+// the catalog can be recovered when the machine is lost.
+func (m *BackupManager) EnableCatalogExport(dir string) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	m.exportDir = dir
+	m.exportChan = make(chan struct{}, 1)
+	go m.runCatalogExport()
+
+	m.catalogChanged()
+	return nil
+}
+
+// catalogChanged schedules a catalog export, if enabled.
+func (m *BackupManager) catalogChanged() {
+	if m.exportChan == nil {
+		return
+	}
+
+	select {
+	case m.exportChan <- struct{}{}:
+	default:
+	}
+}
+
+func (m *BackupManager) runCatalogExport() {
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-m.exportChan:
+		}
+
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-time.After(m.exportDelay):
+		}
+
+		if err := m.exportCatalog(); err != nil {
+			slog.Error("[manager] catalog export failed", "error", err)
+		}
+	}
+}
+
+// exportCatalog writes the export file and stores it with every provider.
+func (m *BackupManager) exportCatalog() error {
+	path := filepath.Join(m.exportDir, CatalogExportName)
+
+	size, err := writeExport(m.db, path)
+	if err != nil {
+		return err
+	}
+
+	for name, provider := range m.providers {
+		objects, ok := provider.(model.ObjectProvider)
+		if !ok {
+			continue
+		}
+		if err := m.uploadExport(path, size, provider, objects); err != nil {
+			slog.Error("[manager] failed to upload catalog export", "providerName", name, "error", err)
+			continue
+		}
+		slog.Debug("[manager] catalog export uploaded", "providerName", name, "size", size)
+	}
+
+	return nil
+}
+
+// uploadExport stores the export as a reserved object, which keeps it out of
+// the files of the provider. Like backups it waits for unhealthy providers
+// and respects their quota and upload limit.
+func (m *BackupManager) uploadExport(path string, size int64, provider model.Provider, objects model.ObjectProvider) error {
+	if !m.health.Healthy(provider.Name()) {
+		return errors.New("provider is unhealthy")
+	}
+	release, err := m.reserveQuota(model.Event{Size: size}, provider)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return objects.PutObject(model.CatalogExportObject, m.throttles.Limiter(provider.Name()).Reader(m.ctx, file))
+}
+
+// writeExport exports the catalog into a temporary file that replaces path
+// once complete, and returns its size.
+func writeExport(storage model.DB, path string) (int64, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), CatalogExportName+".*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	counter := &countingWriter{w: tmp}
+	if err := catalog.Export(storage, counter); err != nil {
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, err
+	}
+
+	return counter.n, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
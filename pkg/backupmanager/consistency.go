@@ -0,0 +1,129 @@
+package backupmanager
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/sevigo/shugosha/pkg/model"
+	"github.com/sevigo/shugosha/pkg/throttle"
+)
+
+const (
+	// defaultQuietPeriod is how long to wait before retrying a file that
+	// changed while it was backed up.
+	defaultQuietPeriod = 5 * time.Second
+	// maxConsistencyRetries is the number of retries before a backup is
+	// recorded as inconsistent.
+	maxConsistencyRetries = 3
+)
+
+// fileState is the part of the file state that changes when it is written.
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+func statFile(path string) (fileState, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}, err
+	}
+	return fileState{size: info.Size(), modTime: info.ModTime()}, nil
+}
+
+// backupConsistent runs the backup and verifies that the file did not change
+// while the provider was reading it. A file that changed is retried after a
+// quiet period; if it never settles the last copy is reported as inconsistent.
+// It returns the event describing what was backed up and the number of attempts.
+func (m *BackupManager) backupConsistent(event model.Event, provider model.Provider) (model.Event, int, bool, error) {
+	if event.Kind == model.KindSymlink {
+		return event, 1, true, provider.Backup(event, nil)
+	}
+
+	for attempt := 1; ; attempt++ {
+		before, err := statFile(event.Path)
+		if err != nil {
+			return event, attempt, false, err
+		}
+
+		sum, err := m.backup(event, provider)
+		if err != nil {
+			return event, attempt, false, err
+		}
+
+		after, err := statFile(event.Path)
+		if err != nil {
+			return event, attempt, false, err
+		}
+
+		if before == after && after.size == event.Size && sum == event.Checksum {
+			return event, attempt, true, nil
+		}
+
+		slog.Warn("[manager] file changed during backup", "providerName", provider.Name(), "file", event.Path, "attempt", attempt)
+		if attempt > maxConsistencyRetries {
+			return event, attempt, false, nil
+		}
+
+		select {
+		case <-m.ctx.Done():
+			return event, attempt, false, m.ctx.Err()
+		case <-time.After(m.quietPeriod):
+		}
+
+		// Back up the current state of the file on the next attempt
+		event.Checksum = sum
+		event.Size = after.size
+		event.Timestamp = time.Now()
+	}
+}
+
+// backup runs the provider backup and returns the checksum of the uploaded
+// content. The content is read once through the limits of the provider and
+// hashed as it is read. Anything a provider leaves unread is hashed after it
+// returned, so the checksum always covers the whole file as it was read.
+func (m *BackupManager) backup(event model.Event, provider model.Provider) (string, error) {
+	limiter := m.throttles.Limiter(provider.Name())
+
+	if streamProvider, ok := provider.(model.StreamProvider); ok {
+		return m.backupStream(event, streamProvider, limiter)
+	}
+
+	file, err := os.Open(event.Path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if err := provider.Backup(event, io.TeeReader(limiter.Reader(m.ctx, file), hash)); err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(hash, limiter.FileReader(m.ctx, file)); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// hashFile calculates the SHA256 checksum of the file, reading it through
+// the read limit of limiter.
+func hashFile(ctx context.Context, path string, limiter *throttle.Limiter) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, limiter.FileReader(ctx, file)); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
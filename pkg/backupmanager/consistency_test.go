@@ -0,0 +1,112 @@
+package backupmanager
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// writingProvider appends to the file while "reading" it for the first writes calls.
+type writingProvider struct {
+	writes int
+	calls  int
+}
+
+func (p *writingProvider) Backup(event model.Event, _ io.Reader) error {
+	p.calls++
+	if p.calls <= p.writes {
+		f, err := os.OpenFile(event.Path, os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = f.WriteString("more")
+		return err
+	}
+	return nil
+}
+
+func (p *writingProvider) DirectoryList() []string { return nil }
+func (p *writingProvider) Name() string            { return "Writing" }
+
+func newTestFile(t *testing.T, content string) model.Event {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "file")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return model.Event{
+		Path:     path,
+		Kind:     model.KindFile,
+		Checksum: fmt.Sprintf("%x", sha256.Sum256([]byte(content))),
+		Size:     int64(len(content)),
+	}
+}
+
+func TestBackupConsistentRetriesChangedFile(t *testing.T) {
+	m := &BackupManager{ctx: context.Background()}
+	provider := &writingProvider{writes: 1}
+
+	event, attempts, consistent, err := m.backupConsistent(newTestFile(t, "data"), provider)
+	assert.NoError(t, err)
+	assert.True(t, consistent)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, fmt.Sprintf("%x", sha256.Sum256([]byte("datamore"))), event.Checksum)
+}
+
+func TestBackupConsistentGivesUp(t *testing.T) {
+	m := &BackupManager{ctx: context.Background()}
+	provider := &writingProvider{writes: maxConsistencyRetries + 1}
+
+	_, attempts, consistent, err := m.backupConsistent(newTestFile(t, "data"), provider)
+	assert.NoError(t, err)
+	assert.False(t, consistent)
+	assert.Equal(t, maxConsistencyRetries+1, attempts)
+}
+
+// readingProvider keeps the content it was given.
+type readingProvider struct {
+	content []byte
+}
+
+func (p *readingProvider) Backup(_ model.Event, r io.Reader) error {
+	var err error
+	p.content, err = io.ReadAll(r)
+	return err
+}
+
+func (p *readingProvider) DirectoryList() []string { return nil }
+func (p *readingProvider) Name() string            { return "Reading" }
+
+func TestBackupHashesUploadedContent(t *testing.T) {
+	m := &BackupManager{ctx: context.Background()}
+	provider := &readingProvider{}
+
+	event := newTestFile(t, "data")
+	sum, err := m.backup(event, provider)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("data"), provider.content)
+	assert.Equal(t, event.Checksum, sum)
+}
+
+func TestBackupRespectsReadLimit(t *testing.T) {
+	provider := &readingProvider{}
+	m := newTestManager(t, &model.BackupConfig{Providers: []model.ProviderConfig{
+		{Name: "Reading", Throttle: &model.ThrottleConfig{ReadBytesPerSec: 256 << 10}},
+	}}, provider)
+
+	// The first burst is free, the rest has to wait
+	event := newTestFile(t, string(make([]byte, 384<<10)))
+	start := time.Now()
+	sum, err := m.backup(event, provider)
+	assert.NoError(t, err)
+	assert.Equal(t, event.Checksum, sum)
+	assert.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+}
@@ -4,36 +4,76 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"log/slog"
 	"sync"
+	"time"
 
+	"github.com/sevigo/shugosha/pkg/activity"
+	"github.com/sevigo/shugosha/pkg/catalog"
 	"github.com/sevigo/shugosha/pkg/fsmonitor"
+	"github.com/sevigo/shugosha/pkg/health"
+	"github.com/sevigo/shugosha/pkg/metrics"
 	"github.com/sevigo/shugosha/pkg/model"
+	"github.com/sevigo/shugosha/pkg/quota"
+	"github.com/sevigo/shugosha/pkg/throttle"
 )
 
 type BackupResult struct {
-	Path   string
-	Status string
-	Error  string
+	Path     string
+	Provider string
+	Status   string // "Success", "Linked", "Inconsistent", "Deferred", "Paused" or "Failed"
+	Error    string
+	Checksum string // Checksum of the backed up content
+	Attempts int    // Number of backup attempts, more than one if the file changed meanwhile
 }
 
 type BackupManager struct {
-	db         model.DB
-	providers  map[string]model.Provider
-	resultChan chan BackupResult
-	mu         sync.Mutex
-	ctx        context.Context
-	cancelFunc context.CancelFunc
+	db            model.DB
+	catalog       *catalog.Catalog
+	providers     map[string]model.Provider
+	throttles     *throttle.Manager
+	health        *health.Checker
+	quotas        *quota.Manager
+	activity      *activity.Broker
+	pending       *pendingQueue
+	paused        map[string]map[string]model.Event // Backups waiting for an unhealthy provider, by provider and path
+	pausedLock    sync.Mutex
+	resultChan    chan BackupResult
+	quietPeriod   time.Duration // Wait before retrying a file that changed during backup
+	quotaRetry    time.Duration // Wait before retrying a backup deferred by a quota
+	progressFuncs []model.ProgressFunc
+	progressLock  sync.Mutex
+	exportDir     string        // Directory of the catalog export, empty if disabled
+	exportChan    chan struct{} // Signals catalog changes to the exporter
+	exportDelay   time.Duration // Wait for more changes before exporting
+	verifyReports map[string]*model.VerifyReport
+	verifyLock    sync.Mutex
+	reserved      map[string]*reservation // Quota of uploads in progress, by provider
+	reservedLock  sync.Mutex
+	rebuilding    map[string]map[string]bool // Paths backed up during a catalog rebuild, by provider
+	mu            sync.Mutex
+	ctx           context.Context
+	cancelFunc    context.CancelFunc
 }
 
-func NewBackupManager(storage model.DB, monitor *fsmonitor.Monitor, providers map[string]model.Provider) (*BackupManager, error) {
+func NewBackupManager(storage model.DB, monitor *fsmonitor.Monitor, providers map[string]model.Provider, throttles *throttle.Manager, checker *health.Checker, quotas *quota.Manager, broker *activity.Broker) (*BackupManager, error) {
 	ctx, cancelFunc := context.WithCancel(context.Background())
 	bm := &BackupManager{
-		db:         storage,
-		providers:  providers,
-		resultChan: make(chan BackupResult, 10),
-		ctx:        ctx,
-		cancelFunc: cancelFunc,
+		db:          storage,
+		catalog:     catalog.New(storage),
+		providers:   providers,
+		throttles:   throttles,
+		health:      checker,
+		quotas:      quotas,
+		activity:    broker,
+		pending:     newPendingQueue(),
+		resultChan:  make(chan BackupResult, 10),
+		quietPeriod: defaultQuietPeriod,
+		exportDelay: defaultExportDelay,
+		quotaRetry:  defaultQuotaRetryInterval,
+		ctx:         ctx,
+		cancelFunc:  cancelFunc,
 	}
 
 	for _, rootDir := range monitor.RootDirs() {
@@ -49,6 +89,7 @@ func NewBackupManager(storage model.DB, monitor *fsmonitor.Monitor, providers ma
 		return nil, err
 	}
 
+	monitor.SetReadThrottle(bm.readThrottle)
 	monitor.Subscribe(bm)
 
 	return bm, nil
@@ -71,11 +112,24 @@ func (m *BackupManager) HandleEvent(event model.Event) {
 
 	for name, provider := range m.providers {
 		if isSubscribed(event.Root, provider) {
+			m.activity.Publish(model.ActivityEvent{Type: model.ActivityDetected, Provider: name, Root: event.Root, Path: event.Path})
+			m.pending.add(name, event.Path, event.Timestamp)
 			go m.backupIfNeeded(event, name, provider)
 		}
 	}
 }
 
+// readThrottle applies the read limits of every provider backing up root to
+// a file read by the monitor.
+func (m *BackupManager) readThrottle(root string, r io.Reader) io.Reader {
+	for _, provider := range m.providers {
+		if isSubscribed(root, provider) {
+			r = m.throttles.Limiter(provider.Name()).FileReader(m.ctx, r)
+		}
+	}
+	return r
+}
+
 func isSubscribed(root string, provider model.Provider) bool {
 	slog.Debug("[manager] is subscribed", "root", root, "dirs", provider.DirectoryList())
 
@@ -91,12 +145,20 @@ func isSubscribed(root string, provider model.Provider) bool {
 func (m *BackupManager) backupIfNeeded(event model.Event, providerName string, provider model.Provider) {
 	slog.Debug("[manager] backup if needed", "providerName", providerName, "file", event.Path)
 
+	// Backups of an unhealthy provider wait in a queue until it recovers
+	if !m.health.Healthy(providerName) {
+		m.pauseBackup(event, provider)
+		return
+	}
+
 	select {
 	case <-m.ctx.Done():
-		return
+		m.pending.done(providerName, event.Path)
 	default:
 		if m.isBackupNeeded(event.Path, event.Checksum, providerName) {
 			m.processBackup(event, provider)
+		} else {
+			m.pending.done(providerName, event.Path)
 		}
 	}
 }
@@ -104,41 +166,140 @@ func (m *BackupManager) backupIfNeeded(event model.Event, providerName string, p
 func (m *BackupManager) processBackup(event model.Event, provider model.Provider) {
 	slog.Debug("[manager] process backup", "providerName", provider.Name(), "file", event.Path)
 
-	result := BackupResult{Path: event.Path, Status: "Success"}
-	if err := provider.Backup(event); err != nil {
+	if linker, ok := provider.(model.LinkProvider); ok && m.isHardlinkBackedUp(event, provider.Name()) {
+		slog.Debug("[manager] hard link already backed up", "providerName", provider.Name(), "file", event.Path, "origin", event.HardlinkOf)
+		err := linker.Link(event)
+		if err == nil {
+			m.updateLinkRecord(provider.Name(), event)
+			m.sendResult(event, BackupResult{Path: event.Path, Provider: provider.Name(), Status: "Linked", Checksum: event.Checksum, Attempts: 1})
+			return
+		}
+		slog.Warn("[manager] failed to store hard link, backing up content", "providerName", provider.Name(), "file", event.Path, "error", err)
+	}
+
+	// The content is uploaded with this file, so it is no longer a link
+	event.HardlinkOf = ""
+
+	m.activity.Publish(model.ActivityEvent{Type: model.ActivityStarted, Provider: provider.Name(), Root: event.Root, Path: event.Path})
+	result := BackupResult{Path: event.Path, Provider: provider.Name(), Status: "Success"}
+	start := time.Now()
+
+	release, err := m.reserveQuota(event, provider)
+	if err != nil {
+		result.Status = "Failed"
+		result.Error = err.Error()
+		if m.quotas.Action(provider.Name()) == model.QuotaDefer {
+			result.Status = "Deferred"
+			m.deferBackup(event, provider)
+		}
+		slog.Warn("[manager] backup exceeds quota", "providerName", provider.Name(), "file", event.Path, "error", err)
+		m.sendResult(event, result)
+		return
+	}
+	// The reservation ends once the backup is recorded or failed
+	defer release()
+
+	backedUp, attempts, consistent, err := m.backupConsistent(event, provider)
+	result.Attempts = attempts
+	result.Checksum = backedUp.Checksum
+
+	switch {
+	case err != nil && !m.health.Recheck(m.ctx, provider.Name()):
+		slog.Warn("[manager] provider became unhealthy, backup paused", "providerName", provider.Name(), "file", event.Path, "error", err)
+		m.pauseBackup(event, provider)
+		return
+
+	case err != nil:
 		result.Status = "Failed"
 		result.Error = err.Error()
 		slog.Error("Backup failed", "error", err, "path", event.Path)
-	} else {
-		m.updateRecord(provider.Name(), event)
+
+	case !consistent:
+		result.Status = "Inconsistent"
+		result.Error = "file kept changing during backup"
+		backedUp.Inconsistent = true
+		m.updateRecord(provider.Name(), backedUp)
+		m.warnQuota(provider.Name())
+
+	default:
+		m.updateRecord(provider.Name(), backedUp)
+		m.warnQuota(provider.Name())
+	}
+
+	metrics.BackupDuration.WithLabelValues(provider.Name()).Observe(time.Since(start).Seconds())
+	m.sendResult(event, result)
+}
+
+// sendResult publishes the result as an activity event and sends it to
+// Results. Deferred and paused backups stay pending until they are tried
+// again.
+func (m *BackupManager) sendResult(event model.Event, result BackupResult) {
+	metrics.Backups.WithLabelValues(result.Provider, result.Status).Inc()
+	if result.Status != "Deferred" && result.Status != "Paused" {
+		m.pending.done(result.Provider, event.Path)
+	}
+
+	activityType := model.ActivitySucceeded
+	switch result.Status {
+	case "Success", "Linked":
+	case "Paused":
+		activityType = model.ActivityPaused
+	default:
+		activityType = model.ActivityFailed
 	}
-	
+	m.activity.Publish(model.ActivityEvent{
+		Type:     activityType,
+		Provider: result.Provider,
+		Root:     event.Root,
+		Path:     result.Path,
+		Status:   result.Status,
+		Error:    result.Error,
+	})
+
 	m.resultChan <- result
 }
 
+// isHardlinkBackedUp reports whether the file the event is a hard link of was
+// already backed up by the provider with the same content.
+func (m *BackupManager) isHardlinkBackedUp(event model.Event, providerName string) bool {
+	if event.HardlinkOf == "" {
+		return false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record, err := m.getRecord(catalog.RecordKey(providerName, event.HardlinkOf))
+	if err != nil {
+		return false
+	}
+
+	return record.Checksum == event.Checksum
+}
+
 func (m *BackupManager) isBackupNeeded(path, checksum, providerName string) bool {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	key := providerName + ":" + path
+	key := catalog.RecordKey(providerName, path)
 	record, err := m.getRecord(key)
 	if err != nil {
 		return true
 	}
 
-	return record.Checksum != checksum
+	return record.Inconsistent || record.Checksum != checksum
 }
 
-func (m *BackupManager) getRecord(key string) (*model.Event, error) {
+func (m *BackupManager) getRecord(key string) (*model.FileRecord, error) {
 	value, err := m.db.Get(key)
 	if errors.Is(err, model.ErrDBKeyNotFound) {
-		return &model.Event{}, nil
+		return &model.FileRecord{}, nil
 	} else if err != nil {
 		slog.Error("[BackupManager] Error accessing DB", "error", err)
 		return nil, err
 	}
 
-	var record model.Event
+	var record model.FileRecord
 	if err := json.Unmarshal(value, &record); err != nil {
 		slog.Error("[BackupManager] Error unmarshaling file record", "error", err)
 		return nil, err
@@ -146,6 +307,11 @@ func (m *BackupManager) getRecord(key string) (*model.Event, error) {
 	return &record, nil
 }
 
+// SearchFiles queries the catalog of backed up files.
+func (m *BackupManager) SearchFiles(query model.FileQuery) (*model.FileQueryResult, error) {
+	return m.catalog.SearchFiles(query)
+}
+
 func (m *BackupManager) Close() error {
 	m.cancelFunc()
 	close(m.resultChan)
@@ -0,0 +1,108 @@
+package backupmanager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sevigo/shugosha/pkg/activity"
+	"github.com/sevigo/shugosha/pkg/db"
+	"github.com/sevigo/shugosha/pkg/fsmonitor"
+	"github.com/sevigo/shugosha/pkg/health"
+	"github.com/sevigo/shugosha/pkg/model"
+	"github.com/sevigo/shugosha/pkg/provider/local"
+	"github.com/sevigo/shugosha/pkg/quota"
+	"github.com/sevigo/shugosha/pkg/throttle"
+)
+
+// newTestManager creates a backup manager for the providers of cfg, which
+// must be given in the same order as their configuration.
+func newTestManager(t *testing.T, cfg *model.BackupConfig, providers ...model.Provider) *BackupManager {
+	t.Helper()
+
+	monitor, err := fsmonitor.New(fsmonitor.DefaultConfig())
+	assert.NoError(t, err)
+	throttles, err := throttle.NewManager(cfg)
+	assert.NoError(t, err)
+	quotas, err := quota.NewManager(cfg)
+	assert.NoError(t, err)
+
+	byName := map[string]model.Provider{}
+	for _, provider := range providers {
+		byName[provider.Name()] = provider
+	}
+
+	m, err := NewBackupManager(db.NewMemoryDB(), monitor, byName, throttles, health.NewChecker(), quotas, activity.NewBroker(0))
+	assert.NoError(t, err)
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+// newLocalProvider creates a local provider writing below a temporary directory.
+func newLocalProvider(t *testing.T, root string) (model.Provider, string) {
+	t.Helper()
+
+	dest := t.TempDir()
+	provider, err := local.NewLocalProvider(&model.ProviderConfig{
+		Name:          "Local",
+		DirectoryList: []string{root},
+		Settings:      map[string]string{"path": dest},
+	})
+	assert.NoError(t, err)
+	return provider, dest
+}
+
+func TestRestoreHardlink(t *testing.T) {
+	source := t.TempDir()
+	provider, _ := newLocalProvider(t, source)
+	m := newTestManager(t, &model.BackupConfig{}, provider)
+
+	origin := filepath.Join(source, "origin")
+	link := filepath.Join(source, "link")
+	assert.NoError(t, os.WriteFile(origin, []byte("shared content"), 0o640))
+	assert.NoError(t, os.Link(origin, link))
+
+	event := newTestEvent(t, source, origin)
+	m.processBackup(event, provider)
+	assert.Equal(t, "Success", (<-m.Results()).Status)
+
+	linked := newTestEvent(t, source, link)
+	linked.HardlinkOf = origin
+	m.processBackup(linked, provider)
+	assert.Equal(t, "Linked", (<-m.Results()).Status)
+
+	// The link is restored from the content stored with its origin
+	target := filepath.Join(t.TempDir(), "restored")
+	assert.NoError(t, m.Restore(model.RestoreRequest{Provider: "Local", Path: link, Target: target, SkipOwnership: true}))
+	data, err := os.ReadFile(target)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("shared content"), data)
+
+	// The provider keeps the link, so a rebuilt catalog still has it
+	result, err := m.RebuildCatalog("Local")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Files)
+	assert.Equal(t, uint64(len("shared content")), result.Size)
+}
+
+// newTestEvent describes an existing file the way the monitor would.
+func newTestEvent(t *testing.T, root, path string) model.Event {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	sum, err := hashFile(context.Background(), path, nil)
+	assert.NoError(t, err)
+
+	return model.Event{
+		Root:     root,
+		Path:     path,
+		Type:     "added",
+		Kind:     model.KindFile,
+		Checksum: sum,
+		Size:     int64(len(data)),
+	}
+}
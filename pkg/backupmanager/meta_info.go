@@ -31,27 +31,42 @@ func (m *BackupManager) GetMetaInfo(providerName string) (*model.ProviderMetaInf
 }
 
 func (m *BackupManager) updateTotalSize(providerName, rootDir string, size int64) {
+	err := m.db.Update(func(txn model.Txn) error {
+		return addTotalSize(txn, providerName, rootDir, size)
+	})
+	if err != nil {
+		slog.Error("Failed to update provider meta info", "error", err)
+	}
+}
+
+// addTotalSize adds size to the total of the root directory of the provider,
+// a negative size is subtracted down to zero.
+func addTotalSize(txn model.Txn, providerName, rootDir string, size int64) error {
 	key := fmt.Sprintf("meta:%s", providerName)
 	slog.Debug("[BackupManager] update total size", "providerName", providerName, "key", key, "size", size, "root", rootDir)
 
-	providerMeta, err := m.getProviderMeta(key, providerName)
+	providerMeta, err := getProviderMeta(txn, key, providerName)
 	if err != nil {
-		slog.Error("Failed to get or unmarshal provider meta info", "error", err)
-		return
+		return fmt.Errorf("failed to get or unmarshal provider meta info: %w", err)
 	}
 
 	// Update the size for the specified directory
-	providerMeta.Directories[rootDir] += uint64(size)
+	if size < 0 && uint64(-size) > providerMeta.Directories[rootDir] {
+		providerMeta.Directories[rootDir] = 0
+	} else {
+		providerMeta.Directories[rootDir] += uint64(size)
+	}
 	slog.Debug("[BackupManager] new total size is", "providerName", providerName, "key", key, "size", providerMeta.Directories[rootDir], "root", rootDir)
 
 	// Marshal and save the updated provider meta
-	if err := m.saveProviderMeta(key, providerMeta); err != nil {
-		slog.Error("Failed to marshal or save provider meta info", "error", err)
+	if err := saveProviderMeta(txn, key, providerMeta); err != nil {
+		return fmt.Errorf("failed to marshal or save provider meta info: %w", err)
 	}
+	return nil
 }
 
-func (m *BackupManager) getProviderMeta(key, providerName string) (*model.ProviderMetaInfo, error) {
-	value, err := m.db.Get(key)
+func getProviderMeta(txn model.Txn, key, providerName string) (*model.ProviderMetaInfo, error) {
+	value, err := txn.Get(key)
 	if err != nil && !errors.Is(err, model.ErrDBKeyNotFound) {
 		return nil, err
 	}
@@ -65,12 +80,12 @@ func (m *BackupManager) getProviderMeta(key, providerName string) (*model.Provid
 	return providerMeta, nil
 }
 
-func (m *BackupManager) saveProviderMeta(key string, providerMeta *model.ProviderMetaInfo) error {
+func saveProviderMeta(txn model.Txn, key string, providerMeta *model.ProviderMetaInfo) error {
 	updatedValue, err := json.Marshal(providerMeta)
 	if err != nil {
 		return err
 	}
-	return m.db.Set(key, updatedValue)
+	return txn.Set(key, updatedValue)
 }
 
 func (b *BackupManager) SetProviders(providers []string) error {
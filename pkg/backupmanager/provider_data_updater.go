@@ -1,31 +1,92 @@
 package backupmanager
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 
+	"github.com/sevigo/shugosha/pkg/catalog"
 	"github.com/sevigo/shugosha/pkg/model"
 )
 
+// updateRecord stores the record of a backed up file and updates the provider
+// totals in a single transaction, so both can never diverge. The new copy
+// replaces the previous one, so only the difference in size is added.
 func (m *BackupManager) updateRecord(providerName string, event model.Event) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	key := providerName + ":" + event.Path
+	key := catalog.RecordKey(providerName, event.Path)
 	slog.Debug("[BackupManager] update record in db", "providerName", providerName, "key", key)
 
-	if err := m.saveEvent(key, event); err != nil {
-		slog.Error("Failed to save event to DB", "error", err, "key", key)
+	err := m.db.Update(func(txn model.Txn) error {
+		if err := releaseStoredSize(txn, providerName, event.Path); err != nil {
+			return err
+		}
+		if err := catalog.Put(txn, catalog.FromEvent(providerName, event)); err != nil {
+			return fmt.Errorf("failed to save event: %w", err)
+		}
+		return addTotalSize(txn, providerName, event.Root, event.Size)
+	})
+	if err != nil {
+		slog.Error("Failed to update record in DB", "error", err, "key", key)
+		return
 	}
-
-	m.updateTotalSize(providerName, event.Root, event.Size)
+	m.recordedDuringRebuild(providerName, event.Path)
+	m.catalogChanged()
 }
 
-func (m *BackupManager) saveEvent(key string, event model.Event) error {
-	eventBytes, err := json.Marshal(event)
+// updateLinkRecord stores a record for a hard link whose content was already
+// uploaded, without counting its size a second time. A copy stored for the
+// path before is replaced by the link.
+func (m *BackupManager) updateLinkRecord(providerName string, event model.Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	err := m.db.Update(func(txn model.Txn) error {
+		if err := releaseStoredSize(txn, providerName, event.Path); err != nil {
+			return err
+		}
+		return catalog.Put(txn, catalog.FromEvent(providerName, event))
+	})
 	if err != nil {
-		return err
+		slog.Error("Failed to save event to DB", "error", err, "key", catalog.RecordKey(providerName, event.Path))
+		return
+	}
+	m.recordedDuringRebuild(providerName, event.Path)
+	m.catalogChanged()
+}
+
+// recordedDuringRebuild keeps the record of path if the catalog of the
+// provider is being rebuilt, the listing may predate the backup. The caller
+// holds m.mu.
+func (m *BackupManager) recordedDuringRebuild(providerName, path string) {
+	if backedUp, ok := m.rebuilding[providerName]; ok {
+		backedUp[path] = true
+	}
+}
+
+// storedSize returns the root and size the provider totals hold for the copy
+// of path, which is nothing for hard links and files not backed up yet.
+func storedSize(txn model.Txn, providerName, path string) (string, int64, error) {
+	record, err := catalog.Get(txn, providerName, path)
+	if errors.Is(err, model.ErrDBKeyNotFound) {
+		return "", 0, nil
+	} else if err != nil {
+		return "", 0, err
 	}
+	if record.HardlinkOf != "" {
+		return "", 0, nil
+	}
+	return record.Root, record.Size, nil
+}
 
-	return m.db.Set(key, eventBytes)
+// releaseStoredSize removes the size of the current copy of path from the
+// provider totals.
+func releaseStoredSize(txn model.Txn, providerName, path string) error {
+	root, size, err := storedSize(txn, providerName, path)
+	if err != nil || size == 0 {
+		return err
+	}
+	return addTotalSize(txn, providerName, root, -size)
 }
@@ -0,0 +1,130 @@
+package backupmanager
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// pendingQueue tracks detected changes until their backup finishes, including
+// backups waiting for a provider to recover or deferred by a quota.
+type pendingQueue struct {
+	mu      sync.Mutex
+	changes map[string]*pendingChange // By provider and path
+}
+
+type pendingChange struct {
+	provider string
+	count    int       // Backups of the path in progress
+	detected time.Time // Detection time of the first of them
+}
+
+func newPendingQueue() *pendingQueue {
+	return &pendingQueue{changes: map[string]*pendingChange{}}
+}
+
+func (q *pendingQueue) add(provider, path string, detected time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if detected.IsZero() {
+		detected = time.Now()
+	}
+
+	key := provider + ":" + path
+	change, ok := q.changes[key]
+	if !ok {
+		change = &pendingChange{provider: provider, detected: detected}
+		q.changes[key] = change
+	}
+	change.count++
+}
+
+func (q *pendingQueue) done(provider, path string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	key := provider + ":" + path
+	change, ok := q.changes[key]
+	if !ok {
+		return
+	}
+	if change.count--; change.count <= 0 {
+		delete(q.changes, key)
+	}
+}
+
+// PendingBackups returns the number of changed files waiting for their backup
+// and the oldest detection time per provider.
+func (m *BackupManager) PendingBackups() map[string]model.PendingBackups {
+	m.pending.mu.Lock()
+	defer m.pending.mu.Unlock()
+
+	result := make(map[string]model.PendingBackups, len(m.providers))
+	for name := range m.providers {
+		result[name] = model.PendingBackups{}
+	}
+	for _, change := range m.pending.changes {
+		pending := result[change.provider]
+		pending.Count++
+		if pending.Oldest.IsZero() || change.detected.Before(pending.Oldest) {
+			pending.Oldest = change.detected
+		}
+		result[change.provider] = pending
+	}
+	return result
+}
+
+// pauseBackup queues the backup until the provider is healthy again. Only the
+// latest event of a path is kept, and a single goroutine per provider waits
+// for it to recover.
+func (m *BackupManager) pauseBackup(event model.Event, provider model.Provider) {
+	name := provider.Name()
+
+	m.pausedLock.Lock()
+	if m.paused == nil {
+		m.paused = map[string]map[string]model.Event{}
+	}
+	queue, waiting := m.paused[name]
+	if !waiting {
+		queue = map[string]model.Event{}
+		m.paused[name] = queue
+	}
+	if _, queued := queue[event.Path]; queued {
+		// The queued event is replaced by the newer one
+		m.pending.done(name, event.Path)
+	}
+	queue[event.Path] = event
+	m.pausedLock.Unlock()
+
+	if !waiting {
+		go m.resumeBackups(name, provider)
+	}
+
+	m.sendResult(event, BackupResult{Path: event.Path, Provider: name, Status: "Paused", Error: "provider is unhealthy"})
+}
+
+// resumeBackups waits for the provider to recover and then backs up the
+// paused files one after the other.
+func (m *BackupManager) resumeBackups(name string, provider model.Provider) {
+	err := m.health.WaitHealthy(m.ctx, name)
+
+	m.pausedLock.Lock()
+	queue := m.paused[name]
+	delete(m.paused, name)
+	m.pausedLock.Unlock()
+
+	if err != nil {
+		for path := range queue {
+			m.pending.done(name, path)
+		}
+		return
+	}
+
+	slog.Info("[manager] provider recovered, resuming paused backups", "providerName", name, "files", len(queue))
+	for _, event := range queue {
+		m.backupIfNeeded(event, name, provider)
+	}
+}
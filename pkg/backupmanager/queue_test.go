@@ -0,0 +1,70 @@
+package backupmanager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sevigo/shugosha/pkg/model"
+	"github.com/sevigo/shugosha/pkg/provider/local"
+)
+
+func TestPendingBackups(t *testing.T) {
+	m := &BackupManager{providers: map[string]model.Provider{"Local": nil, "Echo": nil}, pending: newPendingQueue()}
+	first := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	m.pending.add("Local", "/data/a.txt", first)
+	m.pending.add("Local", "/data/a.txt", first.Add(time.Second))
+	m.pending.add("Local", "/data/b.txt", first.Add(time.Minute))
+
+	assert.Equal(t, map[string]model.PendingBackups{
+		"Local": {Count: 2, Oldest: first},
+		"Echo":  {},
+	}, m.PendingBackups())
+
+	// The first path stays pending until both of its backups are done
+	m.pending.done("Local", "/data/a.txt")
+	assert.Equal(t, 2, m.PendingBackups()["Local"].Count)
+	m.pending.done("Local", "/data/a.txt")
+	assert.Equal(t, model.PendingBackups{Count: 1, Oldest: first.Add(time.Minute)}, m.PendingBackups()["Local"])
+}
+
+func TestUnhealthyProviderPausesQueue(t *testing.T) {
+	source := t.TempDir()
+	// A file in place of the destination directory fails the health check
+	dest := filepath.Join(t.TempDir(), "dest")
+	assert.NoError(t, os.WriteFile(dest, nil, 0o600))
+	provider, err := local.NewLocalProvider(&model.ProviderConfig{Name: "Local", DirectoryList: []string{source}, Settings: map[string]string{"path": dest}})
+	assert.NoError(t, err)
+	m := newTestManager(t, &model.BackupConfig{}, provider)
+	assert.False(t, m.health.Add(provider).Healthy)
+
+	path := filepath.Join(source, "file")
+	assert.NoError(t, os.WriteFile(path, []byte("first"), 0o600))
+	m.HandleEvent(newTestEvent(t, source, path))
+	assert.Equal(t, "Paused", (<-m.Results()).Status)
+
+	// Only the latest change of the file stays queued
+	assert.NoError(t, os.WriteFile(path, []byte("second"), 0o600))
+	latest := newTestEvent(t, source, path)
+	m.HandleEvent(latest)
+	assert.Equal(t, "Paused", (<-m.Results()).Status)
+	assert.Equal(t, 1, m.PendingBackups()["Local"].Count)
+
+	assert.NoError(t, os.Remove(dest))
+	assert.True(t, m.health.Recheck(context.Background(), "Local"))
+
+	result := <-m.Results()
+	assert.Equal(t, "Success", result.Status)
+	assert.Equal(t, latest.Checksum, result.Checksum)
+	assert.Equal(t, 0, m.PendingBackups()["Local"].Count)
+	select {
+	case result := <-m.Results():
+		t.Fatalf("unexpected result %+v", result)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
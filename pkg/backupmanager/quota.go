@@ -0,0 +1,115 @@
+package backupmanager
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// defaultQuotaRetryInterval is how long a backup deferred by a quota waits
+// before it is tried again.
+const defaultQuotaRetryInterval = 10 * time.Minute
+
+// reservation holds the bytes of the uploads of a provider that passed the
+// quota check but are not recorded yet, by watched directory.
+type reservation struct {
+	mu    sync.Mutex
+	roots map[string]uint64
+}
+
+// reserveQuota returns an error if the backup would exceed a quota or the
+// free space of the provider. Otherwise the size of the backup is reserved
+// until release is called, so that concurrent backups are checked against
+// each other. A copy the provider already stores of the file is replaced, so
+// only the growth counts.
+func (m *BackupManager) reserveQuota(event model.Event, provider model.Provider) (release func(), err error) {
+	r := m.reservation(provider.Name())
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	usage, err := m.GetMetaInfo(provider.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	var stored int64
+	if err := m.db.View(func(txn model.Txn) error {
+		_, stored, err = storedSize(txn, provider.Name(), event.Path)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	size := uint64(max(event.Size-stored, 0))
+
+	if usage == nil || usage.Directories == nil {
+		// Nothing is recorded yet, but uploads may be reserved
+		usage = &model.ProviderMetaInfo{Name: provider.Name(), Directories: map[string]uint64{}}
+	}
+	var reserved uint64
+	for root, bytes := range r.roots {
+		usage.Directories[root] += bytes
+		reserved += bytes
+	}
+
+	var free *uint64
+	if spaceProvider, ok := provider.(model.SpaceProvider); ok {
+		if space, err := spaceProvider.FreeSpace(); err != nil {
+			slog.Debug("[manager] failed to get free space", "providerName", provider.Name(), "error", err)
+		} else {
+			// Reserved uploads still take their space
+			space -= min(space, reserved)
+			free = &space
+		}
+	}
+
+	if err := m.quotas.Check(provider.Name(), event.Root, usage, size, free); err != nil {
+		return nil, err
+	}
+
+	r.roots[event.Root] += size
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.roots[event.Root] -= size; r.roots[event.Root] == 0 {
+			delete(r.roots, event.Root)
+		}
+	}, nil
+}
+
+func (m *BackupManager) reservation(providerName string) *reservation {
+	m.reservedLock.Lock()
+	defer m.reservedLock.Unlock()
+
+	if m.reserved == nil {
+		m.reserved = map[string]*reservation{}
+	}
+	r, ok := m.reserved[providerName]
+	if !ok {
+		r = &reservation{roots: map[string]uint64{}}
+		m.reserved[providerName] = r
+	}
+	return r
+}
+
+// warnQuota raises a warning if the provider is close to its quota.
+func (m *BackupManager) warnQuota(providerName string) {
+	usage, err := m.GetMetaInfo(providerName)
+	if err != nil {
+		return
+	}
+	m.quotas.Warn(providerName, usage)
+}
+
+// deferBackup tries the backup again after the quota retry interval.
+func (m *BackupManager) deferBackup(event model.Event, provider model.Provider) {
+	go func() {
+		select {
+		case <-m.ctx.Done():
+			m.pending.done(provider.Name(), event.Path)
+		case <-time.After(m.quotaRetry):
+			m.backupIfNeeded(event, provider.Name(), provider)
+		}
+	}()
+}
@@ -0,0 +1,67 @@
+package backupmanager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sevigo/shugosha/pkg/model"
+	"github.com/sevigo/shugosha/pkg/quota"
+)
+
+func TestBackupAgainCountsOnlyTheGrowth(t *testing.T) {
+	source := t.TempDir()
+	provider, _ := newLocalProvider(t, source)
+	m := newTestManager(t, &model.BackupConfig{Providers: []model.ProviderConfig{
+		{Name: "Local", Type: "Local", DirectoryList: []string{source}, Quota: &model.QuotaConfig{MaxBytes: 15}},
+	}}, provider)
+
+	path := filepath.Join(source, "file")
+	assert.NoError(t, os.WriteFile(path, []byte("0123456789"), 0o600))
+	event := newTestEvent(t, source, path)
+
+	// The second copy replaces the first, so both fit into the quota
+	for i := 0; i < 2; i++ {
+		m.processBackup(event, provider)
+		assert.Equal(t, "Success", (<-m.Results()).Status)
+	}
+	meta, err := m.GetMetaInfo("Local")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), meta.Directories[source])
+
+	assert.NoError(t, os.WriteFile(path, []byte("0123"), 0o600))
+	m.processBackup(newTestEvent(t, source, path), provider)
+	assert.Equal(t, "Success", (<-m.Results()).Status)
+	meta, err = m.GetMetaInfo("Local")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), meta.Directories[source])
+
+	// Growing past the quota is still refused
+	assert.NoError(t, os.WriteFile(path, []byte("0123456789abcdef"), 0o600))
+	m.processBackup(newTestEvent(t, source, path), provider)
+	assert.Equal(t, "Failed", (<-m.Results()).Status)
+}
+
+func TestConcurrentBackupsReserveQuota(t *testing.T) {
+	source := t.TempDir()
+	provider, _ := newLocalProvider(t, source)
+	m := newTestManager(t, &model.BackupConfig{Providers: []model.ProviderConfig{
+		{Name: "Local", Type: "Local", DirectoryList: []string{source}, Quota: &model.QuotaConfig{MaxBytes: 15}},
+	}}, provider)
+
+	first := model.Event{Root: source, Path: filepath.Join(source, "a"), Size: 10}
+	second := model.Event{Root: source, Path: filepath.Join(source, "b"), Size: 10}
+
+	// The upload of the first file is not recorded yet, but still counts
+	release, err := m.reserveQuota(first, provider)
+	assert.NoError(t, err)
+	_, err = m.reserveQuota(second, provider)
+	assert.ErrorIs(t, err, quota.ErrExceeded)
+
+	release()
+	release, err = m.reserveQuota(second, provider)
+	assert.NoError(t, err)
+	release()
+}
@@ -0,0 +1,139 @@
+package backupmanager
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/sevigo/shugosha/pkg/catalog"
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// Ensure BackupManager satisfies the CatalogRebuilder interface
+var _ model.CatalogRebuilder = (*BackupManager)(nil)
+
+// RebuildCatalog replaces the records and totals of the provider with what
+// the provider actually stores. This makes restores work on a new machine
+// that lost its catalog. The provider is listed while backups go on; files
+// backed up meanwhile keep the record of their backup.
+func (m *BackupManager) RebuildCatalog(providerName string) (*model.RebuildResult, error) {
+	provider, ok := m.providers[providerName]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", model.ErrUnknownProvider, providerName)
+	}
+
+	lister, ok := provider.(model.ListProvider)
+	if !ok {
+		return nil, fmt.Errorf("provider %q does not support listing its files", providerName)
+	}
+
+	m.mu.Lock()
+	if _, running := m.rebuilding[providerName]; running {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("catalog of provider %q is already being rebuilt", providerName)
+	}
+	if m.rebuilding == nil {
+		m.rebuilding = map[string]map[string]bool{}
+	}
+	m.rebuilding[providerName] = map[string]bool{}
+	m.mu.Unlock()
+
+	slog.Info("[manager] rebuilding catalog", "providerName", providerName)
+
+	listed := map[string]*model.FileRecord{}
+	err := lister.List(func(record *model.FileRecord) error {
+		listed[record.Path] = record
+		return nil
+	})
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	backedUp := m.rebuilding[providerName]
+	delete(m.rebuilding, providerName)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files of provider %q: %w", providerName, err)
+	}
+	return m.reconcileCatalog(provider, listed, backedUp)
+}
+
+// reconcileCatalog writes the listed records and removes the ones of files
+// the provider does not store, except for the files backed up during the
+// listing. The caller holds m.mu.
+func (m *BackupManager) reconcileCatalog(provider model.Provider, listed map[string]*model.FileRecord, backedUp map[string]bool) (*model.RebuildResult, error) {
+	providerName := provider.Name()
+
+	stale, err := m.recordPaths(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &model.RebuildResult{Provider: providerName}
+	providerMeta := &model.ProviderMetaInfo{Name: providerName, Directories: map[string]uint64{}}
+	for _, dir := range provider.DirectoryList() {
+		providerMeta.Directories[dir] = 0
+	}
+	addSize := func(record *model.FileRecord) {
+		// Hard links share the content of the file they link to
+		if record.HardlinkOf == "" && record.Size > 0 {
+			providerMeta.Directories[record.Root] += uint64(record.Size)
+			result.Size += uint64(record.Size)
+		}
+	}
+
+	batch := &model.Batch{}
+	for path, record := range listed {
+		delete(stale, path)
+		result.Files++
+		if backedUp[path] {
+			continue
+		}
+		if err := catalog.PutBatch(m.db, batch, record); err != nil {
+			return nil, err
+		}
+		addSize(record)
+	}
+
+	for path := range backedUp {
+		delete(stale, path)
+		record, err := m.getRecord(catalog.RecordKey(providerName, path))
+		if err != nil {
+			return nil, err
+		}
+		addSize(record)
+	}
+
+	for path := range stale {
+		if err := catalog.DeleteBatch(m.db, batch, providerName, path); err != nil {
+			return nil, err
+		}
+		result.Removed++
+	}
+
+	if err := m.db.WriteBatch(batch); err != nil {
+		return nil, err
+	}
+	if err := m.db.Update(func(txn model.Txn) error {
+		return saveProviderMeta(txn, fmt.Sprintf("meta:%s", providerName), providerMeta)
+	}); err != nil {
+		return nil, err
+	}
+
+	slog.Info("[manager] catalog rebuilt", "providerName", providerName, "files", result.Files, "removed", result.Removed, "size", result.Size)
+	m.catalogChanged()
+
+	return result, nil
+}
+
+// recordPaths returns the paths of all records of the provider.
+func (m *BackupManager) recordPaths(providerName string) (map[string]bool, error) {
+	prefix := catalog.RecordKey(providerName, "")
+	paths := map[string]bool{}
+
+	err := m.db.Iterate(prefix, func(key string, _ []byte) error {
+		paths[strings.TrimPrefix(key, prefix)] = true
+		return nil
+	})
+	return paths, err
+}
@@ -0,0 +1,107 @@
+package backupmanager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sevigo/shugosha/pkg/catalog"
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+func TestRebuildCatalog(t *testing.T) {
+	source := t.TempDir()
+	provider, dest := newLocalProvider(t, source)
+	m := newTestManager(t, &model.BackupConfig{}, provider)
+
+	a := filepath.Join(source, "a")
+	b := filepath.Join(source, "b")
+	c := filepath.Join(source, "c")
+	assert.NoError(t, os.WriteFile(a, []byte("0123456789"), 0o600))
+	assert.NoError(t, os.Link(a, b))
+	assert.NoError(t, os.WriteFile(c, []byte("01234"), 0o600))
+
+	m.processBackup(newTestEvent(t, source, a), provider)
+	assert.Equal(t, "Success", (<-m.Results()).Status)
+	linked := newTestEvent(t, source, b)
+	linked.HardlinkOf = a
+	m.processBackup(linked, provider)
+	assert.Equal(t, "Linked", (<-m.Results()).Status)
+	m.processBackup(newTestEvent(t, source, c), provider)
+	assert.Equal(t, "Success", (<-m.Results()).Status)
+
+	// The catalog export is stored with the provider but is not one of its files
+	assert.NoError(t, m.EnableCatalogExport(t.TempDir()))
+	assert.NoError(t, m.exportCatalog())
+	_, err := os.Stat(filepath.Join(dest, "objects", model.CatalogExportObject))
+	assert.NoError(t, err)
+
+	// A record of a file the provider no longer has
+	gone := filepath.Join(source, "gone")
+	assert.NoError(t, m.db.Update(func(txn model.Txn) error {
+		return catalog.Put(txn, &model.FileRecord{Provider: "Local", Root: source, Path: gone, Checksum: "x"})
+	}))
+
+	result, err := m.RebuildCatalog("Local")
+	assert.NoError(t, err)
+	assert.Equal(t, &model.RebuildResult{Provider: "Local", Files: 3, Removed: 1, Size: 15}, result)
+
+	meta, err := m.GetMetaInfo("Local")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]uint64{source: 15}, meta.Directories)
+
+	found, err := m.SearchFiles(model.FileQuery{Checksum: "x"})
+	assert.NoError(t, err)
+	assert.Empty(t, found.Files)
+
+	record, err := m.getRecord(catalog.RecordKey("Local", b))
+	assert.NoError(t, err)
+	assert.Equal(t, a, record.HardlinkOf)
+}
+
+// listingProvider backs up a file after listing, like a backup finishing
+// while a slow provider is listed.
+type listingProvider struct {
+	model.Provider
+	afterList func()
+}
+
+func (p *listingProvider) List(fn func(record *model.FileRecord) error) error {
+	if err := p.Provider.(model.ListProvider).List(fn); err != nil {
+		return err
+	}
+	p.afterList()
+	return nil
+}
+
+func TestRebuildKeepsBackupsDuringListing(t *testing.T) {
+	source := t.TempDir()
+	local, _ := newLocalProvider(t, source)
+	provider := &listingProvider{Provider: local}
+	m := newTestManager(t, &model.BackupConfig{}, provider)
+
+	a := filepath.Join(source, "a")
+	b := filepath.Join(source, "b")
+	assert.NoError(t, os.WriteFile(a, []byte("0123456789"), 0o600))
+	assert.NoError(t, os.WriteFile(b, []byte("01234"), 0o600))
+	m.processBackup(newTestEvent(t, source, a), provider)
+	assert.Equal(t, "Success", (<-m.Results()).Status)
+
+	// The catalog is not locked while listing, the backup is kept
+	provider.afterList = func() {
+		m.processBackup(newTestEvent(t, source, b), provider)
+		assert.Equal(t, "Success", (<-m.Results()).Status)
+	}
+	result, err := m.RebuildCatalog("Local")
+	assert.NoError(t, err)
+	assert.Equal(t, &model.RebuildResult{Provider: "Local", Files: 1, Size: 15}, result)
+
+	record, err := m.getRecord(catalog.RecordKey("Local", b))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), record.Size)
+
+	_, err = m.RebuildCatalog("Nope")
+	assert.ErrorIs(t, err, model.ErrUnknownProvider)
+}
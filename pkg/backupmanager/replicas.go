@@ -0,0 +1,62 @@
+package backupmanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/sevigo/shugosha/pkg/catalog"
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// Ensure BackupManager satisfies the ReplicaTracker interface
+var _ model.ReplicaTracker = (*BackupManager)(nil)
+
+// Replicas groups the catalog records of all providers by file. The newest
+// backed up version of a file is the current one, providers with an older or
+// inconsistent copy are listed as stale.
+func (m *BackupManager) Replicas() ([]model.ReplicaStatus, error) {
+	records := map[string][]*model.FileRecord{}
+
+	err := m.db.Iterate("", func(key string, value []byte) error {
+		if !catalog.IsRecordKey(key) {
+			return nil
+		}
+
+		var record model.FileRecord
+		if err := json.Unmarshal(value, &record); err != nil {
+			return fmt.Errorf("failed to unmarshal file record %q: %w", key, err)
+		}
+		records[record.Path] = append(records[record.Path], &record)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	replicas := make([]model.ReplicaStatus, 0, len(records))
+	for path, list := range records {
+		newest := list[0]
+		for _, record := range list[1:] {
+			if record.Timestamp.After(newest.Timestamp) {
+				newest = record
+			}
+		}
+
+		status := model.ReplicaStatus{Path: path, Checksum: newest.Checksum, Current: []string{}}
+		for _, record := range list {
+			if record.Checksum == newest.Checksum && !record.Inconsistent {
+				status.Current = append(status.Current, record.Provider)
+			} else {
+				status.Stale = append(status.Stale, record.Provider)
+			}
+		}
+		sort.Strings(status.Current)
+		sort.Strings(status.Stale)
+
+		replicas = append(replicas, status)
+	}
+
+	sort.Slice(replicas, func(i, j int) bool { return replicas[i].Path < replicas[j].Path })
+	return replicas, nil
+}
@@ -0,0 +1,98 @@
+package backupmanager
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/sevigo/shugosha/pkg/metadata"
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// Ensure BackupManager satisfies the RestoreManager interface
+var _ model.RestoreManager = (*BackupManager)(nil)
+
+// Restore copies a backed up file from a provider to the target path and
+// reapplies the metadata that was stored alongside it.
+func (m *BackupManager) Restore(req model.RestoreRequest) error {
+	provider, ok := m.providers[req.Provider]
+	if !ok {
+		return fmt.Errorf("unknown provider %q", req.Provider)
+	}
+
+	restorer, ok := provider.(model.RestoreProvider)
+	if !ok {
+		return fmt.Errorf("provider %q does not support restore", req.Provider)
+	}
+
+	record, err := restorer.Stat(req.Path)
+	if err != nil {
+		return fmt.Errorf("failed to get stored record: %w", err)
+	}
+
+	target := req.Target
+	if target == "" {
+		target = req.Path
+	}
+	slog.Debug("[BackupManager] restore", "providerName", req.Provider, "path", req.Path, "target", target)
+
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+
+	if record.Kind == model.KindSymlink {
+		err = restoreSymlink(record.LinkTarget, target)
+	} else {
+		err = restoreFile(restorer, record, target)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := metadata.Apply(target, record.Metadata, metadata.ApplyOptions{SkipOwnership: req.SkipOwnership}); err != nil {
+		return fmt.Errorf("restored %s but failed to apply metadata: %w", target, err)
+	}
+
+	return nil
+}
+
+func restoreSymlink(linkTarget, target string) error {
+	if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Symlink(linkTarget, target)
+}
+
+// restoreFile writes the content to a temporary file next to the target and
+// only replaces the target once the checksum has been verified. Hard links
+// are restored from the content stored with the file they link to.
+func restoreFile(restorer model.RestoreProvider, record *model.FileRecord, target string) error {
+	source := record.Path
+	if record.HardlinkOf != "" {
+		source = record.HardlinkOf
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".shugosha-restore-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	if err := restorer.Restore(source, io.MultiWriter(tmp, hash)); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to restore content: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if sum := fmt.Sprintf("%x", hash.Sum(nil)); record.Checksum != "" && sum != record.Checksum {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", record.Path, record.Checksum, sum)
+	}
+
+	return os.Rename(tmp.Name(), target)
+}
@@ -0,0 +1,170 @@
+package backupmanager
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/sevigo/shugosha/pkg/model"
+	"github.com/sevigo/shugosha/pkg/throttle"
+)
+
+// progressInterval is the number of bytes between two progress reports.
+const progressInterval = 1 << 20
+
+// OnProgress registers a function that is called while files are uploaded.
+func (m *BackupManager) OnProgress(fn model.ProgressFunc) {
+	m.progressLock.Lock()
+	defer m.progressLock.Unlock()
+
+	m.progressFuncs = append(m.progressFuncs, fn)
+}
+
+func (m *BackupManager) reportProgress(provider, path string, transferred, total int64) {
+	m.progressLock.Lock()
+	defer m.progressLock.Unlock()
+
+	for _, fn := range m.progressFuncs {
+		fn(provider, path, transferred, total)
+	}
+}
+
+// backupStream uploads the file as a stream, resuming a previous session for
+// the same content if the provider still holds its data. It returns the
+// checksum of the complete content that was uploaded.
+func (m *BackupManager) backupStream(event model.Event, provider model.StreamProvider, limiter *throttle.Limiter) (string, error) {
+	file, err := os.Open(event.Path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	session := m.uploadSession(event, provider, info)
+
+	// The already uploaded part is hashed from the file, the rest as it is streamed
+	hash := sha256.New()
+	if session.Offset > 0 {
+		slog.Info("[manager] resuming upload", "providerName", provider.Name(), "file", event.Path, "offset", session.Offset)
+		if _, err := io.CopyN(hash, limiter.FileReader(m.ctx, file), session.Offset); err != nil {
+			return "", err
+		}
+	}
+
+	reader := &progressReader{
+		r:            io.TeeReader(limiter.Reader(m.ctx, file), hash),
+		transferred:  session.Offset,
+		lastReported: session.Offset,
+		total:        info.Size(),
+		report: func(transferred, total int64) {
+			m.reportProgress(provider.Name(), event.Path, transferred, total)
+			m.activity.Publish(model.ActivityEvent{
+				Type:        model.ActivityProgress,
+				Provider:    provider.Name(),
+				Root:        event.Root,
+				Path:        event.Path,
+				Transferred: transferred,
+				Total:       total,
+			})
+		},
+	}
+
+	if err := provider.BackupStream(m.ctx, event, session, reader, m.saveUploadSession); err != nil {
+		return "", err
+	}
+
+	if err := m.db.Delete(uploadKey(provider.Name(), event.Path)); err != nil {
+		slog.Error("[manager] failed to delete upload session", "error", err, "file", event.Path)
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// uploadSession returns the stored session for the file if it can be resumed,
+// or a new one.
+func (m *BackupManager) uploadSession(event model.Event, provider model.StreamProvider, info os.FileInfo) *model.UploadSession {
+	fresh := &model.UploadSession{
+		Provider: provider.Name(),
+		Path:     event.Path,
+		Checksum: event.Checksum,
+		Size:     info.Size(),
+		ModTime:  info.ModTime(),
+		Started:  time.Now(),
+	}
+
+	session, err := m.getUploadSession(provider.Name(), event.Path)
+	if err != nil {
+		if !errors.Is(err, model.ErrDBKeyNotFound) {
+			slog.Error("[manager] failed to load upload session", "error", err, "file", event.Path)
+		}
+		return fresh
+	}
+
+	if session.Checksum != event.Checksum || session.Size != info.Size() || !session.ModTime.Equal(info.ModTime()) {
+		return fresh
+	}
+
+	offset, err := provider.UploadOffset(session)
+	if err != nil {
+		slog.Warn("[manager] cannot resume upload", "error", err, "file", event.Path)
+		return fresh
+	}
+	session.Offset = min(offset, session.Offset)
+
+	return session
+}
+
+func uploadKey(providerName, path string) string {
+	return "upload:" + providerName + ":" + path
+}
+
+func (m *BackupManager) getUploadSession(providerName, path string) (*model.UploadSession, error) {
+	value, err := m.db.Get(uploadKey(providerName, path))
+	if err != nil {
+		return nil, err
+	}
+
+	var session model.UploadSession
+	if err := json.Unmarshal(value, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (m *BackupManager) saveUploadSession(session *model.UploadSession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return m.db.Set(uploadKey(session.Provider, session.Path), data)
+}
+
+// progressReader reports the number of bytes read every progressInterval bytes.
+type progressReader struct {
+	r            io.Reader
+	transferred  int64
+	total        int64
+	lastReported int64
+	report       func(transferred, total int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.transferred += int64(n)
+
+	if p.transferred-p.lastReported >= progressInterval || (errors.Is(err, io.EOF) && p.transferred != p.lastReported) {
+		p.lastReported = p.transferred
+		p.report(p.transferred, p.total)
+	}
+
+	return n, err
+}
@@ -0,0 +1,83 @@
+package backupmanager
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+func TestProgressReaderReportsIntervalsAndEnd(t *testing.T) {
+	var reports []int64
+	reader := &progressReader{
+		r:     bytes.NewReader(make([]byte, 2*progressInterval+10)),
+		total: 2*progressInterval + 10,
+		report: func(transferred, _ int64) {
+			reports = append(reports, transferred)
+		},
+	}
+
+	n, err := io.Copy(io.Discard, reader)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2*progressInterval+10), n)
+	assert.Equal(t, []int64{progressInterval, 2 * progressInterval, 2*progressInterval + 10}, reports)
+}
+
+func TestBackupStreamResumesInterruptedUpload(t *testing.T) {
+	source := t.TempDir()
+	provider, dest := newLocalProvider(t, source)
+	m := newTestManager(t, &model.BackupConfig{}, provider)
+	streamProvider := provider.(model.StreamProvider)
+
+	// Large enough for the provider to checkpoint twice before it notices
+	// the cancellation
+	content := make([]byte, 17<<20)
+	_, err := rand.Read(content)
+	assert.NoError(t, err)
+	path := filepath.Join(source, "large")
+	assert.NoError(t, os.WriteFile(path, content, 0o600))
+	event := newTestEvent(t, source, path)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.ctx = ctx
+	m.OnProgress(func(_, _ string, transferred, _ int64) {
+		if transferred > 8<<20 {
+			cancel()
+		}
+	})
+	_, err = m.backupStream(event, streamProvider, m.throttles.Limiter("Local"))
+	assert.ErrorIs(t, err, context.Canceled)
+
+	session, err := m.getUploadSession("Local", path)
+	assert.NoError(t, err)
+	assert.Greater(t, session.Offset, int64(0))
+	assert.Less(t, session.Offset, int64(len(content)))
+
+	// The second attempt continues at the checkpoint
+	m.ctx = context.Background()
+	m.progressFuncs = nil
+	var first int64
+	m.OnProgress(func(_, _ string, transferred, _ int64) {
+		if first == 0 {
+			first = transferred
+		}
+	})
+	sum, err := m.backupStream(event, streamProvider, m.throttles.Limiter("Local"))
+	assert.NoError(t, err)
+	assert.Equal(t, event.Checksum, sum)
+	assert.Greater(t, first, session.Offset)
+
+	stored, err := os.ReadFile(filepath.Join(dest, "data", path))
+	assert.NoError(t, err)
+	assert.True(t, bytes.Equal(content, stored), "stored content differs")
+
+	_, err = m.getUploadSession("Local", path)
+	assert.ErrorIs(t, err, model.ErrDBKeyNotFound)
+}
@@ -0,0 +1,265 @@
+package backupmanager
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/sevigo/shugosha/pkg/catalog"
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// Ensure BackupManager satisfies the Verifier interface
+var _ model.Verifier = (*BackupManager)(nil)
+
+// StartVerify checks the files the catalog lists for a provider against what
+// the provider stores. The verification runs in the background, its progress
+// and result are available through VerifyReports.
+func (m *BackupManager) StartVerify(req model.VerifyRequest) (*model.VerifyReport, error) {
+	provider, ok := m.providers[req.Provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q", req.Provider)
+	}
+
+	restorer, ok := provider.(model.RestoreProvider)
+	if !ok {
+		return nil, fmt.Errorf("provider %q does not support reading stored files", req.Provider)
+	}
+
+	if req.Mode == "" {
+		req.Mode = model.VerifyQuick
+	}
+	if req.Mode != model.VerifyQuick && req.Mode != model.VerifyFull {
+		return nil, fmt.Errorf("unknown verify mode %q", req.Mode)
+	}
+	if req.Percent == 0 {
+		req.Percent = 100
+	}
+	if req.Percent < 0 || req.Percent > 100 {
+		return nil, fmt.Errorf("percent must be between 0 and 100")
+	}
+
+	m.verifyLock.Lock()
+	defer m.verifyLock.Unlock()
+
+	if current, ok := m.verifyReports[req.Provider]; ok && current.Running {
+		return nil, fmt.Errorf("verification of provider %q is already running", req.Provider)
+	}
+
+	report := &model.VerifyReport{
+		Provider: req.Provider,
+		Mode:     req.Mode,
+		Percent:  req.Percent,
+		Running:  true,
+		Started:  time.Now(),
+		Issues:   []model.VerifyIssue{},
+	}
+	if m.verifyReports == nil {
+		m.verifyReports = map[string]*model.VerifyReport{}
+	}
+	m.verifyReports[req.Provider] = report
+
+	go m.verify(req, provider, restorer, report)
+
+	return copyReport(report), nil
+}
+
+// VerifyReports returns the latest verification of every provider.
+func (m *BackupManager) VerifyReports() map[string]model.VerifyReport {
+	m.verifyLock.Lock()
+	defer m.verifyLock.Unlock()
+
+	reports := make(map[string]model.VerifyReport, len(m.verifyReports))
+	for name, report := range m.verifyReports {
+		reports[name] = *copyReport(report)
+	}
+	return reports
+}
+
+func copyReport(report *model.VerifyReport) *model.VerifyReport {
+	c := *report
+	c.Issues = append([]model.VerifyIssue{}, report.Issues...)
+	return &c
+}
+
+func (m *BackupManager) verify(req model.VerifyRequest, provider model.Provider, restorer model.RestoreProvider, report *model.VerifyReport) {
+	slog.Info("[manager] verification started", "providerName", req.Provider, "mode", req.Mode, "percent", req.Percent)
+
+	records, err := m.providerRecords(req.Provider)
+	if err != nil {
+		m.finishVerify(report, err)
+		return
+	}
+
+	for _, record := range records {
+		if m.ctx.Err() != nil {
+			m.finishVerify(report, m.ctx.Err())
+			return
+		}
+
+		// Hard links are stored once, with the file they link to
+		if record.HardlinkOf != "" {
+			continue
+		}
+
+		// Files left out of the sample still get the quick check
+		mode := req.Mode
+		if mode == model.VerifyFull && req.Percent < 100 && rand.Float64()*100 >= req.Percent {
+			mode = model.VerifyQuick
+		}
+
+		issue := verifyRecord(restorer, record, mode)
+		if issue != nil && req.Repair {
+			m.repair(provider, record, issue)
+		}
+
+		m.verifyLock.Lock()
+		if mode == req.Mode {
+			report.Checked++
+		} else {
+			report.Skipped++
+		}
+		if issue != nil {
+			report.Issues = append(report.Issues, *issue)
+		}
+		m.verifyLock.Unlock()
+	}
+
+	m.finishVerify(report, nil)
+}
+
+func (m *BackupManager) finishVerify(report *model.VerifyReport, err error) {
+	m.verifyLock.Lock()
+	defer m.verifyLock.Unlock()
+
+	report.Running = false
+	report.Finished = time.Now()
+	if err != nil {
+		report.Error = err.Error()
+		slog.Error("[manager] verification failed", "providerName", report.Provider, "error", err)
+		return
+	}
+	slog.Info("[manager] verification finished", "providerName", report.Provider, "checked", report.Checked, "issues", len(report.Issues))
+}
+
+// providerRecords reads all catalog records of the provider.
+func (m *BackupManager) providerRecords(providerName string) ([]*model.FileRecord, error) {
+	var records []*model.FileRecord
+	err := m.db.Iterate(catalog.RecordKey(providerName, ""), func(key string, value []byte) error {
+		var record model.FileRecord
+		if err := json.Unmarshal(value, &record); err != nil {
+			return fmt.Errorf("failed to unmarshal file record %q: %w", key, err)
+		}
+		records = append(records, &record)
+		return nil
+	})
+	return records, err
+}
+
+// verifyRecord compares the stored copy of a file with its catalog record and
+// returns the problem found, if any.
+func verifyRecord(restorer model.RestoreProvider, record *model.FileRecord, mode model.VerifyMode) *model.VerifyIssue {
+	stored, err := restorer.Stat(record.Path)
+	if err != nil {
+		return storeIssue(record.Path, err)
+	}
+
+	if stored.Size != record.Size {
+		return &model.VerifyIssue{Path: record.Path, Problem: model.VerifySize, Expected: strconv.FormatInt(record.Size, 10), Actual: strconv.FormatInt(stored.Size, 10)}
+	}
+	if stored.Checksum != record.Checksum {
+		return &model.VerifyIssue{Path: record.Path, Problem: model.VerifyChecksum, Expected: record.Checksum, Actual: stored.Checksum}
+	}
+
+	// Symbolic links have no stored content
+	if record.Kind == model.KindSymlink {
+		return nil
+	}
+
+	// The record may be intact while the content is truncated or gone
+	size, err := restorer.Size(record.Path)
+	if err != nil {
+		return storeIssue(record.Path, err)
+	}
+	if size != record.Size {
+		return &model.VerifyIssue{Path: record.Path, Problem: model.VerifySize, Expected: strconv.FormatInt(record.Size, 10), Actual: strconv.FormatInt(size, 10)}
+	}
+
+	if mode != model.VerifyFull {
+		return nil
+	}
+
+	hash := sha256.New()
+	if err := restorer.Restore(record.Path, hash); err != nil {
+		return storeIssue(record.Path, err)
+	}
+
+	if sum := fmt.Sprintf("%x", hash.Sum(nil)); sum != record.Checksum {
+		return &model.VerifyIssue{Path: record.Path, Problem: model.VerifyChecksum, Expected: record.Checksum, Actual: sum}
+	}
+	return nil
+}
+
+func storeIssue(path string, err error) *model.VerifyIssue {
+	if errors.Is(err, fs.ErrNotExist) {
+		return &model.VerifyIssue{Path: path, Problem: model.VerifyMissing}
+	}
+	return &model.VerifyIssue{Path: path, Problem: model.VerifyError, Error: err.Error()}
+}
+
+// repair uploads the local file again if it still has the content the
+// catalog expects.
+func (m *BackupManager) repair(provider model.Provider, record *model.FileRecord, issue *model.VerifyIssue) {
+	if issue.Problem == model.VerifyError {
+		return
+	}
+
+	event := model.Event{
+		Root:       record.Root,
+		Path:       record.Path,
+		Timestamp:  time.Now(),
+		Checksum:   record.Checksum,
+		Size:       record.Size,
+		Kind:       record.Kind,
+		LinkTarget: record.LinkTarget,
+		Metadata:   record.Metadata,
+	}
+
+	if record.Kind == model.KindSymlink {
+		if err := provider.Backup(event, nil); err != nil {
+			issue.Error = "repair failed: " + err.Error()
+			return
+		}
+		issue.Repaired = true
+		return
+	}
+
+	sum, err := hashFile(m.ctx, record.Path, m.throttles.Limiter(provider.Name()))
+	if err != nil {
+		issue.Error = "repair failed: " + err.Error()
+		return
+	}
+	if sum != record.Checksum {
+		issue.Error = "repair skipped: local file changed since the backup"
+		return
+	}
+
+	uploaded, err := m.backup(event, provider)
+	if err != nil {
+		issue.Error = "repair failed: " + err.Error()
+		return
+	}
+	if uploaded != record.Checksum {
+		issue.Error = "repair failed: local file changed during upload"
+		return
+	}
+
+	slog.Info("[manager] repaired stored file", "providerName", provider.Name(), "file", record.Path)
+	issue.Repaired = true
+}
@@ -0,0 +1,80 @@
+package backupmanager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sevigo/shugosha/pkg/catalog"
+	"github.com/sevigo/shugosha/pkg/db"
+	"github.com/sevigo/shugosha/pkg/model"
+	"github.com/sevigo/shugosha/pkg/provider/local"
+)
+
+func TestVerifyFindsAndRepairsDamagedFiles(t *testing.T) {
+	source, dest := t.TempDir(), t.TempDir()
+	provider, err := local.NewLocalProvider(&model.ProviderConfig{Name: "Local", Settings: map[string]string{"path": dest}})
+	assert.NoError(t, err)
+
+	storage := db.NewMemoryDB()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := &BackupManager{db: storage, providers: map[string]model.Provider{"Local": provider}, ctx: ctx}
+
+	for _, name := range []string{"intact", "damaged", "missing", "truncated"} {
+		path := filepath.Join(source, name)
+		assert.NoError(t, os.WriteFile(path, []byte(name), 0o600))
+		sum, err := hashFile(context.Background(), path, nil)
+		assert.NoError(t, err)
+
+		event := model.Event{Root: source, Path: path, Checksum: sum, Size: int64(len(name))}
+		assert.NoError(t, provider.Backup(event, strings.NewReader(name)))
+		assert.NoError(t, storage.Update(func(txn model.Txn) error {
+			return catalog.Put(txn, catalog.FromEvent("Local", event))
+		}))
+	}
+
+	// Same size, different content
+	assert.NoError(t, os.WriteFile(filepath.Join(dest, "data", source, "damaged"), []byte("DAMAGED"), 0o600))
+	assert.NoError(t, os.Remove(filepath.Join(dest, "meta", source, "missing.json")))
+	// Intact record, short content
+	assert.NoError(t, os.WriteFile(filepath.Join(dest, "data", source, "truncated"), []byte("trunc"), 0o600))
+
+	waitForVerify := func(req model.VerifyRequest) model.VerifyReport {
+		_, err := m.StartVerify(req)
+		assert.NoError(t, err)
+		for {
+			report := m.VerifyReports()["Local"]
+			if !report.Running {
+				return report
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	quick := waitForVerify(model.VerifyRequest{Provider: "Local"})
+	assert.Equal(t, 4, quick.Checked)
+	assert.Equal(t, []model.VerifyIssue{
+		{Path: filepath.Join(source, "missing"), Problem: model.VerifyMissing},
+		{Path: filepath.Join(source, "truncated"), Problem: model.VerifySize, Expected: "9", Actual: "5"},
+	}, quick.Issues)
+
+	// Files outside the sample are checked quickly
+	sampled := waitForVerify(model.VerifyRequest{Provider: "Local", Mode: model.VerifyFull, Percent: 1e-9})
+	assert.Equal(t, 4, sampled.Skipped)
+	assert.Equal(t, quick.Issues, sampled.Issues)
+
+	full := waitForVerify(model.VerifyRequest{Provider: "Local", Mode: model.VerifyFull, Repair: true})
+	assert.Len(t, full.Issues, 3)
+	for _, issue := range full.Issues {
+		assert.True(t, issue.Repaired, issue.Path)
+	}
+
+	again := waitForVerify(model.VerifyRequest{Provider: "Local", Mode: model.VerifyFull})
+	assert.Empty(t, again.Issues)
+}
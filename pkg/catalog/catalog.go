@@ -0,0 +1,197 @@
+// Package catalog stores backed up file records with secondary indexes and
+// answers queries over them.
+package catalog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// Key prefixes that do not hold file records.
+var reservedPrefixes = []string{"meta:", "config:", "upload:", indexPrefix}
+
+// Catalog provides indexed access to the file records in the database.
+type Catalog struct {
+	db model.DB
+}
+
+func New(storage model.DB) *Catalog {
+	return &Catalog{db: storage}
+}
+
+// RecordKey returns the key of the record of path backed up by provider.
+func RecordKey(providerName, path string) string {
+	return providerName + ":" + path
+}
+
+// IsRecordKey reports whether the key holds a file record.
+func IsRecordKey(key string) bool {
+	for _, prefix := range reservedPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return false
+		}
+	}
+	return strings.Contains(key, ":")
+}
+
+// FromEvent creates the record of a file event backed up by provider.
+func FromEvent(providerName string, event model.Event) *model.FileRecord {
+	return &model.FileRecord{
+		Root:         event.Root,
+		Path:         event.Path,
+		Timestamp:    event.Timestamp,
+		Checksum:     event.Checksum,
+		Provider:     providerName,
+		Size:         event.Size,
+		Kind:         event.Kind,
+		LinkTarget:   event.LinkTarget,
+		HardlinkOf:   event.HardlinkOf,
+		Metadata:     event.Metadata,
+		Inconsistent: event.Inconsistent,
+	}
+}
+
+// Put stores the record of a backed up file and updates its index entries.
+func Put(txn model.Txn, record *model.FileRecord) error {
+	key := RecordKey(record.Provider, record.Path)
+
+	old, err := get(txn, key)
+	if err != nil && !errors.Is(err, model.ErrDBKeyNotFound) {
+		return err
+	}
+	if old != nil {
+		for _, indexKey := range indexKeys(old) {
+			if err := txn.Delete(indexKey); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := txn.Set(key, data); err != nil {
+		return err
+	}
+
+	for _, indexKey := range indexKeys(record) {
+		if err := txn.Set(indexKey, []byte{}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes the record of path backed up by provider and its index entries.
+func Delete(txn model.Txn, providerName, path string) error {
+	key := RecordKey(providerName, path)
+
+	old, err := get(txn, key)
+	if errors.Is(err, model.ErrDBKeyNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, indexKey := range indexKeys(old) {
+		if err := txn.Delete(indexKey); err != nil {
+			return err
+		}
+	}
+	return txn.Delete(key)
+}
+
+// PutBatch adds the writes of Put to batch, reading the previous record from
+// storage. Records written by earlier calls with the same batch are not seen.
+func PutBatch(storage model.Txn, batch *model.Batch, record *model.FileRecord) error {
+	return Put(batchTxn{Txn: storage, batch: batch}, record)
+}
+
+// DeleteBatch adds the writes of Delete to batch, like PutBatch.
+func DeleteBatch(storage model.Txn, batch *model.Batch, providerName, path string) error {
+	return Delete(batchTxn{Txn: storage, batch: batch}, providerName, path)
+}
+
+// batchTxn reads from the database and collects the writes in a batch.
+type batchTxn struct {
+	model.Txn
+	batch *model.Batch
+}
+
+func (t batchTxn) Set(key string, value []byte) error {
+	t.batch.Set(key, value)
+	return nil
+}
+
+func (t batchTxn) Delete(key string) error {
+	t.batch.Delete(key)
+	return nil
+}
+
+// Get returns the record of path backed up by provider.
+func Get(txn model.Txn, providerName, path string) (*model.FileRecord, error) {
+	return get(txn, RecordKey(providerName, path))
+}
+
+func get(txn model.Txn, key string) (*model.FileRecord, error) {
+	value, err := txn.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var record model.FileRecord
+	if err := json.Unmarshal(value, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal file record %q: %w", key, err)
+	}
+	return &record, nil
+}
+
+// Reindex rebuilds all index entries from the stored records. The changes are
+// written as a batch, so that large catalogs do not exceed transaction limits.
+func Reindex(storage model.DB) error {
+	batch := &model.Batch{}
+	if err := ReindexBatch(storage, batch); err != nil {
+		return err
+	}
+	return storage.WriteBatch(batch)
+}
+
+// ReindexBatch adds the writes that rebuild all index entries to batch.
+func ReindexBatch(storage model.DB, batch *model.Batch) error {
+	slog.Info("[catalog] rebuilding indexes")
+
+	if err := storage.Iterate(indexPrefix, func(key string, _ []byte) error {
+		batch.Delete(key)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := storage.Iterate("", func(key string, value []byte) error {
+		if !IsRecordKey(key) {
+			return nil
+		}
+
+		var record model.FileRecord
+		if err := json.Unmarshal(value, &record); err != nil {
+			slog.Warn("[catalog] skipping unreadable record", "key", key, "error", err)
+			return nil
+		}
+
+		for _, indexKey := range indexKeys(&record) {
+			batch.Set(indexKey, []byte{})
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
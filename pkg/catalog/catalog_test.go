@@ -0,0 +1,214 @@
+package catalog
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sevigo/shugosha/pkg/db"
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+var now = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestCatalog(t *testing.T, events map[string][]model.Event) *Catalog {
+	t.Helper()
+	storage := db.NewMemoryDB()
+	for providerName, list := range events {
+		for _, event := range list {
+			assert.NoError(t, storage.Update(func(txn model.Txn) error {
+				return Put(txn, FromEvent(providerName, event))
+			}))
+		}
+	}
+	return New(storage)
+}
+
+func paths(result *model.FileQueryResult) []string {
+	list := []string{}
+	for _, record := range result.Files {
+		list = append(list, record.Provider+":"+record.Path)
+	}
+	return list
+}
+
+func TestSearchFilesFilters(t *testing.T) {
+	c := newTestCatalog(t, map[string][]model.Event{
+		"Echo": {
+			{Root: "/data", Path: "/data/movie.MKV", Size: 2 << 30, Checksum: "aaa", Timestamp: now.Add(-24 * time.Hour)},
+			{Root: "/data", Path: "/data/old.mkv", Size: 3 << 30, Checksum: "bbb", Timestamp: now.Add(-30 * 24 * time.Hour)},
+			{Root: "/docs", Path: "/docs/notes.txt", Size: 10, Checksum: "aaa", Timestamp: now},
+		},
+		"Local": {
+			{Root: "/data", Path: "/data/movie.MKV", Size: 2 << 30, Checksum: "aaa", Timestamp: now.Add(-24 * time.Hour)},
+		},
+	})
+
+	result, err := c.SearchFiles(model.FileQuery{MinSize: 1 << 30, ModifiedAfter: now.Add(-7 * 24 * time.Hour)})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Echo:/data/movie.MKV", "Local:/data/movie.MKV"}, paths(result))
+
+	result, err = c.SearchFiles(model.FileQuery{Checksum: "aaa", Provider: "Echo"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Echo:/data/movie.MKV", "Echo:/docs/notes.txt"}, paths(result))
+
+	result, err = c.SearchFiles(model.FileQuery{Extension: ".mkv", Sort: "size", Descending: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Echo:/data/old.mkv", "Local:/data/movie.MKV", "Echo:/data/movie.MKV"}, paths(result))
+
+	result, err = c.SearchFiles(model.FileQuery{Root: "/docs"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Echo:/docs/notes.txt"}, paths(result))
+
+	_, err = c.SearchFiles(model.FileQuery{Sort: "owner"})
+	assert.Error(t, err)
+}
+
+func TestSearchFilesCursor(t *testing.T) {
+	c := newTestCatalog(t, map[string][]model.Event{
+		"Echo": {
+			{Root: "/r", Path: "/r/a", Timestamp: now},
+			{Root: "/r", Path: "/r/b", Timestamp: now},
+			{Root: "/r", Path: "/r/c", Timestamp: now},
+		},
+	})
+
+	first, err := c.SearchFiles(model.FileQuery{Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Echo:/r/a", "Echo:/r/b"}, paths(first))
+	assert.NotEmpty(t, first.NextCursor)
+
+	second, err := c.SearchFiles(model.FileQuery{Limit: 2, Cursor: first.NextCursor})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Echo:/r/c"}, paths(second))
+	assert.Empty(t, second.NextCursor)
+}
+
+func TestSearchFilesPagesInSortOrder(t *testing.T) {
+	var events []model.Event
+	for i := 0; i < 40; i++ {
+		events = append(events, model.Event{
+			Root:      "/r",
+			Path:      fmt.Sprintf("/r/%02d", i),
+			Size:      int64(i % 7),
+			Timestamp: now.Add(time.Duration(i%5) * time.Hour),
+		})
+	}
+	c := newTestCatalog(t, map[string][]model.Event{"Echo": events, "Local": events[:10]})
+
+	for _, sortField := range []string{"path", "size", "modified"} {
+		for _, descending := range []bool{false, true} {
+			all, err := c.SearchFiles(model.FileQuery{Sort: sortField, Descending: descending, Limit: 100})
+			assert.NoError(t, err)
+			assert.Len(t, all.Files, 50)
+
+			var paged []string
+			query := model.FileQuery{Sort: sortField, Descending: descending, Limit: 7}
+			for {
+				result, err := c.SearchFiles(query)
+				assert.NoError(t, err)
+				paged = append(paged, paths(result)...)
+				if result.NextCursor == "" {
+					break
+				}
+				query.Cursor = result.NextCursor
+			}
+			assert.Equal(t, paths(all), paged, "sort %s, descending %v", sortField, descending)
+		}
+	}
+}
+
+// countingDB counts the records read by searches.
+type countingDB struct {
+	model.DB
+	reads int
+}
+
+func (d *countingDB) View(fn func(txn model.Txn) error) error {
+	return d.DB.View(func(txn model.Txn) error {
+		return fn(&countingTxn{Txn: txn, db: d})
+	})
+}
+
+type countingTxn struct {
+	model.Txn
+	db *countingDB
+}
+
+func (t *countingTxn) Get(key string) ([]byte, error) {
+	t.db.reads++
+	return t.Txn.Get(key)
+}
+
+func TestSearchFilesRanges(t *testing.T) {
+	var events []model.Event
+	for i := 0; i < 100; i++ {
+		events = append(events, model.Event{
+			Root:      "/r",
+			Path:      fmt.Sprintf("/r/%02d", i),
+			Size:      int64(i),
+			Timestamp: now.Add(time.Duration(i) * time.Hour),
+		})
+	}
+	storage := &countingDB{DB: newTestCatalog(t, map[string][]model.Event{"Echo": events}).db}
+	c := New(storage)
+
+	for _, test := range []struct {
+		query model.FileQuery
+		files []string
+	}{
+		{model.FileQuery{MinSize: 40, MaxSize: 42}, []string{"Echo:/r/40", "Echo:/r/41", "Echo:/r/42"}},
+		{model.FileQuery{MinSize: 40, MaxSize: 42, Sort: "size", Descending: true}, []string{"Echo:/r/42", "Echo:/r/41", "Echo:/r/40"}},
+		{model.FileQuery{ModifiedAfter: now.Add(70 * time.Hour), ModifiedBefore: now.Add(73 * time.Hour), Sort: "modified"}, []string{"Echo:/r/71", "Echo:/r/72"}},
+		{model.FileQuery{ModifiedAfter: now.Add(97 * time.Hour), Sort: "size", Descending: true}, []string{"Echo:/r/99", "Echo:/r/98"}},
+	} {
+		storage.reads = 0
+		result, err := c.SearchFiles(test.query)
+		assert.NoError(t, err)
+		assert.Equal(t, test.files, paths(result))
+		// Only the entries in the range are read, not the whole catalog
+		assert.LessOrEqual(t, storage.reads, len(test.files)+1, "%+v", test.query)
+	}
+}
+
+func TestSearchFilesInvalidQuery(t *testing.T) {
+	c := newTestCatalog(t, nil)
+
+	_, err := c.SearchFiles(model.FileQuery{Sort: "owner"})
+	assert.ErrorIs(t, err, model.ErrInvalidQuery)
+
+	_, err = c.SearchFiles(model.FileQuery{Cursor: "not a cursor"})
+	assert.ErrorIs(t, err, model.ErrInvalidQuery)
+}
+
+func TestPutReplacesIndexEntries(t *testing.T) {
+	c := newTestCatalog(t, map[string][]model.Event{
+		"Echo": {
+			{Root: "/r", Path: "/r/a", Checksum: "old", Timestamp: now},
+			{Root: "/r", Path: "/r/a", Checksum: "new", Timestamp: now},
+		},
+	})
+
+	result, err := c.SearchFiles(model.FileQuery{Checksum: "old"})
+	assert.NoError(t, err)
+	assert.Empty(t, result.Files)
+
+	result, err = c.SearchFiles(model.FileQuery{Checksum: "new"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Echo:/r/a"}, paths(result))
+}
+
+func TestReindexRebuildsMissingIndexes(t *testing.T) {
+	storage := db.NewMemoryDB()
+	assert.NoError(t, storage.Set("Echo:/r/a", []byte(`{"provider":"Echo","root":"/r","path":"/r/a","checksum":"abc"}`)))
+	assert.NoError(t, storage.Set("meta:Echo", []byte(`{"name":"Echo"}`)))
+
+	assert.NoError(t, Reindex(storage))
+	c := New(storage)
+
+	result, err := c.SearchFiles(model.FileQuery{Checksum: "abc"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Echo:/r/a"}, paths(result))
+}
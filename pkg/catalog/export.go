@@ -0,0 +1,114 @@
+package catalog
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+const (
+	// ExportFormat identifies catalog export files.
+	ExportFormat = "shugosha-catalog"
+	// ExportVersion is the version of the export file format.
+	ExportVersion = 1
+)
+
+// Keys that are not exported: indexes are rebuilt on import and upload
+// sessions only make sense on the machine that started them.
+var notExportedPrefixes = []string{indexPrefix, "upload:"}
+
+// ErrNotEmpty is returned when importing into a database that already has data.
+var ErrNotEmpty = errors.New("database is not empty")
+
+// ExportHeader is the first line of an export file.
+type ExportHeader struct {
+	Format  string    `json:"format"`
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+}
+
+// exportEntry is a single key of the database.
+type exportEntry struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// Export writes the configuration, file records and provider metadata of the
+// database to w as gzip compressed JSON lines, starting with an ExportHeader.
+func Export(storage model.DB, w io.Writer) error {
+	zw := gzip.NewWriter(w)
+	encoder := json.NewEncoder(zw)
+
+	header := ExportHeader{Format: ExportFormat, Version: ExportVersion, Created: time.Now().UTC()}
+	if err := encoder.Encode(header); err != nil {
+		return err
+	}
+
+	err := storage.Iterate("", func(key string, value []byte) error {
+		for _, prefix := range notExportedPrefixes {
+			if strings.HasPrefix(key, prefix) {
+				return nil
+			}
+		}
+		return encoder.Encode(exportEntry{Key: key, Value: value})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export catalog: %w", err)
+	}
+
+	return zw.Close()
+}
+
+// Import restores an export written by Export into an empty database and
+// rebuilds the indexes. The schema of the imported data is the one of the
+// exporting version, so migrations have to run afterwards.
+func Import(storage model.DB, r io.Reader) error {
+	if err := storage.Iterate("", func(string, []byte) error {
+		return ErrNotEmpty
+	}); err != nil {
+		return err
+	}
+
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("failed to read catalog export: %w", err)
+	}
+	defer zr.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(zr))
+
+	var header ExportHeader
+	if err := decoder.Decode(&header); err != nil {
+		return fmt.Errorf("failed to read catalog export header: %w", err)
+	}
+	if header.Format != ExportFormat {
+		return fmt.Errorf("not a catalog export: format %q", header.Format)
+	}
+	if header.Version > ExportVersion {
+		return fmt.Errorf("catalog export version %d is newer than the supported version %d", header.Version, ExportVersion)
+	}
+
+	batch := &model.Batch{}
+	for {
+		var entry exportEntry
+		if err := decoder.Decode(&entry); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to read catalog export: %w", err)
+		}
+		batch.Set(entry.Key, entry.Value)
+	}
+
+	if err := storage.WriteBatch(batch); err != nil {
+		return err
+	}
+
+	return Reindex(storage)
+}
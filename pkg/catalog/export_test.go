@@ -0,0 +1,39 @@
+package catalog
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sevigo/shugosha/pkg/db"
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	source := newTestCatalog(t, map[string][]model.Event{
+		"Echo": {{Root: "/r", Path: "/r/a.txt", Checksum: "abc", Size: 3, Timestamp: now}},
+	})
+	assert.NoError(t, source.db.Set("config:backupConfig", []byte(`{"providers":[]}`)))
+	assert.NoError(t, source.db.Set("upload:Echo:/r/b.txt", []byte(`{}`)))
+
+	var buf bytes.Buffer
+	assert.NoError(t, Export(source.db, &buf))
+
+	target := db.NewMemoryDB()
+	assert.NoError(t, Import(target, bytes.NewReader(buf.Bytes())))
+
+	config, err := target.Get("config:backupConfig")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"providers":[]}`, string(config))
+
+	_, err = target.Get("upload:Echo:/r/b.txt")
+	assert.ErrorIs(t, err, model.ErrDBKeyNotFound)
+
+	result, err := New(target).SearchFiles(model.FileQuery{Extension: ".txt"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Echo:/r/a.txt"}, paths(result))
+
+	// Importing twice would mix two catalogs
+	assert.ErrorIs(t, Import(target, bytes.NewReader(buf.Bytes())), ErrNotEmpty)
+}
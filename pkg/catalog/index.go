@@ -0,0 +1,83 @@
+package catalog
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// Index entries are empty values under keys made of the index name, the
+// indexed value and the record identity, separated by NUL bytes:
+//
+//	idx\x00checksum\x00<sha256>\x00<provider>\x00<path>
+//
+// The path, size and modified indexes hold every record in the order of the
+// sort field of the same name.
+const (
+	indexPrefix = "idx\x00"
+	sep         = "\x00"
+
+	indexChecksum  = "checksum"
+	indexModified  = "modified"
+	indexRoot      = "root"
+	indexExtension = "ext"
+	indexPath      = "path"
+	indexSize      = "size"
+)
+
+// indexKeys returns the index entries of a record.
+func indexKeys(record *model.FileRecord) []string {
+	id := record.Provider + sep + record.Path
+
+	keys := []string{
+		indexKey(indexModified, sortValue(indexModified, record)) + id,
+		indexKey(indexPath, sortValue(indexPath, record)) + id,
+		indexKey(indexSize, sortValue(indexSize, record)) + id,
+		indexKey(indexRoot, record.Root) + id,
+		indexKey(indexExtension, extension(record.Path)) + id,
+	}
+	if record.Checksum != "" {
+		keys = append(keys, indexKey(indexChecksum, record.Checksum)+id)
+	}
+	return keys
+}
+
+// indexKey returns the prefix of all entries of the index with the value.
+func indexKey(index, value string) string {
+	return indexPrefix + index + sep + value + sep
+}
+
+// parseIndexKey returns the provider and path of an index entry.
+func parseIndexKey(key string) (providerName, path string, ok bool) {
+	parts := strings.SplitN(key, sep, 5)
+	if len(parts) != 5 {
+		return "", "", false
+	}
+	return parts[3], parts[4], true
+}
+
+// modTime returns the modification time of the file, or the time it was seen.
+func modTime(record *model.FileRecord) time.Time {
+	if record.Metadata != nil && !record.Metadata.ModTime.IsZero() {
+		return record.Metadata.ModTime
+	}
+	return record.Timestamp
+}
+
+// sizeKey encodes the size so that keys sort numerically.
+func sizeKey(size int64) string {
+	return fmt.Sprintf("%020d", size)
+}
+
+// modifiedKey encodes the time so that keys sort chronologically, flipping
+// the sign bit keeps dates before 1970 in order.
+func modifiedKey(t time.Time) string {
+	return fmt.Sprintf("%020d", uint64(t.UnixNano())^(1<<63))
+}
+
+func extension(path string) string {
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+}
@@ -0,0 +1,323 @@
+package catalog
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+// Ensure Catalog satisfies the FileSearcher interface
+var _ model.FileSearcher = (*Catalog)(nil)
+
+// cursor identifies the last record of a page by its sort value and identity.
+type cursor struct {
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// hit is a record matching the query together with its sort value.
+type hit struct {
+	record model.FileRecord
+	value  string
+	id     string
+}
+
+// errStopScan ends an index scan once no further entry can be on the page.
+var errStopScan = errors.New("stop scan")
+
+// SearchFiles returns a page of the records matching the query. Ascending
+// queries without a selective filter walk the index of the sort field from
+// the cursor and stop as soon as the page is full. All other queries scan
+// the most selective index, or the range of a size or modified filter, and
+// only keep the entries of the page.
+func (c *Catalog) SearchFiles(query model.FileQuery) (*model.FileQueryResult, error) {
+	if err := validateQuery(&query); err != nil {
+		return nil, err
+	}
+
+	var after *cursor
+	if query.Cursor != "" {
+		decoded, err := decodeCursor(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		after = decoded
+	}
+
+	// One more than the limit tells whether there is a next page
+	p := &page{query: &query, after: after, size: query.Limit + 1}
+	sortPrefix := indexPrefix + query.Sort + sep
+	start := sortPrefix
+	if after != nil {
+		start = sortPrefix + after.Value + sep + after.ID
+	}
+	r, ranged := rangeFilter(&query)
+
+	err := c.db.View(func(txn model.Txn) error {
+		if prefix, ok := filterPrefix(&query); ok {
+			return txn.Iterate(prefix, func(key string, _ []byte) error {
+				return p.consider(txn, key)
+			})
+		}
+
+		if ranged && (r.index != query.Sort || query.Descending) {
+			// Only the entries in the range of the filter can match
+			return txn.IterateFrom(r.prefix, r.start, func(key string, _ []byte) error {
+				if r.past(key) {
+					return errStopScan
+				}
+				return p.consider(txn, key)
+			})
+		}
+
+		if query.Descending {
+			// Entries from the cursor on come before it in descending order
+			return txn.Iterate(sortPrefix, func(key string, _ []byte) error {
+				if after != nil && key >= start {
+					return errStopScan
+				}
+				return p.consider(txn, key)
+			})
+		}
+
+		if ranged && r.start > start {
+			start = r.start
+		}
+		return txn.IterateFrom(sortPrefix, start, func(key string, _ []byte) error {
+			if ranged && r.past(key) {
+				return errStopScan
+			}
+			if err := p.consider(txn, key); err != nil {
+				return err
+			}
+			if len(p.hits) == p.size {
+				return errStopScan
+			}
+			return nil
+		})
+	})
+	if err != nil && !errors.Is(err, errStopScan) {
+		return nil, err
+	}
+
+	result := &model.FileQueryResult{Files: []model.FileRecord{}}
+	for i := range p.hits {
+		if len(result.Files) == query.Limit {
+			last := p.hits[i-1]
+			result.NextCursor = encodeCursor(cursor{Value: last.value, ID: last.id})
+			break
+		}
+		result.Files = append(result.Files, p.hits[i].record)
+	}
+
+	return result, nil
+}
+
+// page keeps the first size hits after the cursor in sort order.
+type page struct {
+	query *model.FileQuery
+	after *cursor
+	size  int
+	hits  []hit
+}
+
+// consider adds the record of the index entry if it matches the query and
+// belongs on the page.
+func (p *page) consider(txn model.Txn, key string) error {
+	providerName, path, ok := parseIndexKey(key)
+	if !ok || (p.query.Provider != "" && providerName != p.query.Provider) {
+		return nil
+	}
+
+	record, err := Get(txn, providerName, path)
+	if errors.Is(err, model.ErrDBKeyNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !matches(p.query, record) {
+		return nil
+	}
+
+	h := hit{record: *record, value: sortValue(p.query.Sort, record), id: providerName + sep + path}
+	if p.after != nil && !less(hit{value: p.after.Value, id: p.after.ID}, h, p.query.Descending) {
+		return nil
+	}
+
+	i := sort.Search(len(p.hits), func(i int) bool {
+		return less(h, p.hits[i], p.query.Descending)
+	})
+	if i == p.size {
+		return nil
+	}
+	p.hits = append(p.hits, hit{})
+	copy(p.hits[i+1:], p.hits[i:])
+	p.hits[i] = h
+	if len(p.hits) > p.size {
+		p.hits = p.hits[:p.size]
+	}
+	return nil
+}
+
+func validateQuery(query *model.FileQuery) error {
+	switch query.Sort {
+	case "":
+		query.Sort = "path"
+	case "path", "size", "modified":
+	default:
+		return fmt.Errorf("%w: unknown sort field %q", model.ErrInvalidQuery, query.Sort)
+	}
+
+	if query.Limit <= 0 {
+		query.Limit = defaultLimit
+	}
+	query.Limit = min(query.Limit, maxLimit)
+
+	query.Extension = strings.ToLower(strings.TrimPrefix(query.Extension, "."))
+	return nil
+}
+
+// filterPrefix returns the index of the most selective filter of the query,
+// if it has one.
+func filterPrefix(query *model.FileQuery) (string, bool) {
+	switch {
+	case query.Checksum != "":
+		return indexKey(indexChecksum, query.Checksum), true
+	case query.Root != "":
+		return indexKey(indexRoot, query.Root), true
+	case query.Extension != "":
+		return indexKey(indexExtension, query.Extension), true
+	default:
+		return "", false
+	}
+}
+
+// bounds are the entries of the size or modified index that a range filter
+// allows.
+type bounds struct {
+	index  string
+	prefix string // All entries of the index
+	start  string // First entry that may match
+	end    string // Entries from end on are past the range, empty without upper bound
+}
+
+func (b bounds) past(key string) bool {
+	return b.end != "" && key >= b.end
+}
+
+// rangeFilter returns the range of the size or modified filter of the query,
+// preferring the one on the sort field, which also yields the sort order.
+func rangeFilter(query *model.FileQuery) (bounds, bool) {
+	size, hasSize := sizeBounds(query)
+	modified, hasModified := modifiedBounds(query)
+	switch {
+	case hasSize && (query.Sort == indexSize || !hasModified):
+		return size, true
+	case hasModified:
+		return modified, true
+	default:
+		return bounds{}, false
+	}
+}
+
+func sizeBounds(query *model.FileQuery) (bounds, bool) {
+	if query.MinSize <= 0 && query.MaxSize <= 0 {
+		return bounds{}, false
+	}
+
+	b := bounds{index: indexSize, prefix: indexPrefix + indexSize + sep}
+	b.start = b.prefix + sizeKey(max(query.MinSize, 0))
+	if query.MaxSize > 0 && query.MaxSize < math.MaxInt64 {
+		b.end = b.prefix + sizeKey(query.MaxSize+1)
+	}
+	return b, true
+}
+
+// modifiedBounds returns the range of the modified filter. Both bounds are
+// exclusive, entries at the lower bound are left to matches.
+func modifiedBounds(query *model.FileQuery) (bounds, bool) {
+	if query.ModifiedAfter.IsZero() && query.ModifiedBefore.IsZero() {
+		return bounds{}, false
+	}
+
+	b := bounds{index: indexModified, prefix: indexPrefix + indexModified + sep}
+	b.start = b.prefix
+	if !query.ModifiedAfter.IsZero() {
+		b.start = b.prefix + modifiedKey(query.ModifiedAfter)
+	}
+	if !query.ModifiedBefore.IsZero() {
+		b.end = b.prefix + modifiedKey(query.ModifiedBefore)
+	}
+	return b, true
+}
+
+func matches(query *model.FileQuery, record *model.FileRecord) bool {
+	switch {
+	case query.Root != "" && record.Root != query.Root:
+		return false
+	case query.PathPrefix != "" && !strings.HasPrefix(record.Path, query.PathPrefix):
+		return false
+	case query.Checksum != "" && record.Checksum != query.Checksum:
+		return false
+	case query.Extension != "" && extension(record.Path) != query.Extension:
+		return false
+	case query.MinSize > 0 && record.Size < query.MinSize:
+		return false
+	case query.MaxSize > 0 && record.Size > query.MaxSize:
+		return false
+	case !query.ModifiedAfter.IsZero() && !modTime(record).After(query.ModifiedAfter):
+		return false
+	case !query.ModifiedBefore.IsZero() && !modTime(record).Before(query.ModifiedBefore):
+		return false
+	}
+	return true
+}
+
+func sortValue(field string, record *model.FileRecord) string {
+	switch field {
+	case "size":
+		return sizeKey(record.Size)
+	case "modified":
+		return modifiedKey(modTime(record))
+	default:
+		return record.Path
+	}
+}
+
+// less orders hits by sort value, ties are broken by identity so that the
+// order is total and cursors are stable.
+func less(a, b hit, descending bool) bool {
+	if a.value != b.value {
+		return (a.value < b.value) != descending
+	}
+	return (a.id < b.id) != descending
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor: %v", model.ErrInvalidQuery, err)
+	}
+
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor: %v", model.ErrInvalidQuery, err)
+	}
+	return &c, nil
+}
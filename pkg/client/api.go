@@ -0,0 +1,241 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// OpenAPI returns the OpenAPI document of the API.
+func (c *Client) OpenAPI(ctx context.Context) (map[string]any, error) {
+	var document map[string]any
+	return document, c.do(ctx, http.MethodGet, "/api/openapi.json", nil, nil, &document)
+}
+
+// Config returns the current configuration, with secrets redacted.
+func (c *Client) Config(ctx context.Context) (*model.BackupConfig, error) {
+	var config model.BackupConfig
+	if err := c.do(ctx, http.MethodGet, "/api/config", nil, nil, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// UpdateConfig replaces the configuration. Redacted secrets keep their
+// stored value.
+func (c *Client) UpdateConfig(ctx context.Context, config *model.BackupConfig) error {
+	return c.do(ctx, http.MethodPost, "/api/config", nil, config, nil)
+}
+
+// ConfigSchema returns the JSON Schema of the configuration.
+func (c *Client) ConfigSchema(ctx context.Context) (map[string]any, error) {
+	var schema map[string]any
+	return schema, c.do(ctx, http.MethodGet, "/api/config/schema", nil, nil, &schema)
+}
+
+// Revisions returns the stored revisions of the configuration, newest first.
+func (c *Client) Revisions(ctx context.Context) ([]model.ConfigRevision, error) {
+	var revisions []model.ConfigRevision
+	return revisions, c.do(ctx, http.MethodGet, "/api/config/revisions", nil, nil, &revisions)
+}
+
+// Revision returns a revision with its configuration.
+func (c *Client) Revision(ctx context.Context, id int) (*model.ConfigRevision, error) {
+	var revision model.ConfigRevision
+	if err := c.do(ctx, http.MethodGet, "/api/config/revisions/"+strconv.Itoa(id), nil, nil, &revision); err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// DiffRevision returns the changes from revision against to revision id.
+// Against 0 compares with the current configuration.
+func (c *Client) DiffRevision(ctx context.Context, id, against int) ([]model.ConfigChange, error) {
+	query := url.Values{}
+	if against != 0 {
+		query.Set("against", strconv.Itoa(against))
+	}
+
+	var changes []model.ConfigChange
+	return changes, c.do(ctx, http.MethodGet, "/api/config/revisions/"+strconv.Itoa(id)+"/diff", query, nil, &changes)
+}
+
+// Rollback makes the configuration of a revision the current one and
+// returns the new revision.
+func (c *Client) Rollback(ctx context.Context, id int) (*model.ConfigRevision, error) {
+	var revision model.ConfigRevision
+	if err := c.do(ctx, http.MethodPost, "/api/config/revisions/"+strconv.Itoa(id)+"/rollback", nil, nil, &revision); err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// Providers returns the stored sizes and health of all providers.
+func (c *Client) Providers(ctx context.Context) (map[string]model.ProviderInfo, error) {
+	var providers map[string]model.ProviderInfo
+	return providers, c.do(ctx, http.MethodGet, "/api/providers", nil, nil, &providers)
+}
+
+// RebuildCatalog rebuilds the catalog of a provider from the files it stores.
+func (c *Client) RebuildCatalog(ctx context.Context, provider string) (*model.RebuildResult, error) {
+	var result model.RebuildResult
+	if err := c.do(ctx, http.MethodPost, "/api/providers/"+url.PathEscape(provider)+"/rebuild", nil, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SearchFiles searches the catalog of backed up files.
+func (c *Client) SearchFiles(ctx context.Context, fileQuery model.FileQuery) (*model.FileQueryResult, error) {
+	query := url.Values{}
+	set := func(name, value string) {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	set("provider", fileQuery.Provider)
+	set("root", fileQuery.Root)
+	set("pathPrefix", fileQuery.PathPrefix)
+	set("checksum", fileQuery.Checksum)
+	set("ext", fileQuery.Extension)
+	set("sort", fileQuery.Sort)
+	set("cursor", fileQuery.Cursor)
+	if fileQuery.Descending {
+		query.Set("order", "desc")
+	}
+	if fileQuery.MinSize != 0 {
+		query.Set("minSize", strconv.FormatInt(fileQuery.MinSize, 10))
+	}
+	if fileQuery.MaxSize != 0 {
+		query.Set("maxSize", strconv.FormatInt(fileQuery.MaxSize, 10))
+	}
+	if fileQuery.Limit != 0 {
+		query.Set("limit", strconv.Itoa(fileQuery.Limit))
+	}
+	if !fileQuery.ModifiedAfter.IsZero() {
+		query.Set("modifiedAfter", fileQuery.ModifiedAfter.Format(time.RFC3339))
+	}
+	if !fileQuery.ModifiedBefore.IsZero() {
+		query.Set("modifiedBefore", fileQuery.ModifiedBefore.Format(time.RFC3339))
+	}
+
+	var result model.FileQueryResult
+	if err := c.do(ctx, http.MethodGet, "/api/files", query, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Restore restores a single file.
+func (c *Client) Restore(ctx context.Context, req model.RestoreRequest) error {
+	return c.do(ctx, http.MethodPost, "/api/restore", nil, req, nil)
+}
+
+// Compliance returns the files that do not meet the replication rules.
+func (c *Client) Compliance(ctx context.Context) (*model.ComplianceReport, error) {
+	var report model.ComplianceReport
+	if err := c.do(ctx, http.MethodGet, "/api/compliance", nil, nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Throttles returns the configured and effective limits of all providers.
+func (c *Client) Throttles(ctx context.Context) (map[string]model.ThrottleStatus, error) {
+	var throttles map[string]model.ThrottleStatus
+	return throttles, c.do(ctx, http.MethodGet, "/api/throttle", nil, nil, &throttles)
+}
+
+// SetThrottle changes the limits of a provider.
+func (c *Client) SetThrottle(ctx context.Context, provider string, config model.ThrottleConfig) error {
+	return c.do(ctx, http.MethodPut, "/api/throttle/"+url.PathEscape(provider), nil, config, nil)
+}
+
+// Secrets returns the names of the secrets in the keystore.
+func (c *Client) Secrets(ctx context.Context) ([]string, error) {
+	var names []string
+	return names, c.do(ctx, http.MethodGet, "/api/secrets", nil, nil, &names)
+}
+
+// SetSecret stores a secret in the keystore.
+func (c *Client) SetSecret(ctx context.Context, name, value string) error {
+	return c.do(ctx, http.MethodPut, "/api/secrets/"+url.PathEscape(name), nil, model.SecretValue{Value: value}, nil)
+}
+
+// DeleteSecret removes a secret from the keystore.
+func (c *Client) DeleteSecret(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/api/secrets/"+url.PathEscape(name), nil, nil, nil)
+}
+
+// VerifyReports returns the latest verification report of every provider.
+func (c *Client) VerifyReports(ctx context.Context) (map[string]model.VerifyReport, error) {
+	var reports map[string]model.VerifyReport
+	return reports, c.do(ctx, http.MethodGet, "/api/verify", nil, nil, &reports)
+}
+
+// Verify starts verifying the files stored by a provider.
+func (c *Client) Verify(ctx context.Context, req model.VerifyRequest) (*model.VerifyReport, error) {
+	var report model.VerifyReport
+	if err := c.do(ctx, http.MethodPost, "/api/verify", nil, req, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Metrics returns the metrics in the Prometheus text format.
+func (c *Client) Metrics(ctx context.Context) (string, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/metrics", nil, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.send(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	return string(data), err
+}
+
+// Liveness returns whether the daemon watches files and stores the catalog.
+// A daemon that is down is reported in the result, not as error.
+func (c *Client) Liveness(ctx context.Context) (*model.ServiceHealth, error) {
+	return c.health(ctx, "/healthz")
+}
+
+// Readiness returns whether the daemon also finished the initial scan and
+// reaches every provider.
+func (c *Client) Readiness(ctx context.Context) (*model.ServiceHealth, error) {
+	return c.health(ctx, "/readyz")
+}
+
+// health reads a health check, which answers with 503 and the failed
+// components if the daemon is not healthy.
+func (c *Client) health(ctx context.Context, path string) (*model.ServiceHealth, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		return nil, responseError(resp)
+	}
+	defer resp.Body.Close()
+
+	var health model.ServiceHealth
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &health, nil
+}
@@ -0,0 +1,167 @@
+// Package client talks to the HTTP API of a running Shugosha daemon. It
+// covers every operation of the OpenAPI document served at
+// /api/openapi.json.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// Config holds the address and credentials of the daemon.
+type Config struct {
+	// URL of the daemon, e.g. "http://127.0.0.1:8080", or "unix:/path" for
+	// a Unix domain socket.
+	URL string
+	// Token is sent as bearer token. Username and Password are used for
+	// basic authentication if no token is set.
+	Token    string
+	Username string
+	Password string
+	// HTTPClient is used for requests, defaults to a client without timeout
+	// so that event streams stay open.
+	HTTPClient *http.Client
+}
+
+// Client is a typed client of the HTTP API.
+type Client struct {
+	baseURL    string
+	config     Config
+	httpClient *http.Client
+}
+
+// Error is returned for responses with an error status. Errors lists the
+// invalid fields of 400 and 422 responses.
+type Error struct {
+	StatusCode int
+	Message    string
+	Errors     model.ValidationErrors
+}
+
+func (e *Error) Error() string {
+	if len(e.Errors) > 0 {
+		return fmt.Sprintf("%d %s: %v", e.StatusCode, http.StatusText(e.StatusCode), e.Errors)
+	}
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// New creates a client for the daemon at config.URL.
+func New(config Config) (*Client, error) {
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+
+	baseURL := strings.TrimSuffix(config.URL, "/")
+	if socket, ok := strings.CutPrefix(config.URL, "unix:"); ok {
+		if socket == "" {
+			return nil, fmt.Errorf("unix socket path is empty")
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+		clone := *httpClient
+		clone.Transport = transport
+		httpClient = &clone
+		baseURL = "http://unix"
+	} else if u, err := url.Parse(baseURL); err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q", config.URL)
+	}
+
+	return &Client{baseURL: baseURL, config: config, httpClient: httpClient}, nil
+}
+
+// newRequest creates a request for path, encoding body as JSON if not nil.
+func (c *Client) newRequest(ctx context.Context, method, path string, query url.Values, body any) (*http.Request, error) {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	switch {
+	case c.config.Token != "":
+		req.Header.Set("Authorization", "Bearer "+c.config.Token)
+	case c.config.Username != "":
+		req.SetBasicAuth(c.config.Username, c.config.Password)
+	}
+
+	return req, nil
+}
+
+// send performs the request and returns the response if it has a success
+// status, and an *Error otherwise.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	return nil, responseError(resp)
+}
+
+// responseError reads the error of a response and closes its body.
+func responseError(resp *http.Response) *Error {
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	apiErr := &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+
+	var body struct {
+		Errors model.ValidationErrors `json:"errors"`
+	}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") && json.Unmarshal(data, &body) == nil {
+		apiErr.Errors = body.Errors
+	}
+	return apiErr
+}
+
+// do sends a request and decodes the JSON response into result if not nil.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, result any) error {
+	req, err := c.newRequest(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+func TestSearchFiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.Equal(t, "/api/files", r.URL.Path)
+		assert.Equal(t, "ext=jpg&limit=10&modifiedAfter=2024-01-02T03%3A04%3A05Z&order=desc&provider=Local", r.URL.RawQuery)
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"files":[{"provider":"Local","path":"/data/a.jpg"}],"nextCursor":"abc"}`)
+	}))
+	defer server.Close()
+
+	c, err := New(Config{URL: server.URL, Token: "secret"})
+	assert.NoError(t, err)
+
+	result, err := c.SearchFiles(context.Background(), model.FileQuery{
+		Provider:      "Local",
+		Extension:     "jpg",
+		Descending:    true,
+		Limit:         10,
+		ModifiedAfter: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+	assert.NoError(t, err)
+	assert.Len(t, result.Files, 1)
+	assert.Equal(t, "abc", result.NextCursor)
+}
+
+func TestError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/config" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			fmt.Fprint(w, `{"errors":[{"field":"providers[0].name","message":"is required"}]}`)
+			return
+		}
+		http.Error(w, "Unknown provider: S3", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	c, err := New(Config{URL: server.URL})
+	assert.NoError(t, err)
+
+	err = c.UpdateConfig(context.Background(), &model.BackupConfig{})
+	var apiErr *Error
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnprocessableEntity, apiErr.StatusCode)
+	assert.Equal(t, model.ValidationErrors{{Field: "providers[0].name", Message: "is required"}}, apiErr.Errors)
+
+	err = c.SetThrottle(context.Background(), "S3", model.ThrottleConfig{})
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, "Unknown provider: S3", apiErr.Message)
+}
+
+func TestEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "7", r.Header.Get("Last-Event-ID"))
+		assert.Equal(t, []string{"succeeded", "failed"}, r.URL.Query()["type"])
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": heartbeat\n\n")
+		fmt.Fprint(w, "id: 8\nevent: succeeded\ndata: {\"id\":8,\"type\":\"succeeded\",\"path\":\"/data/a.txt\"}\n\n")
+		fmt.Fprint(w, "id: 9\nevent: failed\ndata: {\"id\":9,\"type\":\"failed\",\"error\":\"quota exceeded\"}\n\n")
+	}))
+	defer server.Close()
+
+	c, err := New(Config{URL: server.URL})
+	assert.NoError(t, err)
+
+	stream, err := c.Events(context.Background(), model.ActivityFilter{Types: []model.ActivityType{model.ActivitySucceeded, model.ActivityFailed}}, 7)
+	assert.NoError(t, err)
+	defer stream.Close()
+
+	event, err := stream.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, "/data/a.txt", event.Path)
+
+	event, err = stream.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, "quota exceeded", event.Error)
+	assert.Equal(t, uint64(9), stream.LastID())
+
+	_, err = stream.Next()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestInvalidURL(t *testing.T) {
+	_, err := New(Config{URL: "localhost:8090"})
+	assert.Error(t, err)
+	_, err = New(Config{URL: "unix:"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// EventStream reads backup activity sent as Server-Sent Events.
+type EventStream struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+	lastID  uint64
+}
+
+// Events opens a stream of backup activity matching filter. A lastID other
+// than 0 resumes after that event; pass LastID of a closed stream to
+// continue where it stopped.
+func (c *Client) Events(ctx context.Context, filter model.ActivityFilter, lastID uint64) (*EventStream, error) {
+	query := url.Values{}
+	for _, provider := range filter.Providers {
+		query.Add("provider", provider)
+	}
+	for _, root := range filter.Roots {
+		query.Add("root", root)
+	}
+	for _, activityType := range filter.Types {
+		query.Add("type", string(activityType))
+	}
+
+	req, err := c.newRequest(ctx, http.MethodGet, "/api/events", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if lastID != 0 {
+		req.Header.Set("Last-Event-ID", strconv.FormatUint(lastID, 10))
+	}
+
+	resp, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+	return &EventStream{body: resp.Body, scanner: bufio.NewScanner(resp.Body), lastID: lastID}, nil
+}
+
+// Next blocks until the next event arrives. It returns io.EOF when the
+// daemon closed the stream.
+func (s *EventStream) Next() (model.ActivityEvent, error) {
+	var data strings.Builder
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+
+		if line == "" {
+			if data.Len() == 0 {
+				continue
+			}
+			var event model.ActivityEvent
+			if err := json.Unmarshal([]byte(data.String()), &event); err != nil {
+				return event, fmt.Errorf("failed to decode event: %w", err)
+			}
+			s.lastID = event.ID
+			return event, nil
+		}
+
+		// Lines starting with a colon are comments, e.g. heartbeats; id and
+		// event repeat fields of the data.
+		if value, ok := strings.CutPrefix(line, "data:"); ok {
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(value, " "))
+		}
+	}
+
+	if err := s.scanner.Err(); err != nil {
+		return model.ActivityEvent{}, err
+	}
+	return model.ActivityEvent{}, io.EOF
+}
+
+// LastID returns the ID of the last received event.
+func (s *EventStream) LastID() uint64 {
+	return s.lastID
+}
+
+// Close closes the stream.
+func (s *EventStream) Close() error {
+	return s.body.Close()
+}
@@ -0,0 +1,175 @@
+// Package compliance checks backed up files against the replication rules of
+// the configuration.
+package compliance
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// Checker builds compliance reports from the current configuration and the
+// replicas tracked by the backup manager.
+type Checker struct {
+	configManager model.ConfigManager
+	replicas      model.ReplicaTracker
+}
+
+// Ensure Checker satisfies the ComplianceChecker interface
+var _ model.ComplianceChecker = (*Checker)(nil)
+
+func NewChecker(configManager model.ConfigManager, replicas model.ReplicaTracker) *Checker {
+	return &Checker{
+		configManager: configManager,
+		replicas:      replicas,
+	}
+}
+
+// Compliance lists the files that have fewer current copies than their
+// replication rule requires, including files without any copy.
+func (c *Checker) Compliance() (*model.ComplianceReport, error) {
+	config, err := c.configManager.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	replicas, err := c.replicas.Replicas()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replicas: %w", err)
+	}
+
+	files, err := coveredFiles(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	return Check(config, replicas, files), nil
+}
+
+// Check compares the replicas with the rules of the configuration. files are
+// the paths covered by the rules, those without a replica have no copy at all.
+func Check(config *model.BackupConfig, replicas []model.ReplicaStatus, files []string) *model.ComplianceReport {
+	report := &model.ComplianceReport{Rules: []model.RuleStatus{}, Issues: []model.ComplianceIssue{}}
+	if len(config.Replication) == 0 {
+		return report
+	}
+
+	replicas = withUncopied(replicas, files)
+
+	offsite := map[string]bool{}
+	for _, provider := range config.Providers {
+		offsite[provider.Name] = provider.Offsite
+	}
+
+	for _, rule := range config.Replication {
+		report.Rules = append(report.Rules, ruleStatus(config, rule))
+	}
+
+	for _, replica := range replicas {
+		rule, ok := ruleFor(config.Replication, replica.Path)
+		if !ok {
+			continue
+		}
+		report.Checked++
+
+		issue := model.ComplianceIssue{ReplicaStatus: replica, Rule: rule.Path}
+		for _, name := range replica.Current {
+			isOffsite, configured := offsite[name]
+			if !configured {
+				continue
+			}
+			issue.Copies++
+			if isOffsite {
+				issue.Offsite++
+			}
+		}
+
+		if issue.Copies < rule.MinCopies || issue.Offsite < rule.MinOffsite {
+			report.Issues = append(report.Issues, issue)
+		}
+	}
+
+	return report
+}
+
+// withUncopied adds an empty replica for every file that has none, sorted
+// by path like the replicas.
+func withUncopied(replicas []model.ReplicaStatus, files []string) []model.ReplicaStatus {
+	known := make(map[string]bool, len(replicas))
+	for _, replica := range replicas {
+		known[replica.Path] = true
+	}
+
+	all := append([]model.ReplicaStatus{}, replicas...)
+	for _, path := range files {
+		if !known[path] {
+			all = append(all, model.ReplicaStatus{Path: path, Current: []string{}})
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Path < all[j].Path })
+	return all
+}
+
+// ruleStatus tells whether enough providers back up the directory of a rule.
+func ruleStatus(config *model.BackupConfig, rule model.ReplicationRule) model.RuleStatus {
+	status := model.RuleStatus{Rule: rule, Providers: []string{}, Offsite: []string{}}
+
+	for _, provider := range config.Providers {
+		for _, dir := range provider.DirectoryList {
+			if isUnder(rule.Path, dir) {
+				status.Providers = append(status.Providers, provider.Name)
+				if provider.Offsite {
+					status.Offsite = append(status.Offsite, provider.Name)
+				}
+				break
+			}
+		}
+	}
+
+	status.Satisfiable = len(status.Providers) >= rule.MinCopies && len(status.Offsite) >= rule.MinOffsite
+	return status
+}
+
+// ruleFor returns the most specific rule covering path.
+func ruleFor(rules []model.ReplicationRule, path string) (model.ReplicationRule, bool) {
+	var best model.ReplicationRule
+	found := false
+
+	for _, rule := range rules {
+		if isUnder(path, rule.Path) && (!found || len(rule.Path) > len(best.Path)) {
+			best = rule
+			found = true
+		}
+	}
+	return best, found
+}
+
+// isUnder reports whether path is dir or inside of it.
+func isUnder(path, dir string) bool {
+	path, dir = filepath.Clean(path), filepath.Clean(dir)
+	if path == dir {
+		return true
+	}
+	if !strings.HasSuffix(dir, string(filepath.Separator)) {
+		dir += string(filepath.Separator)
+	}
+	return strings.HasPrefix(path, dir)
+}
+
+// ValidateRule checks a replication rule and returns the problems by field name.
+func ValidateRule(rule model.ReplicationRule) map[string]string {
+	problems := map[string]string{}
+	if rule.Path == "" {
+		problems["path"] = "is required"
+	}
+	if rule.MinCopies < 1 {
+		problems["minCopies"] = "must be at least 1"
+	}
+	if rule.MinOffsite < 0 || rule.MinOffsite > rule.MinCopies {
+		problems["minOffsite"] = "must be between 0 and minCopies"
+	}
+	return problems
+}
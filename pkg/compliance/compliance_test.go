@@ -0,0 +1,87 @@
+package compliance
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+func TestCheck(t *testing.T) {
+	config := &model.BackupConfig{
+		Providers: []model.ProviderConfig{
+			{Name: "Local", DirectoryList: []string{"/data"}},
+			{Name: "NAS", DirectoryList: []string{"/data/docs"}, Offsite: true},
+		},
+		Replication: []model.ReplicationRule{
+			{Path: "/data", MinCopies: 1},
+			{Path: "/data/docs", MinCopies: 2, MinOffsite: 1},
+			{Path: "/photos", MinCopies: 1},
+		},
+	}
+
+	replicas := []model.ReplicaStatus{
+		{Path: "/data/a.txt", Current: []string{"Local"}},
+		{Path: "/data/docs/ok.txt", Current: []string{"Local", "NAS"}},
+		{Path: "/data/docs/stale.txt", Current: []string{"Local"}, Stale: []string{"NAS"}},
+		{Path: "/data/docsx/b.txt", Current: []string{"Local"}},
+		{Path: "/other/c.txt", Current: []string{"Local"}},
+	}
+
+	files := []string{"/data/a.txt", "/data/new.txt"}
+
+	report := Check(config, replicas, files)
+
+	assert.Equal(t, 5, report.Checked)
+	assert.Len(t, report.Issues, 2)
+	assert.Equal(t, "/data/docs/stale.txt", report.Issues[0].Path)
+	assert.Equal(t, "/data/docs", report.Issues[0].Rule)
+	assert.Equal(t, 1, report.Issues[0].Copies)
+	assert.Equal(t, 0, report.Issues[0].Offsite)
+
+	// A file that was never backed up has no copies
+	assert.Equal(t, "/data/new.txt", report.Issues[1].Path)
+	assert.Equal(t, "/data", report.Issues[1].Rule)
+	assert.Equal(t, 0, report.Issues[1].Copies)
+
+	assert.True(t, report.Rules[0].Satisfiable)
+	assert.True(t, report.Rules[1].Satisfiable)
+	assert.Equal(t, []string{"NAS"}, report.Rules[1].Offsite)
+	assert.False(t, report.Rules[2].Satisfiable)
+}
+
+func TestCoveredFiles(t *testing.T) {
+	dir := t.TempDir()
+	skipped := filepath.Join(dir, "skipped")
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "docs", "sub"), 0o700))
+	assert.NoError(t, os.MkdirAll(skipped, 0o700))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "docs", "sub", "a.txt"), nil, 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(skipped, "b.txt"), nil, 0o600))
+	assert.NoError(t, os.Symlink("sub/a.txt", filepath.Join(dir, "docs", "link")))
+	assert.NoError(t, os.Symlink("b.txt", filepath.Join(skipped, "link")))
+
+	files, err := coveredFiles(&model.BackupConfig{
+		Directories: map[string]model.DirectoryOptions{skipped: {SymlinkPolicy: model.SymlinkSkip}},
+		Replication: []model.ReplicationRule{
+			{Path: dir, MinCopies: 1},
+			{Path: filepath.Join(dir, "docs"), MinCopies: 2},
+			{Path: filepath.Join(dir, "missing"), MinCopies: 1},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "docs", "link"),
+		filepath.Join(dir, "docs", "sub", "a.txt"),
+		filepath.Join(skipped, "b.txt"),
+	}, files)
+}
+
+func TestValidateRule(t *testing.T) {
+	assert.Empty(t, ValidateRule(model.ReplicationRule{Path: "/data", MinCopies: 2, MinOffsite: 1}))
+	assert.Contains(t, ValidateRule(model.ReplicationRule{MinCopies: 1}), "path")
+	assert.Contains(t, ValidateRule(model.ReplicationRule{Path: "/data"}), "minCopies")
+	assert.Contains(t, ValidateRule(model.ReplicationRule{Path: "/data", MinCopies: 1, MinOffsite: 2}), "minOffsite")
+}
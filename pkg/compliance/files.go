@@ -0,0 +1,93 @@
+package compliance
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// coveredFiles walks the directories of the replication rules and returns
+// every file the monitor would back up, following the symlink policy of the
+// watched directory it is in. Files that were never backed up have no
+// replicas, so they are only found this way.
+func coveredFiles(config *model.BackupConfig) ([]string, error) {
+	files := map[string]bool{}
+	for _, rule := range config.Replication {
+		if err := walkFiles(config, rule.Path, files, map[string]bool{}); err != nil {
+			return nil, err
+		}
+	}
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// walkFiles adds the files below dir to files. Directories reached through
+// followed links are tracked by their real path, so link loops are walked
+// only once.
+func walkFiles(config *model.BackupConfig, dir string, files, visited map[string]bool) error {
+	realDir, err := filepath.EvalSymlinks(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if visited[realDir] {
+		return nil
+	}
+	visited[realDir] = true
+
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			slog.Warn("[compliance] skipping unreadable path", "path", path, "error", err)
+			return nil
+		}
+
+		switch {
+		case entry.Type().IsRegular():
+			files[path] = true
+
+		case entry.Type()&os.ModeSymlink != 0:
+			switch symlinkPolicy(config, path) {
+			case model.SymlinkSkip:
+			case model.SymlinkFollow:
+				info, err := os.Stat(path)
+				if err != nil {
+					return nil
+				}
+				if info.IsDir() {
+					return walkFiles(config, path, files, visited)
+				}
+				if info.Mode().IsRegular() {
+					files[path] = true
+				}
+			default:
+				files[path] = true
+			}
+		}
+		return nil
+	})
+}
+
+// symlinkPolicy returns the policy of the most specific watched directory
+// holding path.
+func symlinkPolicy(config *model.BackupConfig, path string) model.SymlinkPolicy {
+	var policy model.SymlinkPolicy
+	best := ""
+	for dir, options := range config.Directories {
+		if isUnder(path, dir) && len(dir) > len(best) {
+			best = dir
+			policy = options.SymlinkPolicy
+		}
+	}
+	return policy
+}
@@ -2,44 +2,180 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"runtime"
 
 	"github.com/sevigo/shugosha/pkg/model"
 )
 
-func LoadDefaultConfig() *model.BackupConfig {
-	var configPath string
+const (
+	// EnvConfig names the environment variable holding the config file path.
+	EnvConfig = "SHUGOSHA_CONFIG"
+
+	appName = "shugosha"
+)
+
+// fileNames are the names of config files in the searched directories.
+var fileNames = []string{"config.json", "config.yaml", "config.yml", "config.toml"}
+
+// ErrNotFound is returned by Find when none of the search paths holds a config file.
+var ErrNotFound = errors.New("no config file found")
+
+// Find returns the config file to use: the explicit path if given, then the
+// SHUGOSHA_CONFIG environment variable, then the first existing file of
+// SearchPaths.
+func Find(explicit string) (string, error) {
+	if explicit != "" {
+		return explicit, nil
+	}
+	if path := os.Getenv(EnvConfig); path != "" {
+		return path, nil
+	}
+
+	for _, path := range SearchPaths() {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", ErrNotFound
+}
+
+// SearchPaths lists the locations of config files, the user's config
+// directory first, then the system wide ones and the working directory.
+func SearchPaths() []string {
+	var dirs []string
+	if dir, err := os.UserConfigDir(); err == nil {
+		dirs = append(dirs, filepath.Join(dir, appName))
+	}
+	dirs = append(dirs, systemDirs()...)
+
+	// The working directory, which also holds the per OS names used by earlier versions
+	dirs = append(dirs, "")
+
+	var paths []string
+	for _, dir := range dirs {
+		for _, name := range fileNames {
+			paths = append(paths, filepath.Join(dir, name))
+		}
+	}
+	return append(paths, legacyFileName())
+}
+
+func systemDirs() []string {
 	switch runtime.GOOS {
 	case "windows":
-		configPath = "../../config.win.json"
+		if dir := os.Getenv("ProgramData"); dir != "" {
+			return []string{filepath.Join(dir, appName)}
+		}
+		return nil
+
 	case "darwin":
-		configPath = "../../config.mac.json"
-	case "linux":
-		configPath = "config.linux.json"
+		return []string{
+			filepath.Join("/Library/Application Support", appName),
+			filepath.Join("/etc", appName),
+		}
+
 	default:
-		log.Fatalf("Unsupported operating system")
+		xdgDirs := os.Getenv("XDG_CONFIG_DIRS")
+		if xdgDirs == "" {
+			xdgDirs = "/etc/xdg"
+		}
+
+		var dirs []string
+		for _, dir := range filepath.SplitList(xdgDirs) {
+			dirs = append(dirs, filepath.Join(dir, appName))
+		}
+		return append(dirs, filepath.Join("/etc", appName))
 	}
+}
 
-	backupConfig, err := LoadConfig(configPath)
-	if err != nil {
-		log.Fatalf("Error loading config: %s", err)
+func legacyFileName() string {
+	switch runtime.GOOS {
+	case "windows":
+		return "config.win.json"
+	case "darwin":
+		return "config.mac.json"
+	default:
+		return "config.linux.json"
 	}
+}
+
+// DataDir returns the directory for application data, following the XDG
+// base directory specification on Unix systems.
+func DataDir() (string, error) {
+	switch runtime.GOOS {
+	case "windows":
+		if dir := os.Getenv("LocalAppData"); dir != "" {
+			return filepath.Join(dir, appName), nil
+		}
+		return "", errors.New("%LocalAppData% is not defined")
+
+	case "darwin":
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, "Library", "Application Support", appName), nil
 
-	return backupConfig
+	default:
+		if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+			return filepath.Join(dir, appName), nil
+		}
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, ".local", "share", appName), nil
+	}
 }
 
+// LoadDefaultConfig loads the config file returned by Find. Without any
+// config file an empty configuration is returned, so that providers can be
+// added through the API.
+func LoadDefaultConfig(explicit string) (*model.BackupConfig, error) {
+	path, err := Find(explicit)
+	if errors.Is(err, ErrNotFound) {
+		slog.Info("No config file found, starting without providers", "searched", SearchPaths())
+		return &model.BackupConfig{}, nil
+	}
+
+	slog.Info("Loading config file", "path", path)
+	return LoadConfig(path)
+}
+
+// LoadConfig reads a JSON, YAML or TOML config file, chosen by its extension.
+// Files listed under "include" are loaded first and overridden by the
+// including file, and ${ENV_VAR} references in values are expanded, except in
+// provider settings and API tokens, which are resolved where they are used.
 func LoadConfig(configPath string) (*model.BackupConfig, error) {
+	return loadConfig(configPath, map[string]bool{})
+}
+
+// loadConfig is LoadConfig, adding the absolute paths of the config file and
+// its includes to files, even if loading fails.
+func loadConfig(configPath string, files map[string]bool) (*model.BackupConfig, error) {
 	var config model.BackupConfig
 
-	configFile, err := os.ReadFile(configPath)
+	tree, err := loadTree(configPath, map[string]bool{}, files)
+	if err != nil {
+		return nil, err
+	}
+
+	expanded, err := expand(tree, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand %s: %w", configPath, err)
+	}
+
+	data, err := json.Marshal(expanded)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	err = json.Unmarshal(configFile, &config)
+	err = json.Unmarshal(data, &config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+func TestFind(t *testing.T) {
+	t.Setenv(EnvConfig, "/from/env.json")
+	path, err := Find("/explicit.json")
+	assert.NoError(t, err)
+	assert.Equal(t, "/explicit.json", path)
+
+	path, err = Find("")
+	assert.NoError(t, err)
+	assert.Equal(t, "/from/env.json", path)
+}
+
+func TestFindSearchesUserConfigDir(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("XDG_CONFIG_HOME is only used on Linux")
+	}
+
+	dir := t.TempDir()
+	t.Setenv(EnvConfig, "")
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	expected := filepath.Join(dir, "shugosha", "config.json")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(expected), 0o700))
+	assert.NoError(t, os.WriteFile(expected, []byte(`{"providers":[{"name":"Local","type":"Local"}]}`), 0o600))
+
+	path, err := Find("")
+	assert.NoError(t, err)
+	assert.Equal(t, expected, path)
+
+	config, err := LoadDefaultConfig("")
+	assert.NoError(t, err)
+	assert.Equal(t, []model.ProviderConfig{{Name: "Local", Type: "Local"}}, config.Providers)
+}
+
+func TestLoadDefaultConfigMissingExplicitFile(t *testing.T) {
+	_, err := LoadDefaultConfig(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
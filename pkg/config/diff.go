@@ -0,0 +1,85 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// Diff lists the differences between two configurations by field path.
+func Diff(from, to *model.BackupConfig) ([]model.ConfigChange, error) {
+	fromTree, err := toTree(from)
+	if err != nil {
+		return nil, err
+	}
+	toTree, err := toTree(to)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []model.ConfigChange{}
+	diffValues("", fromTree, toTree, &changes)
+	return changes, nil
+}
+
+// toTree converts a configuration into the generic tree of its JSON form.
+func toTree(config *model.BackupConfig) (any, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var tree any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+func diffValues(path string, from, to any, changes *[]model.ConfigChange) {
+	fromMap, fromIsMap := from.(map[string]any)
+	toMap, toIsMap := to.(map[string]any)
+	if fromIsMap && toIsMap {
+		keys := map[string]bool{}
+		for key := range fromMap {
+			keys[key] = true
+		}
+		for key := range toMap {
+			keys[key] = true
+		}
+
+		for _, key := range sortedKeys(keys) {
+			diffValues(joinPath(path, key), fromMap[key], toMap[key], changes)
+		}
+		return
+	}
+
+	fromList, fromIsList := from.([]any)
+	toList, toIsList := to.([]any)
+	if fromIsList && toIsList {
+		for i := 0; i < len(fromList) || i < len(toList); i++ {
+			var fromItem, toItem any
+			if i < len(fromList) {
+				fromItem = fromList[i]
+			}
+			if i < len(toList) {
+				toItem = toList[i]
+			}
+			diffValues(fmt.Sprintf("%s[%d]", path, i), fromItem, toItem, changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(from, to) {
+		*changes = append(*changes, model.ConfigChange{Path: path, Old: from, New: to})
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,194 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sevigo/shugosha/pkg/model"
+	"github.com/sevigo/shugosha/pkg/secrets"
+)
+
+// reloadDelay is how long the config file has to be unchanged before it is reloaded.
+const reloadDelay = 500 * time.Millisecond
+
+// isTemplate reports whether the config file includes other files or refers
+// to environment variables. Such files are not overwritten, since writing the
+// resolved configuration would lose the includes and leak the variables.
+func isTemplate(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+
+	tree, err := parse(path, data)
+	if err != nil {
+		return false
+	}
+	if _, _, ok := lookup(tree, includeKey); ok {
+		return true
+	}
+	return secrets.HasVariables(string(data))
+}
+
+// writeConfigFile writes the configuration to path in the format given by
+// its extension, replacing the file only once it is completely written.
+func writeConfigFile(path string, config *model.BackupConfig) error {
+	tree, err := toTree(config)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		data, err = yaml.Marshal(tree)
+
+	case ".toml":
+		var buf bytes.Buffer
+		err = toml.NewEncoder(&buf).Encode(tree)
+		data = buf.Bytes()
+
+	default:
+		data, err = json.MarshalIndent(tree, "", "  ")
+		data = append(data, '\n')
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".shugosha-config-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Watch reloads the config file whenever it or one of the files it includes
+// changes, as long as the manager is in file mode. The watched files are
+// updated after every reload. It returns when ctx is cancelled.
+func (m *Manager) Watch(ctx context.Context) error {
+	if m.mode != ModeFile {
+		return nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create config watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	// Only the files are needed, the configuration was loaded before
+	files := map[string]bool{}
+	loadConfig(m.path, files)
+	w := &configWatcher{watcher: watcher, dirs: map[string]bool{}}
+	if err := w.watch(files); err != nil {
+		return fmt.Errorf("failed to watch config file: %w", err)
+	}
+
+	timer := time.NewTimer(reloadDelay)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if w.files[filepath.Clean(event.Name)] {
+				timer.Reset(reloadDelay)
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Error("Config watcher failed", "error", err)
+
+		case <-timer.C:
+			if err := w.watch(m.reload()); err != nil {
+				slog.Error("Failed to watch config files", "error", err)
+			}
+		}
+	}
+}
+
+// configWatcher watches the directories of the config files, editors often
+// replace a file instead of writing it.
+type configWatcher struct {
+	watcher *fsnotify.Watcher
+	files   map[string]bool // Absolute paths of the config file and its includes
+	dirs    map[string]bool // Watched directories
+}
+
+// watch replaces the watched files, adding and removing their directories.
+func (w *configWatcher) watch(files map[string]bool) error {
+	dirs := map[string]bool{}
+	for file := range files {
+		dirs[filepath.Dir(file)] = true
+	}
+
+	for dir := range w.dirs {
+		if !dirs[dir] {
+			w.watcher.Remove(dir)
+			delete(w.dirs, dir)
+		}
+	}
+	w.files = files
+
+	var errs []error
+	for dir := range dirs {
+		if w.dirs[dir] {
+			continue
+		}
+		if err := w.watcher.Add(dir); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		w.dirs[dir] = true
+	}
+	return errors.Join(errs...)
+}
+
+// reload loads the config file and saves it if it is valid. It returns the
+// files the configuration was loaded from.
+func (m *Manager) reload() map[string]bool {
+	files := map[string]bool{}
+	config, err := loadConfig(m.path, files)
+	if err != nil {
+		slog.Error("Failed to reload config file, keeping the current configuration", "path", m.path, "error", err)
+		return files
+	}
+
+	if err := m.save(config, "file", ""); err != nil {
+		slog.Error("Invalid config file, keeping the current configuration", "path", m.path, "error", err)
+		return files
+	}
+	slog.Info("Reloaded config file", "path", m.path)
+	return files
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+const (
+	revisionPrefix  = "config:revision:"
+	lastRevisionKey = "config:lastRevision"
+
+	// maxRevisions is the number of revisions kept, older ones are removed.
+	maxRevisions = 100
+)
+
+// Ensure Manager satisfies the ConfigHistory interface
+var _ model.ConfigHistory = (*Manager)(nil)
+
+func revisionKey(id int) string {
+	return fmt.Sprintf("%s%010d", revisionPrefix, id)
+}
+
+// addRevision stores the configuration as the next revision, together with
+// the changes that wait for a restart.
+func addRevision(txn model.Txn, config *model.BackupConfig, source, comment string, restart []string) error {
+	id := 1
+	value, err := txn.Get(lastRevisionKey)
+	if err == nil {
+		last, err := strconv.Atoi(string(value))
+		if err != nil {
+			return fmt.Errorf("invalid last revision %q: %w", value, err)
+		}
+		id = last + 1
+	} else if !errors.Is(err, model.ErrDBKeyNotFound) {
+		return err
+	}
+
+	revision := model.ConfigRevision{ID: id, Created: time.Now(), Source: source, Comment: comment, Config: config, RestartRequired: restart}
+	data, err := json.Marshal(revision)
+	if err != nil {
+		return err
+	}
+
+	if err := txn.Set(revisionKey(id), data); err != nil {
+		return err
+	}
+	if id > maxRevisions {
+		if err := txn.Delete(revisionKey(id - maxRevisions)); err != nil {
+			return err
+		}
+	}
+	return txn.Set(lastRevisionKey, []byte(strconv.Itoa(id)))
+}
+
+// Revisions lists all stored revisions, newest first, without their configuration.
+func (m *Manager) Revisions() ([]model.ConfigRevision, error) {
+	revisions := []model.ConfigRevision{}
+	err := m.db.Iterate(revisionPrefix, func(key string, value []byte) error {
+		var revision model.ConfigRevision
+		if err := json.Unmarshal(value, &revision); err != nil {
+			return fmt.Errorf("failed to unmarshal revision %q: %w", key, err)
+		}
+		revision.Config = nil
+		revisions = append([]model.ConfigRevision{revision}, revisions...)
+		return nil
+	})
+	return revisions, err
+}
+
+// Revision returns a stored revision with its configuration.
+func (m *Manager) Revision(id int) (*model.ConfigRevision, error) {
+	value, err := m.db.Get(revisionKey(id))
+	if err != nil {
+		return nil, fmt.Errorf("revision %d: %w", id, err)
+	}
+
+	var revision model.ConfigRevision
+	if err := json.Unmarshal(value, &revision); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal revision %d: %w", id, err)
+	}
+	return &revision, nil
+}
+
+// DiffRevisions compares two revisions, a revision of 0 is the current configuration.
+func (m *Manager) DiffRevisions(from, to int) ([]model.ConfigChange, error) {
+	fromConfig, err := m.revisionConfig(from)
+	if err != nil {
+		return nil, err
+	}
+	toConfig, err := m.revisionConfig(to)
+	if err != nil {
+		return nil, err
+	}
+	return Diff(fromConfig, toConfig)
+}
+
+func (m *Manager) revisionConfig(id int) (*model.BackupConfig, error) {
+	if id == 0 {
+		return m.LoadConfig()
+	}
+
+	revision, err := m.Revision(id)
+	if err != nil {
+		return nil, err
+	}
+	return revision.Config, nil
+}
+
+// Rollback saves the configuration of a revision as a new revision. It is
+// refused in file mode, where the config file has to be changed instead.
+func (m *Manager) Rollback(id int) (*model.ConfigRevision, error) {
+	if m.mode == ModeFile {
+		return nil, model.ErrConfigReadOnly
+	}
+
+	revision, err := m.Revision(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.save(revision.Config, "rollback", fmt.Sprintf("rollback to revision %d", id)); err != nil {
+		return nil, err
+	}
+
+	revisions, err := m.Revisions()
+	if err != nil || len(revisions) == 0 {
+		return nil, err
+	}
+	return m.Revision(revisions[0].ID)
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sevigo/shugosha/pkg/db"
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+func TestDiff(t *testing.T) {
+	from := &model.BackupConfig{Providers: []model.ProviderConfig{
+		{Name: "Local", Type: "Local", Settings: map[string]string{"path": "/backup"}},
+	}}
+	to := &model.BackupConfig{Providers: []model.ProviderConfig{
+		{Name: "Local", Type: "Local", Settings: map[string]string{"path": "/mnt/backup"}},
+		{Name: "Echo", Type: "Echo"},
+	}}
+
+	changes, err := Diff(from, to)
+	assert.NoError(t, err)
+	assert.Equal(t, "providers[0].settings.path", changes[0].Path)
+	assert.Equal(t, "/backup", changes[0].Old)
+	assert.Equal(t, "/mnt/backup", changes[0].New)
+	assert.Equal(t, "providers[1]", changes[1].Path)
+	assert.Nil(t, changes[1].Old)
+
+	changes, err = Diff(to, to)
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestRevisionsAndRollback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"providers": [{"name": "Echo", "type": "Echo"}]}`), 0o600))
+
+	cm, err := NewConfigManager(db.NewMemoryDB(), Options{Path: path})
+	assert.NoError(t, err)
+	m := cm.(*Manager)
+
+	var applied *model.BackupConfig
+	m.OnChange(func(config *model.BackupConfig) { applied = config })
+
+	changed := &model.BackupConfig{Providers: []model.ProviderConfig{{Name: "Other", Type: "Echo"}}}
+	assert.NoError(t, m.SaveConfig(changed))
+	assert.Equal(t, changed, applied)
+
+	// Saving the same configuration again adds no revision
+	assert.NoError(t, m.SaveConfig(changed))
+	revisions, err := m.Revisions()
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 2)
+	assert.Equal(t, 2, revisions[0].ID)
+	assert.Equal(t, "api", revisions[0].Source)
+	assert.Nil(t, revisions[0].Config)
+	assert.Equal(t, []string{"providers[0].name"}, revisions[0].RestartRequired)
+
+	// API changes are written back to the config file
+	written, err := LoadConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, changed, written)
+
+	changes, err := m.DiffRevisions(1, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.ConfigChange{{Path: "providers[0].name", Old: "Echo", New: "Other"}}, changes)
+
+	revision, err := m.Rollback(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, revision.ID)
+	assert.Equal(t, "rollback", revision.Source)
+	assert.Equal(t, []string{"providers[0].name"}, revision.RestartRequired)
+
+	current, err := m.LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "Echo", current.Providers[0].Name)
+
+	_, err = m.Rollback(42)
+	assert.ErrorIs(t, err, model.ErrDBKeyNotFound)
+}
+
+func TestFileModeIsReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("providers:\n  - name: Echo\n    type: Echo\n"), 0o600))
+
+	cm, err := NewConfigManager(db.NewMemoryDB(), Options{Path: path, Mode: ModeFile})
+	assert.NoError(t, err)
+
+	err = cm.SaveConfig(&model.BackupConfig{})
+	assert.ErrorIs(t, err, model.ErrConfigReadOnly)
+
+	_, err = cm.(*Manager).Rollback(1)
+	assert.ErrorIs(t, err, model.ErrConfigReadOnly)
+
+	// Changes to the file are picked up on reload
+	assert.NoError(t, os.WriteFile(path, []byte("providers:\n  - name: Changed\n    type: Echo\n"), 0o600))
+	cm.(*Manager).reload()
+
+	config, err := cm.LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "Changed", config.Providers[0].Name)
+
+	// The provider change waits for a restart, which the revision tells
+	revisions, err := cm.(*Manager).Revisions()
+	assert.NoError(t, err)
+	assert.Equal(t, "file", revisions[0].Source)
+	assert.Equal(t, []string{"providers[0].name"}, revisions[0].RestartRequired)
+}
+
+func TestWatchReloadsIncludes(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	base := filepath.Join(dir, "conf.d", "base.yaml")
+	other := filepath.Join(dir, "conf.d", "other.yaml")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(base), 0o700))
+	assert.NoError(t, os.WriteFile(base, []byte("providers:\n  - name: Base\n    type: Echo\n"), 0o600))
+	assert.NoError(t, os.WriteFile(other, []byte("providers:\n  - name: Other\n    type: Echo\n"), 0o600))
+	assert.NoError(t, os.WriteFile(path, []byte("include: conf.d/base.yaml\n"), 0o600))
+
+	cm, err := NewConfigManager(db.NewMemoryDB(), Options{Path: path, Mode: ModeFile})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go cm.(*Manager).Watch(ctx)
+
+	providerName := func() string {
+		config, err := cm.LoadConfig()
+		assert.NoError(t, err)
+		return config.Providers[0].Name
+	}
+
+	// Give the watcher time to start before the first change
+	time.Sleep(100 * time.Millisecond)
+	assert.NoError(t, os.WriteFile(base, []byte("providers:\n  - name: Changed\n    type: Echo\n"), 0o600))
+	assert.Eventually(t, func() bool { return providerName() == "Changed" }, 5*time.Second, 50*time.Millisecond)
+
+	// A newly included file is watched after the reload
+	assert.NoError(t, os.WriteFile(path, []byte("include: conf.d/other.yaml\n"), 0o600))
+	assert.Eventually(t, func() bool { return providerName() == "Other" }, 5*time.Second, 50*time.Millisecond)
+	assert.NoError(t, os.WriteFile(other, []byte("providers:\n  - name: Edited\n    type: Echo\n"), 0o600))
+	assert.Eventually(t, func() bool { return providerName() == "Edited" }, 5*time.Second, 50*time.Millisecond)
+}
@@ -0,0 +1,269 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sevigo/shugosha/pkg/secrets"
+)
+
+// includeKey lists the files a config file is based on.
+const includeKey = "include"
+
+// loadTree reads a config file and the files it includes into a generic tree.
+// Included files are loaded first and overridden by the including file. The
+// absolute paths of all files it tries to read are added to files.
+func loadTree(path string, visiting, files map[string]bool) (map[string]any, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if visiting[abs] {
+		return nil, fmt.Errorf("include cycle at %s", path)
+	}
+	visiting[abs] = true
+	defer delete(visiting, abs)
+	files[abs] = true
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	tree, err := parse(path, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	includes, err := includeList(tree)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	merged := map[string]any{}
+	for _, include := range includes {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(path), include)
+		}
+
+		base, err := loadTree(include, visiting, files)
+		if err != nil {
+			return nil, err
+		}
+		merged = merge(merged, base).(map[string]any)
+	}
+
+	return merge(merged, tree).(map[string]any), nil
+}
+
+// parse decodes a config file, choosing the format by its extension.
+func parse(path string, data []byte) (map[string]any, error) {
+	tree := map[string]any{}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &tree); err != nil {
+			return nil, err
+		}
+
+	case ".toml":
+		if err := toml.Unmarshal(data, &tree); err != nil {
+			return nil, err
+		}
+
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&tree); err != nil {
+			return nil, err
+		}
+	}
+
+	return normalize(tree).(map[string]any), nil
+}
+
+// normalize turns the typed lists some decoders return, such as TOML arrays
+// of tables, into plain lists.
+func normalize(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, item := range value {
+			value[key] = normalize(item)
+		}
+		return value
+	case []map[string]any:
+		list := make([]any, len(value))
+		for i, item := range value {
+			list[i] = normalize(item)
+		}
+		return list
+	case []any:
+		for i, item := range value {
+			value[i] = normalize(item)
+		}
+		return value
+	default:
+		return value
+	}
+}
+
+// includeList removes the include key from the tree and returns its files.
+func includeList(tree map[string]any) ([]string, error) {
+	key, value, ok := lookup(tree, includeKey)
+	if !ok {
+		return nil, nil
+	}
+	delete(tree, key)
+
+	switch value := value.(type) {
+	case string:
+		return []string{value}, nil
+	case []any:
+		includes := make([]string, 0, len(value))
+		for _, item := range value {
+			include, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must list file names", includeKey)
+			}
+			includes = append(includes, include)
+		}
+		return includes, nil
+	default:
+		return nil, fmt.Errorf("%s must be a file name or a list of file names", includeKey)
+	}
+}
+
+// merge overrides base with override. Maps are merged key by key, ignoring
+// the case of keys like JSON decoding does. Lists of objects with a name,
+// such as providers, are merged by name; all other values are replaced.
+func merge(base, override any) any {
+	switch override := override.(type) {
+	case map[string]any:
+		baseMap, ok := base.(map[string]any)
+		if !ok {
+			return override
+		}
+
+		result := make(map[string]any, len(baseMap)+len(override))
+		for key, value := range baseMap {
+			result[key] = value
+		}
+		for key, value := range override {
+			if existing, old, found := lookup(result, key); found {
+				delete(result, existing)
+				value = merge(old, value)
+			}
+			result[key] = value
+		}
+		return result
+
+	case []any:
+		baseList, ok := base.([]any)
+		if !ok || !namedList(baseList) || !namedList(override) {
+			return override
+		}
+
+		result := append([]any{}, baseList...)
+		for _, item := range override {
+			name := itemName(item)
+			replaced := false
+			for i, existing := range result {
+				if itemName(existing) == name {
+					result[i] = merge(existing, item)
+					replaced = true
+					break
+				}
+			}
+			if !replaced {
+				result = append(result, item)
+			}
+		}
+		return result
+
+	default:
+		return override
+	}
+}
+
+func namedList(list []any) bool {
+	for _, item := range list {
+		if itemName(item) == "" {
+			return false
+		}
+	}
+	return len(list) > 0
+}
+
+func itemName(item any) string {
+	object, ok := item.(map[string]any)
+	if !ok {
+		return ""
+	}
+	_, value, _ := lookup(object, "name")
+	name, _ := value.(string)
+	return name
+}
+
+// lookup finds a key ignoring its case.
+func lookup(tree map[string]any, key string) (string, any, bool) {
+	if value, ok := tree[key]; ok {
+		return key, value, true
+	}
+	for existing, value := range tree {
+		if strings.EqualFold(existing, key) {
+			return existing, value, true
+		}
+	}
+	return "", nil, false
+}
+
+// expand replaces environment variable references in all string values but
+// secrets, found at path. Secrets keep their ${NAME} references, which are
+// resolved only when they are used, so that the stored configuration never
+// holds their values. Their variables must be defined all the same.
+func expand(value any, path string) (any, error) {
+	switch value := value.(type) {
+	case string:
+		if isSecretPath(path) {
+			_, err := secrets.ExpandVariables(value)
+			return value, err
+		}
+		return secrets.ExpandVariables(value)
+
+	case map[string]any:
+		for key, item := range value {
+			expanded, err := expand(item, path+"."+strings.ToLower(key))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			value[key] = expanded
+		}
+		return value, nil
+
+	case []any:
+		for i, item := range value {
+			expanded, err := expand(item, path+"[]")
+			if err != nil {
+				return nil, fmt.Errorf("%d: %w", i, err)
+			}
+			value[i] = expanded
+		}
+		return value, nil
+
+	default:
+		return value, nil
+	}
+}
+
+// isSecretPath reports whether a value is resolved by secrets.Resolver: a
+// provider setting or an API token.
+func isSecretPath(path string) bool {
+	return strings.HasPrefix(path, ".providers[].settings.") || path == ".api.tokens[].token"
+}
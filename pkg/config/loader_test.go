@@ -0,0 +1,106 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sevigo/shugosha/pkg/model"
+	"github.com/sevigo/shugosha/pkg/secrets"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadConfigFormats(t *testing.T) {
+	dir := t.TempDir()
+	expected := []model.ProviderConfig{{
+		Name:          "Local",
+		Type:          "Local",
+		Settings:      map[string]string{"path": "/backup"},
+		DirectoryList: []string{"/data"},
+	}}
+
+	files := map[string]string{
+		"config.json": `{"providers": [{"name": "Local", "type": "Local", "settings": {"path": "/backup"}, "directoryList": ["/data"]}]}`,
+		"config.yaml": `
+providers:
+  - name: Local
+    type: Local
+    settings:
+      path: /backup
+    directoryList: [/data]
+`,
+		"config.toml": `
+[[providers]]
+name = "Local"
+type = "Local"
+directoryList = ["/data"]
+settings = { path = "/backup" }
+`,
+	}
+
+	for name, content := range files {
+		config, err := LoadConfig(writeFile(t, dir, name, content))
+		assert.NoError(t, err, name)
+		assert.Equal(t, expected, config.Providers, name)
+	}
+}
+
+func TestLoadConfigIncludesAndEnv(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("BACKUP_TOKEN", "secret")
+
+	writeFile(t, dir, "base.yaml", `
+providers:
+  - name: Local
+    type: Local
+    settings:
+      path: /backup
+    directoryList: [/data]
+  - name: Cloud
+    type: Echo
+    settings:
+      token: ${BACKUP_TOKEN}
+      region: ${BACKUP_REGION:-eu}
+      literal: $${NOT_EXPANDED}
+`)
+	host := writeFile(t, dir, "host.toml", `
+include = "base.yaml"
+
+[[Providers]]
+name = "Local"
+directoryList = ["/home"]
+`)
+
+	config, err := LoadConfig(host)
+	assert.NoError(t, err)
+	assert.Len(t, config.Providers, 2)
+	assert.Equal(t, []string{"/home"}, config.Providers[0].DirectoryList)
+	assert.Equal(t, "/backup", config.Providers[0].Settings["path"])
+
+	// Settings keep their references, they are resolved by the provider
+	assert.Equal(t, map[string]string{"token": "${BACKUP_TOKEN}", "region": "${BACKUP_REGION:-eu}", "literal": "$${NOT_EXPANDED}"}, config.Providers[1].Settings)
+	settings, err := secrets.NewResolver(nil).ResolveSettings(config.Providers[1].Settings)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"token": "secret", "region": "eu", "literal": "${NOT_EXPANDED}"}, settings)
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := LoadConfig(writeFile(t, dir, "env.json", `{"providers": [{"name": "${UNDEFINED_SHUGOSHA_VAR}"}]}`))
+	assert.ErrorContains(t, err, "UNDEFINED_SHUGOSHA_VAR")
+	_, err = LoadConfig(writeFile(t, dir, "secret.json", `{"providers": [{"name": "Local", "settings": {"path": "${UNDEFINED_SHUGOSHA_VAR}"}}]}`))
+	assert.ErrorContains(t, err, "UNDEFINED_SHUGOSHA_VAR")
+
+	writeFile(t, dir, "a.yaml", "include: b.yaml\n")
+	_, err = LoadConfig(writeFile(t, dir, "b.yaml", "include: a.yaml\n"))
+	assert.ErrorContains(t, err, "include cycle")
+}
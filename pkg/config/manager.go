@@ -1,48 +1,187 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
+	"sync"
 
 	"github.com/sevigo/shugosha/pkg/model"
 )
 
 const key = "config:backupConfig"
 
+// Mode defines whether the config file or the API owns the configuration.
+type Mode string
+
+const (
+	// ModeAPI keeps the configuration in the database. Changes made through
+	// the API are written back to the config file.
+	ModeAPI Mode = "api"
+	// ModeFile loads the configuration from the config file and reloads it
+	// when the file or one it includes changes. Changes through the API are
+	// refused.
+	ModeFile Mode = "file"
+)
+
+// In both modes throttles, quotas, replication rules and the api section are
+// applied right away. Changes to providers and directories only take effect
+// after a restart; the revision of such a change lists them as RestartRequired.
+
+// Options configures a Manager.
+type Options struct {
+	Path      string                // Config file, the one found by Find if empty
+	Mode      Mode                  // Defaults to ModeAPI
+	Validator model.ConfigValidator // Checks configurations before they are saved
+}
+
 type Manager struct {
-	db model.DB
+	db        model.DB
+	validator model.ConfigValidator
+	mode      Mode
+	path      string // Config file in use, empty if there is none
+	writeBack bool   // Whether API changes are written to the config file
+	listeners []func(*model.BackupConfig)
+	mu        sync.Mutex
 }
 
-func NewConfigManager(storage model.DB) (model.ConfigManager, error) {
+// NewConfigManager creates a manager storing the configuration in the
+// database. In API mode a database without configuration is initialized from
+// the config file; in file mode the config file replaces the stored one.
+// Configurations are checked by the validator before they are saved.
+func NewConfigManager(storage model.DB, opts Options) (model.ConfigManager, error) {
 	manager := &Manager{
-		db: storage,
+		db:        storage,
+		validator: opts.Validator,
+		mode:      opts.Mode,
+	}
+	if manager.mode == "" {
+		manager.mode = ModeAPI
 	}
 
-	_, err := manager.LoadConfig()
-	if err != nil {
-		slog.Debug("No existing configuration found. Saving default configuration")
-		backupConfig := LoadDefaultConfig()
-		if err := manager.SaveConfig(backupConfig); err != nil {
-			return nil, fmt.Errorf("failed to save default config: %w", err)
+	path, err := Find(opts.Path)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	manager.path = path
+
+	switch manager.mode {
+	case ModeFile:
+		if path == "" {
+			return nil, fmt.Errorf("file managed configuration requires a config file, searched %v", SearchPaths())
+		}
+
+		backupConfig, err := LoadConfig(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config: %w", err)
+		}
+		slog.Info("Configuration is managed by the config file", "path", path)
+		if err := manager.save(backupConfig, "file", ""); err != nil {
+			return nil, fmt.Errorf("failed to save config: %w", err)
+		}
+
+	case ModeAPI:
+		manager.writeBack = path != "" && !isTemplate(path)
+		if path != "" && !manager.writeBack {
+			slog.Info("Config file uses includes or environment variables, API changes are not written back", "path", path)
+		}
+
+		_, err := manager.LoadConfig()
+		if err != nil {
+			slog.Debug("No existing configuration found. Saving default configuration")
+			backupConfig, err := LoadDefaultConfig(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load config: %w", err)
+			}
+			if err := manager.save(backupConfig, "file", ""); err != nil {
+				return nil, fmt.Errorf("failed to save default config: %w", err)
+			}
 		}
+
+	default:
+		return nil, fmt.Errorf("unknown config mode %q", manager.mode)
 	}
 
 	slog.Debug("Loaded existing configuration.")
 	return manager, nil
 }
 
+// SaveConfig validates and stores the configuration as a new revision.
+// Invalid configurations are rejected with model.ValidationErrors, and in file
+// mode all changes are refused with model.ErrConfigReadOnly.
 func (m *Manager) SaveConfig(config *model.BackupConfig) error {
+	if m.mode == ModeFile {
+		return model.ErrConfigReadOnly
+	}
+
+	if err := m.save(config, "api", ""); err != nil {
+		return err
+	}
+
+	slog.Debug("Configuration saved successfully.")
+	return nil
+}
+
+// save stores the configuration and a revision of it, unless it did not change.
+func (m *Manager) save(config *model.BackupConfig, source, comment string) error {
+	if m.validator != nil {
+		if err := m.validator.ValidateConfig(config); err != nil {
+			return err
+		}
+	}
+
 	data, err := json.Marshal(config)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	if err := m.db.Set(key, data); err != nil {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var restart []string
+	changed := false
+	err = m.db.Update(func(txn model.Txn) error {
+		current, err := txn.Get(key)
+		if err != nil && !errors.Is(err, model.ErrDBKeyNotFound) {
+			return err
+		}
+		if err == nil && bytes.Equal(current, data) {
+			return nil
+		}
+		changed = true
+		if current != nil {
+			restart = restartRequired(current, config)
+		}
+
+		if err := addRevision(txn, config, source, comment, restart); err != nil {
+			return err
+		}
+		return txn.Set(key, data)
+	})
+	if err != nil {
 		return fmt.Errorf("failed to set config in DB: %w", err)
 	}
 
-	slog.Debug("Configuration saved successfully.")
+	if !changed {
+		return nil
+	}
+
+	if len(restart) > 0 {
+		slog.Warn("Configuration changed, restart to apply provider and directory changes", "source", source, "paths", restart)
+	}
+
+	if m.writeBack && source != "file" {
+		if err := writeConfigFile(m.path, config); err != nil {
+			slog.Error("Failed to write configuration back to the config file", "path", m.path, "error", err)
+		}
+	}
+
+	for _, listener := range m.listeners {
+		listener(config)
+	}
 
 	return nil
 }
@@ -60,3 +199,34 @@ func (m *Manager) LoadConfig() (*model.BackupConfig, error) {
 
 	return &config, nil
 }
+
+// OnChange registers a function called with every newly saved configuration.
+func (m *Manager) OnChange(fn func(config *model.BackupConfig)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listeners = append(m.listeners, fn)
+}
+
+// restartRequired returns the paths of the changes from the previous
+// configuration that only take effect after a restart. Throttles, quotas,
+// replication rules and the api section are applied right away.
+func restartRequired(previous []byte, config *model.BackupConfig) []string {
+	var old model.BackupConfig
+	if err := json.Unmarshal(previous, &old); err != nil {
+		return nil
+	}
+
+	changes, err := Diff(&old, config)
+	if err != nil {
+		return nil
+	}
+
+	var paths []string
+	for _, change := range changes {
+		if strings.Contains(change.Path, ".throttle") || strings.Contains(change.Path, ".quota") || strings.HasPrefix(change.Path, "replication") || strings.HasPrefix(change.Path, "api") {
+			continue
+		}
+		paths = append(paths, change.Path)
+	}
+	return paths
+}
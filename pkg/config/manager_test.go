@@ -7,6 +7,7 @@ import (
 	"github.com/sevigo/shugosha/mocks"
 	"github.com/sevigo/shugosha/pkg/model"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestManager_SaveAndLoadConfig(t *testing.T) {
@@ -24,9 +25,17 @@ func TestManager_SaveAndLoadConfig(t *testing.T) {
 
 	mockDB := mocks.NewDB(t)
 	m := &Manager{
-		db: mockDB,
+		db:   mockDB,
+		mode: ModeAPI,
 	}
 
+	mockDB.On("Update", mock.Anything).Run(func(args mock.Arguments) {
+		assert.NoError(t, args.Get(0).(func(model.Txn) error)(mockDB))
+	}).Return(nil)
+	mockDB.On("Get", "config:backupConfig").Return(nil, model.ErrDBKeyNotFound).Once()
+	mockDB.On("Get", "config:lastRevision").Return(nil, model.ErrDBKeyNotFound)
+	mockDB.On("Set", revisionKey(1), mock.Anything).Return(nil)
+	mockDB.On("Set", "config:lastRevision", []byte("1")).Return(nil)
 	mockDB.On("Set", "config:backupConfig", marshaledConfig).Return(nil)
 	mockDB.On("Get", "config:backupConfig").Return(marshaledConfig, nil)
 
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"github.com/sevigo/shugosha/pkg/jsonschema"
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// ConfigSchema returns the JSON Schema of model.BackupConfig. The settings of
+// every registered provider type are described by a condition on its type.
+func (v *Validator) ConfigSchema() map[string]any {
+	schema := jsonschema.Of(model.BackupConfig{})
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "Shugosha backup configuration"
+
+	provider := schema["properties"].(map[string]any)["providers"].(map[string]any)["items"].(map[string]any)
+	provider["required"] = []string{"name", "type"}
+	provider["properties"].(map[string]any)["type"] = map[string]any{"type": "string", "enum": v.typeNames()}
+
+	var conditions []any
+	for _, name := range v.typeNames() {
+		settings := v.types[name]
+
+		properties := map[string]any{}
+		required := []string{}
+		for _, key := range sortedKeys(settings) {
+			property := map[string]any{"type": "string", "description": settings[key].Description}
+			if settings[key].Secret {
+				// Secrets are redacted when the configuration is read
+				property["writeOnly"] = true
+			}
+			properties[key] = property
+			if settings[key].Required {
+				required = append(required, key)
+			}
+		}
+
+		conditions = append(conditions, map[string]any{
+			"if": map[string]any{"properties": map[string]any{"type": map[string]any{"const": name}}},
+			"then": map[string]any{"properties": map[string]any{"settings": map[string]any{
+				"type":                 []any{"object", "null"},
+				"properties":           properties,
+				"required":             required,
+				"additionalProperties": false,
+			}}},
+		})
+	}
+	if len(conditions) > 0 {
+		provider["allOf"] = conditions
+	}
+
+	return schema
+}
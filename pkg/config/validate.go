@@ -0,0 +1,141 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/sevigo/shugosha/pkg/auth"
+	"github.com/sevigo/shugosha/pkg/compliance"
+	"github.com/sevigo/shugosha/pkg/model"
+	"github.com/sevigo/shugosha/pkg/quota"
+	"github.com/sevigo/shugosha/pkg/secrets"
+	"github.com/sevigo/shugosha/pkg/throttle"
+)
+
+// Validator checks configurations against the registered provider types.
+type Validator struct {
+	types map[string]model.SettingsSchema
+}
+
+// Ensure Validator satisfies the ConfigValidator interface
+var _ model.ConfigValidator = (*Validator)(nil)
+
+// NewValidator creates a validator accepting the given provider types and
+// their settings.
+func NewValidator(types map[string]model.SettingsSchema) *Validator {
+	return &Validator{types: types}
+}
+
+// ValidateConfig checks the whole configuration and returns all problems as
+// model.ValidationErrors, or nil if it is valid.
+func (v *Validator) ValidateConfig(config *model.BackupConfig) error {
+	var errs model.ValidationErrors
+	addError := func(field, format string, args ...any) {
+		errs = append(errs, model.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	names := map[string]int{}
+	for i, provider := range config.Providers {
+		field := fmt.Sprintf("providers[%d]", i)
+
+		if provider.Name == "" {
+			addError(field+".name", "is required")
+		} else if first, ok := names[provider.Name]; ok {
+			addError(field+".name", "duplicates the name of providers[%d]", first)
+		} else {
+			names[provider.Name] = i
+		}
+
+		schema, known := v.types[provider.Type]
+		switch {
+		case provider.Type == "":
+			addError(field+".type", "is required")
+		case !known:
+			addError(field+".type", "unknown provider type %q, expected one of %v", provider.Type, v.typeNames())
+		default:
+			for _, key := range sortedKeys(provider.Settings) {
+				if _, ok := schema[key]; !ok {
+					addError(field+".settings."+key, "unknown setting for provider type %q", provider.Type)
+				}
+			}
+			for _, key := range sortedKeys(schema) {
+				if schema[key].Required && provider.Settings[key] == "" {
+					addError(field+".settings."+key, "is required")
+				}
+			}
+			for _, key := range sortedKeys(provider.Settings) {
+				if value := provider.Settings[key]; secrets.IsReference(value) {
+					if err := secrets.ValidateReference(value); err != nil {
+						addError(field+".settings."+key, "%v", err)
+					}
+				}
+			}
+		}
+
+		for j, dir := range provider.DirectoryList {
+			dirField := fmt.Sprintf("%s.directoryList[%d]", field, j)
+			if !filepath.IsAbs(dir) {
+				addError(dirField, "must be an absolute path")
+				continue
+			}
+			if info, err := os.Stat(dir); err != nil {
+				addError(dirField, "directory does not exist")
+			} else if !info.IsDir() {
+				addError(dirField, "is not a directory")
+			}
+		}
+
+		if provider.Throttle != nil {
+			if err := throttle.Validate(*provider.Throttle); err != nil {
+				addError(field+".throttle", "%v", err)
+			}
+		}
+		if provider.Quota != nil {
+			if err := quota.Validate(*provider.Quota); err != nil {
+				addError(field+".quota", "%v", err)
+			}
+		}
+	}
+
+	for _, dir := range sortedKeys(config.Directories) {
+		switch config.Directories[dir].SymlinkPolicy {
+		case "", model.SymlinkStore, model.SymlinkFollow, model.SymlinkSkip:
+		default:
+			addError(fmt.Sprintf("directories[%q].symlinkPolicy", dir), "must be %q, %q or %q", model.SymlinkStore, model.SymlinkFollow, model.SymlinkSkip)
+		}
+	}
+
+	for i, rule := range config.Replication {
+		problems := compliance.ValidateRule(rule)
+		for _, key := range sortedKeys(problems) {
+			addError(fmt.Sprintf("replication[%d].%s", i, key), "%s", problems[key])
+		}
+	}
+
+	if config.API != nil {
+		problems := auth.Validate(config.API)
+		for _, key := range sortedKeys(problems) {
+			addError("api."+key, "%s", problems[key])
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (v *Validator) typeNames() []string {
+	return sortedKeys(v.types)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
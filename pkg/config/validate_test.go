@@ -0,0 +1,68 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+var testTypes = map[string]model.SettingsSchema{
+	"Echo":  {},
+	"Local": {"path": {Description: "Destination", Required: true}},
+}
+
+func TestValidateConfig(t *testing.T) {
+	dir := t.TempDir()
+	validator := NewValidator(testTypes)
+
+	valid := &model.BackupConfig{Providers: []model.ProviderConfig{
+		{Name: "Local", Type: "Local", Settings: map[string]string{"path": "/backup"}, DirectoryList: []string{dir}},
+	}}
+	assert.NoError(t, validator.ValidateConfig(valid))
+
+	invalid := &model.BackupConfig{
+		Providers: []model.ProviderConfig{
+			{Name: "Local", Type: "Local", Settings: map[string]string{"pth": "/backup"}, DirectoryList: []string{"relative", dir + "/missing"}},
+			{Name: "Local", Type: "S3"},
+			{Type: "Echo", Quota: &model.QuotaConfig{Action: "drop"}},
+			{Name: "Secret", Type: "Local", Settings: map[string]string{"path": "file:relative"}},
+		},
+		Directories: map[string]model.DirectoryOptions{dir: {SymlinkPolicy: "ignore"}},
+		Replication: []model.ReplicationRule{{Path: "/data"}},
+	}
+
+	err := validator.ValidateConfig(invalid)
+	var validationErrors model.ValidationErrors
+	assert.ErrorAs(t, err, &validationErrors)
+
+	fields := []string{}
+	for _, fieldError := range validationErrors {
+		fields = append(fields, fieldError.Field)
+	}
+	assert.Equal(t, []string{
+		"providers[0].settings.pth",
+		"providers[0].settings.path",
+		"providers[0].directoryList[0]",
+		"providers[0].directoryList[1]",
+		"providers[1].name",
+		"providers[1].type",
+		"providers[2].name",
+		"providers[2].quota",
+		"providers[3].settings.path",
+		`directories["` + dir + `"].symlinkPolicy`,
+		"replication[0].minCopies",
+	}, fields)
+}
+
+func TestConfigSchema(t *testing.T) {
+	schema := NewValidator(testTypes).ConfigSchema()
+
+	data, err := json.Marshal(schema)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"enum":["Echo","Local"]`)
+	assert.Contains(t, string(data), `"symlinkPolicy":{"enum":["store","follow","skip"],"type":"string"}`)
+	assert.Contains(t, string(data), `"required":["path"]`)
+}
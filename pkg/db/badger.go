@@ -12,6 +12,12 @@ type BadgerDB struct {
 	db *badger.DB
 }
 
+// Ensure BadgerDB satisfies the DB and SizedDB interfaces
+var (
+	_ model.DB      = (*BadgerDB)(nil)
+	_ model.SizedDB = (*BadgerDB)(nil)
+)
+
 func NewBadgerDB(dbPath string) (*BadgerDB, error) {
 	opts := badger.DefaultOptions(dbPath)
 	db, err := badger.Open(opts)
@@ -24,14 +30,8 @@ func NewBadgerDB(dbPath string) (*BadgerDB, error) {
 func (b *BadgerDB) Get(key string) ([]byte, error) {
 	var valCopy []byte
 	err := b.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get([]byte(key))
-		if err != nil {
-			if errors.Is(err, badger.ErrKeyNotFound) {
-				return model.ErrDBKeyNotFound
-			}
-			return err
-		}
-		valCopy, err = item.ValueCopy(nil)
+		var err error
+		valCopy, err = badgerTxn{txn}.Get(key)
 		return err
 	})
 
@@ -40,12 +40,115 @@ func (b *BadgerDB) Get(key string) ([]byte, error) {
 	}
 	return valCopy, err
 }
+
 func (b *BadgerDB) Set(key string, value []byte) error {
 	return b.db.Update(func(txn *badger.Txn) error {
 		return txn.Set([]byte(key), value)
 	})
 }
 
+func (b *BadgerDB) Delete(key string) error {
+	return b.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(key))
+	})
+}
+
+func (b *BadgerDB) Iterate(prefix string, fn func(key string, value []byte) error) error {
+	return b.IterateFrom(prefix, prefix, fn)
+}
+
+func (b *BadgerDB) IterateFrom(prefix, start string, fn func(key string, value []byte) error) error {
+	return b.db.View(func(txn *badger.Txn) error {
+		return badgerTxn{txn}.IterateFrom(prefix, start, fn)
+	})
+}
+
+func (b *BadgerDB) WriteBatch(batch *model.Batch) error {
+	wb := b.db.NewWriteBatch()
+	defer wb.Cancel()
+
+	for _, op := range batch.Ops {
+		var err error
+		if op.Value == nil {
+			err = wb.Delete([]byte(op.Key))
+		} else {
+			err = wb.Set([]byte(op.Key), op.Value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return wb.Flush()
+}
+
+func (b *BadgerDB) View(fn func(txn model.Txn) error) error {
+	return b.db.View(func(txn *badger.Txn) error {
+		return fn(badgerTxn{txn})
+	})
+}
+
+func (b *BadgerDB) Update(fn func(txn model.Txn) error) error {
+	return b.db.Update(func(txn *badger.Txn) error {
+		return fn(badgerTxn{txn})
+	})
+}
+
+// Size returns the size of the LSM tree and the value log.
+func (b *BadgerDB) Size() (int64, error) {
+	lsm, vlog := b.db.Size()
+	return lsm + vlog, nil
+}
+
 func (b *BadgerDB) Close() error {
 	return b.db.Close()
 }
+
+// badgerTxn adapts a Badger transaction to the model.Txn interface.
+type badgerTxn struct {
+	txn *badger.Txn
+}
+
+func (t badgerTxn) Get(key string) ([]byte, error) {
+	item, err := t.txn.Get([]byte(key))
+	if err != nil {
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil, model.ErrDBKeyNotFound
+		}
+		return nil, err
+	}
+	return item.ValueCopy(nil)
+}
+
+func (t badgerTxn) Set(key string, value []byte) error {
+	return t.txn.Set([]byte(key), value)
+}
+
+func (t badgerTxn) Delete(key string) error {
+	return t.txn.Delete([]byte(key))
+}
+
+func (t badgerTxn) Iterate(prefix string, fn func(key string, value []byte) error) error {
+	return t.IterateFrom(prefix, prefix, fn)
+}
+
+func (t badgerTxn) IterateFrom(prefix, start string, fn func(key string, value []byte) error) error {
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = []byte(prefix)
+
+	it := t.txn.NewIterator(opts)
+	defer it.Close()
+
+	for it.Seek([]byte(max(prefix, start))); it.Valid(); it.Next() {
+		item := it.Item()
+		value, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		if err := fn(string(item.KeyCopy(nil)), value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
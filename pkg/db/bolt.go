@@ -0,0 +1,206 @@
+package db
+
+import (
+	"bytes"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// bucket holds all keys, the catalog uses key prefixes instead of buckets.
+var bucket = []byte("shugosha")
+
+// iteratePage is the number of entries read before fn is called for them,
+// so fn may write without invalidating the cursor or holding a transaction.
+const iteratePage = 256
+
+// BoltDB is an implementation of the DB interface using bbolt.
+type BoltDB struct {
+	db *bolt.DB
+}
+
+// Ensure BoltDB satisfies the DB and SizedDB interfaces
+var (
+	_ model.DB      = (*BoltDB)(nil)
+	_ model.SizedDB = (*BoltDB)(nil)
+)
+
+func NewBoltDB(path string) (*BoltDB, error) {
+	db, err := bolt.Open(path, 0o600, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltDB{db: db}, nil
+}
+
+func (b *BoltDB) Get(key string) ([]byte, error) {
+	var value []byte
+	err := b.View(func(txn model.Txn) error {
+		var err error
+		value, err = txn.Get(key)
+		return err
+	})
+	return value, err
+}
+
+func (b *BoltDB) Set(key string, value []byte) error {
+	return b.Update(func(txn model.Txn) error {
+		return txn.Set(key, value)
+	})
+}
+
+func (b *BoltDB) Delete(key string) error {
+	return b.Update(func(txn model.Txn) error {
+		return txn.Delete(key)
+	})
+}
+
+func (b *BoltDB) Iterate(prefix string, fn func(key string, value []byte) error) error {
+	return b.IterateFrom(prefix, prefix, fn)
+}
+
+// IterateFrom reads the entries a page at a time in separate read
+// transactions, so fn may write to the database while iterating.
+func (b *BoltDB) IterateFrom(prefix, start string, fn func(key string, value []byte) error) error {
+	var after []byte
+	for {
+		var keys, values [][]byte
+		err := b.db.View(func(tx *bolt.Tx) error {
+			keys, values = readPage(tx.Bucket(bucket), []byte(prefix), []byte(start), after)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for i, key := range keys {
+			if err := fn(string(key), values[i]); err != nil {
+				return err
+			}
+		}
+		if len(keys) < iteratePage {
+			return nil
+		}
+		after = keys[len(keys)-1]
+	}
+}
+
+// WriteBatch applies the batch in a single transaction.
+func (b *BoltDB) WriteBatch(batch *model.Batch) error {
+	return b.Update(func(txn model.Txn) error {
+		for _, op := range batch.Ops {
+			var err error
+			if op.Value == nil {
+				err = txn.Delete(op.Key)
+			} else {
+				err = txn.Set(op.Key, op.Value)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *BoltDB) View(fn func(txn model.Txn) error) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		return fn(boltTxn{tx.Bucket(bucket)})
+	})
+}
+
+func (b *BoltDB) Update(fn func(txn model.Txn) error) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return fn(boltTxn{tx.Bucket(bucket)})
+	})
+}
+
+// Size returns the size of the database file.
+func (b *BoltDB) Size() (int64, error) {
+	var size int64
+	err := b.db.View(func(tx *bolt.Tx) error {
+		size = tx.Size()
+		return nil
+	})
+	return size, err
+}
+
+func (b *BoltDB) Close() error {
+	return b.db.Close()
+}
+
+// boltTxn adapts the bucket of a bbolt transaction to the model.Txn interface.
+type boltTxn struct {
+	bucket *bolt.Bucket
+}
+
+func (t boltTxn) Get(key string) ([]byte, error) {
+	value := t.bucket.Get([]byte(key))
+	if value == nil {
+		return nil, model.ErrDBKeyNotFound
+	}
+	// Values are only valid for the lifetime of the transaction
+	return bytes.Clone(value), nil
+}
+
+func (t boltTxn) Set(key string, value []byte) error {
+	return t.bucket.Put([]byte(key), value)
+}
+
+func (t boltTxn) Delete(key string) error {
+	return t.bucket.Delete([]byte(key))
+}
+
+func (t boltTxn) Iterate(prefix string, fn func(key string, value []byte) error) error {
+	return t.IterateFrom(prefix, prefix, fn)
+}
+
+func (t boltTxn) IterateFrom(prefix, start string, fn func(key string, value []byte) error) error {
+	var after []byte
+	for {
+		keys, values := readPage(t.bucket, []byte(prefix), []byte(start), after)
+		for i, key := range keys {
+			if err := fn(string(key), values[i]); err != nil {
+				return err
+			}
+		}
+		if len(keys) < iteratePage {
+			return nil
+		}
+		after = keys[len(keys)-1]
+	}
+}
+
+// readPage returns up to iteratePage entries with the prefix that follow the
+// key after, or begin at start if after is nil.
+func readPage(bucket *bolt.Bucket, prefix, start, after []byte) (keys, values [][]byte) {
+	c := bucket.Cursor()
+
+	if bytes.Compare(start, prefix) < 0 {
+		start = prefix
+	}
+
+	k, v := c.Seek(start)
+	if after != nil {
+		if k, v = c.Seek(after); bytes.Equal(k, after) {
+			k, v = c.Next()
+		}
+	}
+
+	for ; k != nil && bytes.HasPrefix(k, prefix) && len(keys) < iteratePage; k, v = c.Next() {
+		keys = append(keys, bytes.Clone(k))
+		values = append(values, bytes.Clone(v))
+	}
+	return keys, values
+}
@@ -0,0 +1,47 @@
+// Package db provides the catalog storage backends implementing model.DB.
+package db
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// Supported catalog backends.
+const (
+	BackendBadger = "badger"
+	BackendBolt   = "bolt"
+	BackendSQLite = "sqlite"
+	BackendMemory = "memory"
+)
+
+var errReadOnly = errors.New("write in a read-only transaction")
+
+// Open opens the catalog of the given backend in the directory dir.
+func Open(backend, dir string) (model.DB, error) {
+	if backend != BackendMemory {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, err
+		}
+	}
+
+	switch backend {
+	case BackendBadger, "":
+		return NewBadgerDB(dir)
+
+	case BackendBolt:
+		return NewBoltDB(filepath.Join(dir, "catalog.bolt"))
+
+	case BackendSQLite:
+		return NewSQLiteDB(filepath.Join(dir, "catalog.sqlite"))
+
+	case BackendMemory:
+		return NewMemoryDB(), nil
+
+	default:
+		return nil, fmt.Errorf("unknown database backend %q", backend)
+	}
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sevigo/shugosha/pkg/db/dbtest"
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+func TestBadgerDB(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T) model.DB {
+		storage, err := NewBadgerDB(t.TempDir())
+		assert.NoError(t, err)
+		t.Cleanup(func() { storage.Close() })
+		return storage
+	})
+}
+
+func TestBoltDB(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T) model.DB {
+		storage, err := NewBoltDB(filepath.Join(t.TempDir(), "catalog.bolt"))
+		assert.NoError(t, err)
+		t.Cleanup(func() { storage.Close() })
+		return storage
+	})
+}
+
+func TestSQLiteDB(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T) model.DB {
+		storage, err := NewSQLiteDB(filepath.Join(t.TempDir(), "catalog.sqlite"))
+		assert.NoError(t, err)
+		t.Cleanup(func() { storage.Close() })
+		return storage
+	})
+}
+
+func TestMemoryDB(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T) model.DB {
+		return NewMemoryDB()
+	})
+}
+
+func TestSQLiteDB_FileRecordsView(t *testing.T) {
+	storage, err := NewSQLiteDB(filepath.Join(t.TempDir(), "catalog.sqlite"))
+	assert.NoError(t, err)
+	defer storage.Close()
+
+	assert.NoError(t, storage.Set("Echo:/data/a.txt", []byte(`{"provider":"Echo","root":"/data","path":"/data/a.txt","size":42}`)))
+	assert.NoError(t, storage.Set("meta:Echo", []byte(`{"name":"Echo"}`)))
+
+	rows, err := storage.Query(`SELECT provider, path, size FROM file_records`)
+	assert.NoError(t, err)
+	defer rows.Close()
+
+	var provider, path string
+	var size int64
+	assert.True(t, rows.Next())
+	assert.NoError(t, rows.Scan(&provider, &path, &size))
+	assert.Equal(t, "Echo", provider)
+	assert.Equal(t, "/data/a.txt", path)
+	assert.Equal(t, int64(42), size)
+	assert.False(t, rows.Next())
+}
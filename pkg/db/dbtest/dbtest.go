@@ -0,0 +1,276 @@
+// Package dbtest provides a conformance test suite for model.DB implementations.
+package dbtest
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// Run tests the model.DB contract against databases created by newDB. Every
+// subtest gets a fresh, empty database.
+func Run(t *testing.T, newDB func(t *testing.T) model.DB) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, storage model.DB)
+	}{
+		{"GetSet", testGetSet},
+		{"Delete", testDelete},
+		{"Iterate", testIterate},
+		{"IterateStops", testIterateStops},
+		{"IterateFrom", testIterateFrom},
+		{"IterateMany", testIterateMany},
+		{"IterateWrites", testIterateWrites},
+		{"UpdateIterateWrites", testUpdateIterateWrites},
+		{"WriteBatch", testWriteBatch},
+		{"UpdateCommits", testUpdateCommits},
+		{"UpdateRollsBack", testUpdateRollsBack},
+		{"View", testView},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newDB(t))
+		})
+	}
+}
+
+func testGetSet(t *testing.T, storage model.DB) {
+	_, err := storage.Get("missing")
+	assert.ErrorIs(t, err, model.ErrDBKeyNotFound)
+
+	assert.NoError(t, storage.Set("key", []byte("value")))
+	assert.NoError(t, storage.Set("key", []byte("updated")))
+
+	value, err := storage.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("updated"), value)
+}
+
+func testDelete(t *testing.T, storage model.DB) {
+	assert.NoError(t, storage.Set("key", []byte("value")))
+	assert.NoError(t, storage.Delete("key"))
+	assert.NoError(t, storage.Delete("missing"))
+
+	_, err := storage.Get("key")
+	assert.ErrorIs(t, err, model.ErrDBKeyNotFound)
+}
+
+func testIterate(t *testing.T, storage model.DB) {
+	assert.NoError(t, storage.Set("a:2", []byte("two")))
+	assert.NoError(t, storage.Set("a:1", []byte("one")))
+	assert.NoError(t, storage.Set("b:1", []byte("other")))
+	assert.NoError(t, storage.Set("a", []byte("no separator")))
+
+	var keys []string
+	var values []string
+	err := storage.Iterate("a:", func(key string, value []byte) error {
+		keys = append(keys, key)
+		values = append(values, string(value))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a:1", "a:2"}, keys)
+	assert.Equal(t, []string{"one", "two"}, values)
+
+	count := 0
+	assert.NoError(t, storage.Iterate("", func(string, []byte) error {
+		count++
+		return nil
+	}))
+	assert.Equal(t, 4, count)
+}
+
+func testIterateStops(t *testing.T, storage model.DB) {
+	assert.NoError(t, storage.Set("k:1", []byte("1")))
+	assert.NoError(t, storage.Set("k:2", []byte("2")))
+
+	errStop := errors.New("stop")
+	calls := 0
+	err := storage.Iterate("k:", func(string, []byte) error {
+		calls++
+		return errStop
+	})
+	assert.ErrorIs(t, err, errStop)
+	assert.Equal(t, 1, calls)
+}
+
+func testIterateFrom(t *testing.T, storage model.DB) {
+	for _, key := range []string{"a:1", "a:2", "a:3", "b:1"} {
+		assert.NoError(t, storage.Set(key, []byte(key)))
+	}
+
+	collect := func(iterate func(prefix, start string, fn func(string, []byte) error) error, start string) []string {
+		var keys []string
+		assert.NoError(t, iterate("a:", start, func(key string, _ []byte) error {
+			keys = append(keys, key)
+			return nil
+		}))
+		return keys
+	}
+
+	assert.Equal(t, []string{"a:2", "a:3"}, collect(storage.IterateFrom, "a:2"))
+	assert.Equal(t, []string{"a:3"}, collect(storage.IterateFrom, "a:2\x00"))
+	assert.Equal(t, []string{"a:1", "a:2", "a:3"}, collect(storage.IterateFrom, ""))
+	assert.Empty(t, collect(storage.IterateFrom, "a:4"))
+
+	assert.NoError(t, storage.View(func(txn model.Txn) error {
+		assert.Equal(t, []string{"a:2", "a:3"}, collect(txn.IterateFrom, "a:2"))
+		return nil
+	}))
+}
+
+func testIterateMany(t *testing.T, storage model.DB) {
+	batch := &model.Batch{}
+	for i := 0; i < 1000; i++ {
+		batch.Set(fmt.Sprintf("k:%04d", i), []byte("value"))
+	}
+	assert.NoError(t, storage.WriteBatch(batch))
+
+	var keys []string
+	assert.NoError(t, storage.Iterate("k:", func(key string, _ []byte) error {
+		keys = append(keys, key)
+		return nil
+	}))
+	assert.Len(t, keys, 1000)
+	assert.True(t, sort.StringsAreSorted(keys), "keys are not in order")
+}
+
+func testIterateWrites(t *testing.T, storage model.DB) {
+	for _, key := range []string{"k:1", "k:2", "k:3"} {
+		assert.NoError(t, storage.Set(key, []byte("old")))
+	}
+
+	// Writes through the database while iterating must not block
+	visited := 0
+	err := storage.Iterate("k:", func(key string, _ []byte) error {
+		visited++
+		if err := storage.Set(key, []byte("new")); err != nil {
+			return err
+		}
+		return storage.Set("copy:"+key, []byte("new"))
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, visited)
+
+	count := 0
+	assert.NoError(t, storage.Iterate("", func(_ string, value []byte) error {
+		assert.Equal(t, []byte("new"), value)
+		count++
+		return nil
+	}))
+	assert.Equal(t, 6, count)
+}
+
+func testUpdateIterateWrites(t *testing.T, storage model.DB) {
+	for _, key := range []string{"k:1", "k:2", "k:3", "k:4"} {
+		assert.NoError(t, storage.Set(key, []byte("old")))
+	}
+
+	visited := 0
+	err := storage.Update(func(txn model.Txn) error {
+		return txn.Iterate("k:", func(key string, _ []byte) error {
+			visited++
+			if key == "k:2" {
+				return txn.Delete(key)
+			}
+			return txn.Set(key, []byte("new"))
+		})
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, visited)
+
+	var keys []string
+	assert.NoError(t, storage.Iterate("k:", func(key string, value []byte) error {
+		assert.Equal(t, []byte("new"), value)
+		keys = append(keys, key)
+		return nil
+	}))
+	assert.Equal(t, []string{"k:1", "k:3", "k:4"}, keys)
+}
+
+func testWriteBatch(t *testing.T, storage model.DB) {
+	assert.NoError(t, storage.Set("stale", []byte("x")))
+
+	batch := &model.Batch{}
+	batch.Set("k1", []byte("v1"))
+	batch.Set("k2", []byte("v2"))
+	batch.Delete("stale")
+	assert.NoError(t, storage.WriteBatch(batch))
+
+	value, err := storage.Get("k2")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("v2"), value)
+
+	_, err = storage.Get("stale")
+	assert.ErrorIs(t, err, model.ErrDBKeyNotFound)
+}
+
+func testUpdateCommits(t *testing.T, storage model.DB) {
+	assert.NoError(t, storage.Set("counter", []byte("1")))
+
+	err := storage.Update(func(txn model.Txn) error {
+		value, err := txn.Get("counter")
+		if err != nil {
+			return err
+		}
+		if err := txn.Set("counter", append(value, '1')); err != nil {
+			return err
+		}
+
+		// Reads inside the transaction see its own writes
+		value, err = txn.Get("counter")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("11"), value)
+		return txn.Delete("missing")
+	})
+	assert.NoError(t, err)
+
+	value, err := storage.Get("counter")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("11"), value)
+}
+
+func testUpdateRollsBack(t *testing.T, storage model.DB) {
+	assert.NoError(t, storage.Set("existing", []byte("value")))
+	errAbort := errors.New("abort")
+
+	err := storage.Update(func(txn model.Txn) error {
+		assert.NoError(t, txn.Set("record", []byte("value")))
+		assert.NoError(t, txn.Delete("existing"))
+		return errAbort
+	})
+	assert.ErrorIs(t, err, errAbort)
+
+	_, err = storage.Get("record")
+	assert.ErrorIs(t, err, model.ErrDBKeyNotFound)
+
+	_, err = storage.Get("existing")
+	assert.NoError(t, err)
+}
+
+func testView(t *testing.T, storage model.DB) {
+	assert.NoError(t, storage.Set("p:1", []byte("one")))
+
+	err := storage.View(func(txn model.Txn) error {
+		value, err := txn.Get("p:1")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("one"), value)
+
+		count := 0
+		assert.NoError(t, txn.Iterate("p:", func(string, []byte) error {
+			count++
+			return nil
+		}))
+		assert.Equal(t, 1, count)
+		return nil
+	})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,235 @@
+package db
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// MemoryDB is an in-memory implementation of the DB interface, mainly for
+// tests. Its content is lost on Close.
+type MemoryDB struct {
+	mu   sync.RWMutex
+	data map[string][]byte
+	// writeLock serializes read-write transactions
+	writeLock sync.Mutex
+}
+
+// Ensure MemoryDB satisfies the DB and SizedDB interfaces
+var (
+	_ model.DB      = (*MemoryDB)(nil)
+	_ model.SizedDB = (*MemoryDB)(nil)
+)
+
+func NewMemoryDB() *MemoryDB {
+	return &MemoryDB{data: map[string][]byte{}}
+}
+
+func (m *MemoryDB) Get(key string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	value, ok := m.data[key]
+	if !ok {
+		return nil, model.ErrDBKeyNotFound
+	}
+	return bytes.Clone(value), nil
+}
+
+func (m *MemoryDB) Set(key string, value []byte) error {
+	return m.Update(func(txn model.Txn) error {
+		return txn.Set(key, value)
+	})
+}
+
+func (m *MemoryDB) Delete(key string) error {
+	return m.Update(func(txn model.Txn) error {
+		return txn.Delete(key)
+	})
+}
+
+// Iterate works on a snapshot of the matching keys, fn may modify the database.
+func (m *MemoryDB) Iterate(prefix string, fn func(key string, value []byte) error) error {
+	return m.IterateFrom(prefix, prefix, fn)
+}
+
+func (m *MemoryDB) IterateFrom(prefix, start string, fn func(key string, value []byte) error) error {
+	m.mu.RLock()
+	keys := (&memoryTxn{db: m}).keys(prefix, start)
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		values[i] = bytes.Clone(m.data[key])
+	}
+	m.mu.RUnlock()
+
+	for i, key := range keys {
+		if err := fn(key, values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemoryDB) WriteBatch(batch *model.Batch) error {
+	return m.Update(func(txn model.Txn) error {
+		for _, op := range batch.Ops {
+			if op.Value == nil {
+				_ = txn.Delete(op.Key)
+				continue
+			}
+			_ = txn.Set(op.Key, op.Value)
+		}
+		return nil
+	})
+}
+
+// View runs fn on a consistent snapshot of the data.
+func (m *MemoryDB) View(fn func(txn model.Txn) error) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return fn(&memoryTxn{db: m, readOnly: true})
+}
+
+// Update buffers the writes of fn and applies them only if fn succeeds.
+func (m *MemoryDB) Update(fn func(txn model.Txn) error) error {
+	m.writeLock.Lock()
+	defer m.writeLock.Unlock()
+
+	txn := &memoryTxn{db: m, writes: map[string][]byte{}}
+	if err := fn(txn); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, value := range txn.writes {
+		if value == nil {
+			delete(m.data, key)
+		} else {
+			m.data[key] = value
+		}
+	}
+	return nil
+}
+
+// Size returns the bytes of all keys and values.
+func (m *MemoryDB) Size() (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var size int64
+	for key, value := range m.data {
+		size += int64(len(key) + len(value))
+	}
+	return size, nil
+}
+
+func (m *MemoryDB) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.data = map[string][]byte{}
+	return nil
+}
+
+// memoryTxn reads through its pending writes, a nil value marks a deletion.
+// Read-only transactions are run with the read lock of the database held;
+// read-write transactions lock the database for every read.
+type memoryTxn struct {
+	db       *MemoryDB
+	writes   map[string][]byte
+	readOnly bool
+}
+
+func (t *memoryTxn) read(key string) ([]byte, bool) {
+	if value, ok := t.writes[key]; ok {
+		return value, value != nil
+	}
+
+	if !t.readOnly {
+		t.db.mu.RLock()
+		defer t.db.mu.RUnlock()
+	}
+	value, ok := t.db.data[key]
+	return value, ok
+}
+
+func (t *memoryTxn) Get(key string) ([]byte, error) {
+	value, ok := t.read(key)
+	if !ok {
+		return nil, model.ErrDBKeyNotFound
+	}
+	return bytes.Clone(value), nil
+}
+
+func (t *memoryTxn) Set(key string, value []byte) error {
+	if t.readOnly {
+		return errReadOnly
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	t.writes[key] = bytes.Clone(value)
+	return nil
+}
+
+func (t *memoryTxn) Delete(key string) error {
+	if t.readOnly {
+		return errReadOnly
+	}
+	t.writes[key] = nil
+	return nil
+}
+
+func (t *memoryTxn) Iterate(prefix string, fn func(key string, value []byte) error) error {
+	return t.IterateFrom(prefix, prefix, fn)
+}
+
+func (t *memoryTxn) IterateFrom(prefix, start string, fn func(key string, value []byte) error) error {
+	if !t.readOnly {
+		t.db.mu.RLock()
+	}
+	keys := t.keys(prefix, start)
+	if !t.readOnly {
+		t.db.mu.RUnlock()
+	}
+
+	for _, key := range keys {
+		value, ok := t.read(key)
+		if !ok {
+			continue
+		}
+		if err := fn(key, bytes.Clone(value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keys returns the sorted keys with the prefix from start on, the caller
+// holds the read lock.
+func (t *memoryTxn) keys(prefix, start string) []string {
+	seen := map[string]bool{}
+	for key := range t.db.data {
+		if strings.HasPrefix(key, prefix) && key >= start {
+			seen[key] = true
+		}
+	}
+	for key := range t.writes {
+		if strings.HasPrefix(key, prefix) && key >= start {
+			seen[key] = true
+		}
+	}
+
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
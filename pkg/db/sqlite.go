@@ -0,0 +1,230 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+
+	// Pure Go SQLite driver, registers itself as "sqlite"
+	_ "modernc.org/sqlite"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// sqliteSchema stores all keys in one table. The file_records view exposes
+// backed up file records for ad-hoc SQL queries.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS kv (
+	key   TEXT PRIMARY KEY,
+	value BLOB NOT NULL
+) WITHOUT ROWID;
+
+DROP VIEW IF EXISTS file_records;
+CREATE VIEW file_records AS
+SELECT json_extract(value, '$.provider')  AS provider,
+       json_extract(value, '$.path')      AS path,
+       json_extract(value, '$.root')      AS root,
+       json_extract(value, '$.checksum')  AS checksum,
+       json_extract(value, '$.size')      AS size,
+       json_extract(value, '$.timestamp') AS timestamp,
+       json_extract(value, '$.kind')      AS kind
+FROM kv
+WHERE key NOT LIKE 'meta:%' AND key NOT LIKE 'config:%' AND key NOT LIKE 'upload:%'
+  AND json_valid(value) AND json_extract(value, '$.path') IS NOT NULL;
+`
+
+// SQLiteDB is an implementation of the DB interface using SQLite.
+type SQLiteDB struct {
+	db *sql.DB
+}
+
+// Ensure SQLiteDB satisfies the DB and SizedDB interfaces
+var (
+	_ model.DB      = (*SQLiteDB)(nil)
+	_ model.SizedDB = (*SQLiteDB)(nil)
+)
+
+func NewSQLiteDB(path string) (*SQLiteDB, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+
+	// A single connection serializes writers and avoids SQLITE_BUSY errors.
+	// It is also why View and Update callbacks must not use the DB itself.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteDB{db: db}, nil
+}
+
+// Query runs an ad-hoc SQL query, e.g. against the file_records view.
+func (s *SQLiteDB) Query(query string, args ...any) (*sql.Rows, error) {
+	return s.db.Query(query, args...)
+}
+
+func (s *SQLiteDB) Get(key string) ([]byte, error) {
+	return sqliteTxn{s.db}.Get(key)
+}
+
+func (s *SQLiteDB) Set(key string, value []byte) error {
+	return sqliteTxn{s.db}.Set(key, value)
+}
+
+func (s *SQLiteDB) Delete(key string) error {
+	return sqliteTxn{s.db}.Delete(key)
+}
+
+func (s *SQLiteDB) Iterate(prefix string, fn func(key string, value []byte) error) error {
+	return sqliteTxn{s.db}.Iterate(prefix, fn)
+}
+
+func (s *SQLiteDB) IterateFrom(prefix, start string, fn func(key string, value []byte) error) error {
+	return sqliteTxn{s.db}.IterateFrom(prefix, start, fn)
+}
+
+// WriteBatch applies the batch in a single transaction.
+func (s *SQLiteDB) WriteBatch(batch *model.Batch) error {
+	return s.Update(func(txn model.Txn) error {
+		for _, op := range batch.Ops {
+			var err error
+			if op.Value == nil {
+				err = txn.Delete(op.Key)
+			} else {
+				err = txn.Set(op.Key, op.Value)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLiteDB) View(fn func(txn model.Txn) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	return fn(sqliteTxn{tx})
+}
+
+func (s *SQLiteDB) Update(fn func(txn model.Txn) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(sqliteTxn{tx}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Size returns the size of the database pages, without the write-ahead log.
+func (s *SQLiteDB) Size() (int64, error) {
+	var size int64
+	err := s.db.QueryRow("SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()").Scan(&size)
+	return size, err
+}
+
+func (s *SQLiteDB) Close() error {
+	return s.db.Close()
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx.
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// sqliteTxn adapts a SQLite connection or transaction to the model.Txn interface.
+type sqliteTxn struct {
+	q querier
+}
+
+func (t sqliteTxn) Get(key string) ([]byte, error) {
+	var value []byte
+	err := t.q.QueryRow(`SELECT value FROM kv WHERE key = ?`, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, model.ErrDBKeyNotFound
+	}
+	return value, err
+}
+
+func (t sqliteTxn) Set(key string, value []byte) error {
+	if value == nil {
+		value = []byte{}
+	}
+	_, err := t.q.Exec(`INSERT INTO kv (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value`, key, value)
+	return err
+}
+
+func (t sqliteTxn) Delete(key string) error {
+	_, err := t.q.Exec(`DELETE FROM kv WHERE key = ?`, key)
+	return err
+}
+
+func (t sqliteTxn) Iterate(prefix string, fn func(key string, value []byte) error) error {
+	return t.IterateFrom(prefix, prefix, fn)
+}
+
+// IterateFrom reads all matching rows before calling fn, so fn may use the
+// database while iterating.
+func (t sqliteTxn) IterateFrom(prefix, start string, fn func(key string, value []byte) error) error {
+	start = max(prefix, start)
+	query, args := `SELECT key, value FROM kv WHERE key >= ? ORDER BY key`, []any{start}
+	if end, ok := prefixEnd(prefix); ok {
+		query, args = `SELECT key, value FROM kv WHERE key >= ? AND key < ? ORDER BY key`, []any{start, end}
+	}
+
+	rows, err := t.q.Query(query, args...)
+	if err != nil {
+		return err
+	}
+
+	var keys []string
+	var values [][]byte
+	for rows.Next() {
+		var key string
+		var value []byte
+		if err := rows.Scan(&key, &value); err != nil {
+			rows.Close()
+			return err
+		}
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i, key := range keys {
+		if err := fn(key, values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prefixEnd returns the smallest key greater than all keys with the prefix.
+func prefixEnd(prefix string) (string, bool) {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1]), true
+		}
+	}
+	return "", false
+}
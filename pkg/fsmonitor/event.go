@@ -31,7 +31,7 @@ func determineFinalEvent(events []fsnotify.Event) *model.Event {
 		}
 	}
 
-	// Determine final event type and calculate checksum and size if needed
+	// Determine final event type, checksum and size are filled in on flush
 	var finalType string
 	if created && !removed && !renamed {
 		finalType = "added"
@@ -41,12 +41,9 @@ func determineFinalEvent(events []fsnotify.Event) *model.Event {
 		return nil
 	}
 
-	sum, size, _ := getFileChecksumAndSize(lastEvent.Name)
 	return &model.Event{
 		Path:      lastEvent.Name,
 		Type:      finalType,
 		Timestamp: time.Now(),
-		Checksum:  sum,
-		Size:      size,
 	}
 }
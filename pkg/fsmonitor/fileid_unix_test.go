@@ -0,0 +1,36 @@
+//go:build !windows
+
+package fsmonitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHardlinkOriginEviction(t *testing.T) {
+	dir := t.TempDir()
+	origin, link := filepath.Join(dir, "origin"), filepath.Join(dir, "link")
+	assert.NoError(t, os.WriteFile(origin, []byte("data"), 0o600))
+	assert.NoError(t, os.Link(origin, link))
+
+	monitor, err := New(DefaultConfig())
+	assert.NoError(t, err)
+
+	info, err := os.Stat(origin)
+	assert.NoError(t, err)
+	id, ok := fileIdentity(info)
+	assert.True(t, ok)
+
+	assert.Equal(t, "", monitor.hardlinkOrigin(id, origin))
+	assert.Equal(t, origin, monitor.hardlinkOrigin(id, link))
+
+	// A removed origin is forgotten, the next link takes its place
+	monitor.forgetLink(origin)
+	assert.Empty(t, monitor.links)
+	assert.Empty(t, monitor.linkPaths)
+	assert.Equal(t, "", monitor.hardlinkOrigin(id, link))
+	assert.Equal(t, map[fileID]string{id: link}, monitor.links)
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package fsmonitor
+
+import "os"
+
+// fileIdentity is not supported on Windows, where os.FileInfo carries no
+// file index; hard links are backed up as separate files.
+func fileIdentity(_ os.FileInfo) (fileID, bool) {
+	return fileID{}, false
+}
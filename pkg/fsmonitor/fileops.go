@@ -2,28 +2,101 @@ package fsmonitor
 
 import (
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"time"
+
+	"github.com/sevigo/shugosha/pkg/metrics"
+	"github.com/sevigo/shugosha/pkg/model"
 )
 
+// readThrottle wraps a reader of a file to limit how fast it is read.
+type readThrottle func(r io.Reader) io.Reader
+
+// errSkipped is returned for entries that must not be backed up, such as
+// directories, device files, FIFOs, sockets or links excluded by policy.
+var errSkipped = errors.New("entry skipped")
+
 // getFileChecksumAndSize calculates the SHA256 checksum and size of the file.
-func getFileChecksumAndSize(path string) (string, int64, error) {
+// The file is read through throttle, if set.
+func getFileChecksumAndSize(path string, throttle readThrottle) (string, int64, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return "", 0, err
 	}
 	defer file.Close()
 
-	hash := sha256.New()
-	if _, err := io.Copy(hash, file); err != nil {
+	stat, err := file.Stat()
+	if err != nil {
 		return "", 0, err
 	}
 
-	stat, err := file.Stat()
+	// The entry may have been replaced since it was inspected
+	if !stat.Mode().IsRegular() {
+		return "", 0, errSkipped
+	}
+
+	start := time.Now()
+	hash := sha256.New()
+	var r io.Reader = file
+	if throttle != nil {
+		r = throttle(r)
+	}
+	n, err := io.Copy(hash, r)
+	metrics.HashedBytes.Add(float64(n))
 	if err != nil {
 		return "", 0, err
 	}
+	metrics.HashDuration.Observe(time.Since(start).Seconds())
 
 	return fmt.Sprintf("%x", hash.Sum(nil)), stat.Size(), nil
 }
+
+// describeEntry fills in kind, checksum and size of the event according to
+// the symlink policy. It returns the file info of the backed up entry, which
+// is the link target for followed symlinks.
+func describeEntry(event *model.Event, policy model.SymlinkPolicy, throttle readThrottle) (os.FileInfo, error) {
+	info, err := os.Lstat(event.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		switch policy {
+		case model.SymlinkSkip:
+			return nil, errSkipped
+
+		case model.SymlinkFollow:
+			if info, err = os.Stat(event.Path); err != nil {
+				return nil, err
+			}
+
+		default:
+			target, err := os.Readlink(event.Path)
+			if err != nil {
+				return nil, err
+			}
+			event.Kind = model.KindSymlink
+			event.LinkTarget = target
+			event.Checksum = fmt.Sprintf("%x", sha256.Sum256([]byte(target)))
+			event.Size = 0
+			return info, nil
+		}
+	}
+
+	if !info.Mode().IsRegular() {
+		return nil, errSkipped
+	}
+
+	sum, size, err := getFileChecksumAndSize(event.Path, throttle)
+	if err != nil {
+		return nil, err
+	}
+
+	event.Kind = model.KindFile
+	event.Checksum = sum
+	event.Size = size
+	return info, nil
+}
@@ -4,9 +4,12 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/sevigo/shugosha/pkg/model"
 )
 
 func TestGetFileChecksumAndSize(t *testing.T) {
@@ -26,8 +29,40 @@ func TestGetFileChecksumAndSize(t *testing.T) {
 	expectedSize := int64(len(content))
 
 	// Test getFileChecksumAndSize
-	checksum, size, err := getFileChecksumAndSize(tempFile.Name())
+	checksum, size, err := getFileChecksumAndSize(tempFile.Name(), nil)
 	assert.NoError(t, err)
 	assert.Equal(t, expectedChecksum, checksum, "Checksum does not match")
 	assert.Equal(t, expectedSize, size, "File size does not match")
 }
+
+func TestDescribeEntrySymlinkPolicy(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.txt")
+	assert.NoError(t, os.WriteFile(target, []byte("test content"), 0o600))
+
+	link := filepath.Join(dir, "link.txt")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	event := &model.Event{Path: link}
+	_, err := describeEntry(event, model.SymlinkStore, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, model.KindSymlink, event.Kind)
+	assert.Equal(t, target, event.LinkTarget)
+
+	event = &model.Event{Path: link}
+	_, err = describeEntry(event, model.SymlinkFollow, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, model.KindFile, event.Kind)
+	assert.Equal(t, fmt.Sprintf("%x", sha256.Sum256([]byte("test content"))), event.Checksum)
+
+	event = &model.Event{Path: link}
+	_, err = describeEntry(event, model.SymlinkSkip, nil)
+	assert.ErrorIs(t, err, errSkipped)
+}
+
+func TestDescribeEntrySkipsDirectories(t *testing.T) {
+	_, err := describeEntry(&model.Event{Path: t.TempDir()}, model.SymlinkStore, nil)
+	assert.ErrorIs(t, err, errSkipped)
+}
@@ -3,27 +3,39 @@ package fsmonitor
 
 import (
 	"context"
+	"errors"
+	"io"
 	"log/slog"
-	"os"
-	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+
+	"github.com/sevigo/shugosha/pkg/metadata"
+	"github.com/sevigo/shugosha/pkg/metrics"
 	"github.com/sevigo/shugosha/pkg/model"
 )
 
 // Monitor provides file system monitoring.
 type Monitor struct {
 	dirs        map[string]int
+	options     map[string]model.DirectoryOptions
+	links       map[fileID]string // First path seen for each hard-linked file
+	linkPaths   map[string]fileID // Reverse of links, used to evict removed paths
+	linkLock    sync.Mutex
 	watcher     *fsnotify.Watcher
 	eventBuffer map[string][]fsnotify.Event
 	bufferLock  sync.Mutex
 	flushTimer  *time.Timer
 	flushDelay  time.Duration
 	subscribers []model.Subscriber
-	subLock     sync.Mutex // Protects the subscribers slice
+	subLock     sync.Mutex // Protects the subscribers slice and throttle
+	throttle    func(root string, r io.Reader) io.Reader
+	walking     int  // Directories being walked, protected by bufferLock
+	scanPending bool // Files found by a walk are not flushed yet, protected by bufferLock
+	status      model.WatcherStatus
+	statusLock  sync.Mutex
 }
 
 // New creates a new Monitor instance.
@@ -39,6 +51,9 @@ func New(cfg *Config) (*Monitor, error) {
 		flushDelay:  cfg.FlushDelay,
 		subscribers: make([]model.Subscriber, 0),
 		dirs:        make(map[string]int),
+		options:     make(map[string]model.DirectoryOptions),
+		links:       make(map[fileID]string),
+		linkPaths:   make(map[string]fileID),
 	}, nil
 }
 
@@ -61,6 +76,15 @@ func (m *Monitor) Subscribe(sub model.Subscriber) {
 	m.subscribers = append(m.subscribers, sub)
 }
 
+// SetReadThrottle sets a function wrapping every file the monitor reads below
+// root, e.g. to hash files within the read limits of the providers.
+func (m *Monitor) SetReadThrottle(fn func(root string, r io.Reader) io.Reader) {
+	m.subLock.Lock()
+	defer m.subLock.Unlock()
+
+	m.throttle = fn
+}
+
 // Unsubscribe removes a subscriber from the Monitor.
 func (m *Monitor) Unsubscribe(sub model.Subscriber) {
 	m.subLock.Lock()
@@ -74,8 +98,14 @@ func (m *Monitor) Unsubscribe(sub model.Subscriber) {
 	}
 }
 
-// Add adds a new directory to the watch list.
+// Add adds a new directory to the watch list using the default options.
 func (m *Monitor) Add(path string) error {
+	return m.AddWithOptions(path, model.DirectoryOptions{})
+}
+
+// AddWithOptions adds a new directory to the watch list. The options of the
+// first registration of a directory are kept.
+func (m *Monitor) AddWithOptions(path string, opts model.DirectoryOptions) error {
 	_, isMonitored := m.dirs[path]
 	if isMonitored {
 		m.dirs[path]++
@@ -83,25 +113,20 @@ func (m *Monitor) Add(path string) error {
 	}
 
 	m.dirs[path] = 1
+	m.options[path] = opts
 
-	return filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+	m.bufferLock.Lock()
+	m.walking++
+	m.bufferLock.Unlock()
 
-		if info.IsDir() {
-			return m.watcher.Add(path)
-		}
+	err := m.walk(path, opts.SymlinkPolicy, map[string]bool{})
 
-		if !info.IsDir() {
-			m.handleEvent(fsnotify.Event{
-				Name: path,
-				Op:   fsnotify.Create, // Using Create as an equivalent for 'added'
-			})
-		}
+	m.bufferLock.Lock()
+	m.walking--
+	m.scanPending = true
+	m.bufferLock.Unlock()
 
-		return nil
-	})
+	return err
 }
 
 // Remove removes a directory from the watch list.
@@ -112,7 +137,11 @@ func (m *Monitor) Remove(path string) error {
 
 // Start begins monitoring for file system events.
 func (m *Monitor) Start(ctx context.Context) error {
+	m.setRunning(true)
+
 	go func() {
+		defer m.setRunning(false)
+
 		// Periodic flush timer
 		flushTicker := time.NewTicker(m.flushDelay)
 		defer flushTicker.Stop()
@@ -129,6 +158,8 @@ func (m *Monitor) Start(ctx context.Context) error {
 				if !ok {
 					return
 				}
+				metrics.WatcherErrors.Inc()
+				m.setError(err)
 				slog.Error("got an error event from file watcher", "error", err)
 
 			case <-flushTicker.C:
@@ -161,6 +192,7 @@ func (m *Monitor) handleEvent(event fsnotify.Event) {
 	defer m.bufferLock.Unlock()
 
 	slog.Debug("[monitor] handle", "event", event.Op.String(), "file", event.Name)
+	metrics.EventsReceived.WithLabelValues(m.rootOf(event.Name)).Inc()
 
 	// Buffer the event
 	m.eventBuffer[event.Name] = append(m.eventBuffer[event.Name], event)
@@ -180,30 +212,119 @@ func (m *Monitor) flushEvents() {
 	m.bufferLock.Lock()
 	defer m.bufferLock.Unlock()
 
-	for _, events := range m.eventBuffer {
+	for path, events := range m.eventBuffer {
 		finalEvent := determineFinalEvent(events)
-		if finalEvent != nil {
-			m.emitEvent(*finalEvent)
+		if finalEvent == nil {
+			m.forgetLink(path)
+			continue
+		}
+
+		finalEvent.Root = m.rootOf(finalEvent.Path)
+		if err := m.describe(finalEvent); err != nil {
+			if !errors.Is(err, errSkipped) {
+				slog.Error("[monitor] failed to inspect file", "error", err, "file", finalEvent.Path)
+			}
+			continue
 		}
+
+		m.emitEvent(*finalEvent)
 	}
 
 	// Clear the buffer after processing
 	m.eventBuffer = make(map[string][]fsnotify.Event)
+
+	// Files found while walking are flushed once no walk is in progress
+	if m.walking == 0 {
+		m.scanPending = false
+	}
 }
 
-// emitEvent triggers the user-defined event handler.
-func (m *Monitor) emitEvent(event model.Event) {
-	// Determine the root directory for the event
+// describe fills in the file details of the event using the options of its root.
+func (m *Monitor) describe(event *model.Event) error {
+	info, err := describeEntry(event, m.options[event.Root].SymlinkPolicy, m.rootThrottle(event.Root))
+	if err != nil {
+		return err
+	}
+
+	if event.Kind == model.KindFile {
+		if id, ok := fileIdentity(info); ok {
+			event.HardlinkOf = m.hardlinkOrigin(id, event.Path)
+		} else {
+			m.forgetLink(event.Path)
+		}
+	}
+
+	meta, err := metadata.Capture(event.Path, info)
+	if err != nil {
+		slog.Warn("[monitor] failed to capture file metadata", "error", err, "file", event.Path)
+		return nil
+	}
+	event.Metadata = meta
+
+	return nil
+}
+
+// rootThrottle returns the read limit for files below root, or nil.
+func (m *Monitor) rootThrottle(root string) readThrottle {
+	m.subLock.Lock()
+	throttle := m.throttle
+	m.subLock.Unlock()
+
+	if throttle == nil {
+		return nil
+	}
+	return func(r io.Reader) io.Reader { return throttle(root, r) }
+}
+
+// rootOf determines the root directory for the given path.
+func (m *Monitor) rootOf(path string) string {
 	for root := range m.dirs {
-		if strings.HasPrefix(event.Path, root) {
-			event.Root = root
-			break
+		if strings.HasPrefix(path, root) {
+			return root
 		}
 	}
 
+	return ""
+}
+
+// emitEvent triggers the user-defined event handler.
+func (m *Monitor) emitEvent(event model.Event) {
 	m.subLock.Lock()
 	for _, sub := range m.subscribers {
 		sub.HandleEvent(event)
 	}
 	m.subLock.Unlock()
 }
+
+// WatcherStatus reports whether the watcher is running, the last error it
+// reported and whether the files found in the watched directories were
+// hashed and passed on to the subscribers.
+func (m *Monitor) WatcherStatus() model.WatcherStatus {
+	m.bufferLock.Lock()
+	scanned := m.walking == 0 && !m.scanPending
+	m.bufferLock.Unlock()
+
+	m.statusLock.Lock()
+	defer m.statusLock.Unlock()
+
+	status := m.status
+	status.Directories = len(m.RootDirs())
+	status.Scanned = status.Running && scanned
+	return status
+}
+
+func (m *Monitor) setRunning(running bool) {
+	m.statusLock.Lock()
+	defer m.statusLock.Unlock()
+
+	m.status.Running = running
+}
+
+func (m *Monitor) setError(err error) {
+	m.statusLock.Lock()
+	defer m.statusLock.Unlock()
+
+	now := time.Now()
+	m.status.LastError = err.Error()
+	m.status.LastErrorTime = &now
+}
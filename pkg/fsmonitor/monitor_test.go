@@ -0,0 +1,34 @@
+package fsmonitor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatcherStatus(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0o600))
+
+	monitor, err := New(&Config{FlushDelay: 20 * time.Millisecond})
+	assert.NoError(t, err)
+	assert.NoError(t, monitor.Add(dir))
+	assert.False(t, monitor.WatcherStatus().Running)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, monitor.Start(ctx))
+
+	// The file found by the walk is flushed after the delay
+	assert.Eventually(t, func() bool { return monitor.WatcherStatus().Scanned }, time.Second, 10*time.Millisecond)
+	status := monitor.WatcherStatus()
+	assert.True(t, status.Running)
+	assert.Equal(t, 1, status.Directories)
+
+	cancel()
+	assert.Eventually(t, func() bool { return !monitor.WatcherStatus().Running }, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,139 @@
+package fsmonitor
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// fileID identifies a file independent of its path.
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// walk adds dir and its sub-directories to the watcher and reports every file
+// found as added. Symbolic links are handled according to policy; directories
+// reached through followed links are tracked by their real path so that link
+// loops are visited only once.
+func (m *Monitor) walk(dir string, policy model.SymlinkPolicy, visited map[string]bool) error {
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	if visited[realDir] {
+		slog.Warn("[monitor] directory already visited, skipping symlink loop", "path", dir, "target", realDir)
+		return nil
+	}
+	visited[realDir] = true
+
+	if err := m.watcher.Add(dir); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+
+		switch {
+		case entry.IsDir():
+			if err := m.walk(path, policy, visited); err != nil {
+				return err
+			}
+
+		case entry.Type()&os.ModeSymlink != 0:
+			if err := m.walkSymlink(path, policy, visited); err != nil {
+				return err
+			}
+
+		case entry.Type().IsRegular():
+			m.handleEvent(fsnotify.Event{
+				Name: path,
+				Op:   fsnotify.Create, // Using Create as an equivalent for 'added'
+			})
+
+		default:
+			slog.Debug("[monitor] skipping special file", "path", path, "mode", entry.Type().String())
+		}
+	}
+
+	return nil
+}
+
+// walkSymlink handles a symbolic link found during the walk.
+func (m *Monitor) walkSymlink(path string, policy model.SymlinkPolicy, visited map[string]bool) error {
+	switch policy {
+	case model.SymlinkSkip:
+		return nil
+
+	case model.SymlinkFollow:
+		info, err := os.Stat(path)
+		if err != nil {
+			slog.Warn("[monitor] skipping dangling symlink", "path", path, "error", err)
+			return nil
+		}
+		if info.IsDir() {
+			return m.walk(path, policy, visited)
+		}
+		if !info.Mode().IsRegular() {
+			slog.Debug("[monitor] skipping symlink to special file", "path", path)
+			return nil
+		}
+	}
+
+	m.handleEvent(fsnotify.Event{Name: path, Op: fsnotify.Create})
+	return nil
+}
+
+// hardlinkOrigin returns the first path seen for the given file identity, or
+// an empty string if path is the first one.
+func (m *Monitor) hardlinkOrigin(id fileID, path string) string {
+	m.linkLock.Lock()
+	defer m.linkLock.Unlock()
+
+	origin, ok := m.links[id]
+	if ok && origin != path {
+		// The original may have been removed or replaced in the meantime
+		if info, err := os.Stat(origin); err == nil {
+			if current, ok := fileIdentity(info); ok && current == id {
+				return origin
+			}
+		}
+		if m.linkPaths[origin] == id {
+			delete(m.linkPaths, origin)
+		}
+	}
+
+	// The path may have belonged to another file before
+	if previous, ok := m.linkPaths[path]; ok && previous != id && m.links[previous] == path {
+		delete(m.links, previous)
+	}
+
+	m.links[id] = path
+	m.linkPaths[path] = id
+	return ""
+}
+
+// forgetLink drops path from the hard link origins once it was removed or
+// is no longer linked, so the origins only hold files that still exist.
+func (m *Monitor) forgetLink(path string) {
+	m.linkLock.Lock()
+	defer m.linkLock.Unlock()
+
+	id, ok := m.linkPaths[path]
+	if !ok {
+		return
+	}
+	delete(m.linkPaths, path)
+	if m.links[id] == path {
+		delete(m.links, id)
+	}
+}
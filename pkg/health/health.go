@@ -0,0 +1,210 @@
+// Package health tracks whether providers are reachable and pauses their
+// backups while they are not.
+package health
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+const (
+	// pingTimeout bounds a single health check.
+	pingTimeout = 10 * time.Second
+	// defaultInterval is the time between two checks of a healthy provider.
+	defaultInterval = 5 * time.Minute
+	// defaultRetryInterval is the time between two checks of an unhealthy provider.
+	defaultRetryInterval = 30 * time.Second
+)
+
+// Ensure Checker satisfies the HealthReporter interface
+var _ model.HealthReporter = (*Checker)(nil)
+
+// Checker holds the health of all providers.
+type Checker struct {
+	mu            sync.Mutex
+	providers     map[string]model.Provider
+	status        map[string]*model.ProviderHealth
+	ready         map[string]chan struct{} // Closed while the provider is healthy
+	interval      time.Duration
+	retryInterval time.Duration
+}
+
+func NewChecker() *Checker {
+	return &Checker{
+		providers:     map[string]model.Provider{},
+		status:        map[string]*model.ProviderHealth{},
+		ready:         map[string]chan struct{}{},
+		interval:      defaultInterval,
+		retryInterval: defaultRetryInterval,
+	}
+}
+
+// Add registers a provider and checks its health right away.
+func (c *Checker) Add(provider model.Provider) model.ProviderHealth {
+	c.mu.Lock()
+	c.providers[provider.Name()] = provider
+	c.mu.Unlock()
+
+	return c.Check(context.Background(), provider.Name())
+}
+
+// Check pings the provider and updates its health.
+func (c *Checker) Check(ctx context.Context, name string) model.ProviderHealth {
+	c.mu.Lock()
+	provider, ok := c.providers[name]
+	c.mu.Unlock()
+	if !ok {
+		return model.ProviderHealth{Healthy: true}
+	}
+
+	health := check(ctx, provider)
+	c.setStatus(name, health)
+	return health
+}
+
+// Recheck checks the provider after a failed backup and reports whether it
+// is still healthy. A nil Checker treats every provider as healthy.
+func (c *Checker) Recheck(ctx context.Context, name string) bool {
+	if c == nil {
+		return true
+	}
+	return c.Check(ctx, name).Healthy
+}
+
+// Healthy reports the last known health of the provider. Providers that were
+// never checked and a nil Checker count as healthy.
+func (c *Checker) Healthy(name string) bool {
+	if c == nil {
+		return true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	status, ok := c.status[name]
+	return !ok || status.Healthy
+}
+
+// WaitHealthy blocks until the provider is healthy or ctx is done.
+func (c *Checker) WaitHealthy(ctx context.Context, name string) error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	ready, ok := c.ready[name]
+	c.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Health returns the last known health of all providers.
+func (c *Checker) Health() map[string]model.ProviderHealth {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	health := make(map[string]model.ProviderHealth, len(c.status))
+	for name, status := range c.status {
+		health[name] = *status
+	}
+	return health
+}
+
+// Run checks unhealthy providers frequently and healthy ones from time to
+// time, until ctx is done.
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.retryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for name, status := range c.Health() {
+			if !status.Healthy || time.Since(status.LastCheck) >= c.interval {
+				c.Check(ctx, name)
+			}
+		}
+	}
+}
+
+func (c *Checker) setStatus(name string, health model.ProviderHealth) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	previous, known := c.status[name]
+	c.status[name] = &health
+
+	ready, ok := c.ready[name]
+	if !ok {
+		ready = make(chan struct{})
+		c.ready[name] = ready
+	}
+
+	if health.Healthy {
+		select {
+		case <-ready:
+		default:
+			close(ready)
+		}
+		if known && !previous.Healthy {
+			slog.Info("[health] provider recovered, resuming backups", "providerName", name)
+		}
+		return
+	}
+
+	select {
+	case <-ready:
+		// Pause the queue until the provider is healthy again
+		c.ready[name] = make(chan struct{})
+	default:
+	}
+	if !known || previous.Healthy {
+		slog.Warn("[health] provider unhealthy, pausing backups", "providerName", name, "error", health.Error)
+	}
+}
+
+// check pings the provider and collects its capabilities and free space.
+func check(ctx context.Context, provider model.Provider) model.ProviderHealth {
+	health := model.ProviderHealth{Healthy: true, LastCheck: time.Now()}
+
+	if healthProvider, ok := provider.(model.HealthProvider); ok {
+		health.Capabilities = healthProvider.Capabilities()
+
+		ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+		defer cancel()
+		if err := healthProvider.Ping(ctx); err != nil {
+			health.Healthy = false
+			health.Error = err.Error()
+		}
+	}
+
+	_, health.Capabilities.Restore = provider.(model.RestoreProvider)
+	_, health.Capabilities.Stream = provider.(model.StreamProvider)
+	_, health.Capabilities.List = provider.(model.ListProvider)
+
+	if spaceProvider, ok := provider.(model.SpaceProvider); ok && health.Healthy {
+		free, err := spaceProvider.FreeSpace()
+		if err != nil {
+			slog.Debug("[health] failed to get free space", "providerName", provider.Name(), "error", err)
+		} else {
+			health.FreeSpace = &free
+		}
+	}
+
+	return health
+}
@@ -0,0 +1,70 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+type flakyProvider struct {
+	mu  sync.Mutex
+	err error
+}
+
+func (p *flakyProvider) Backup(model.Event, io.Reader) error { return nil }
+func (p *flakyProvider) DirectoryList() []string             { return nil }
+func (p *flakyProvider) Name() string                        { return "Flaky" }
+
+func (p *flakyProvider) Capabilities() model.Capabilities {
+	return model.Capabilities{Versioning: true}
+}
+
+func (p *flakyProvider) Ping(context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+func (p *flakyProvider) setErr(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+func TestCheckerPausesUnhealthyProvider(t *testing.T) {
+	provider := &flakyProvider{err: errors.New("connection refused")}
+	checker := NewChecker()
+
+	status := checker.Add(provider)
+	assert.False(t, status.Healthy)
+	assert.Equal(t, "connection refused", status.Error)
+	assert.True(t, status.Capabilities.Versioning)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, checker.WaitHealthy(ctx, "Flaky"), context.DeadlineExceeded)
+
+	resumed := make(chan error, 1)
+	go func() { resumed <- checker.WaitHealthy(context.Background(), "Flaky") }()
+
+	provider.setErr(nil)
+	assert.True(t, checker.Recheck(context.Background(), "Flaky"))
+	assert.NoError(t, <-resumed)
+
+	provider.setErr(errors.New("timeout"))
+	assert.False(t, checker.Recheck(context.Background(), "Flaky"))
+	assert.False(t, checker.Health()["Flaky"].Healthy)
+}
+
+func TestNilCheckerIsAlwaysHealthy(t *testing.T) {
+	var checker *Checker
+	assert.NoError(t, checker.WaitHealthy(context.Background(), "any"))
+	assert.True(t, checker.Recheck(context.Background(), "any"))
+}
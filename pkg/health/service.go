@@ -0,0 +1,134 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+const (
+	// dbTimeout bounds the database check.
+	dbTimeout = 5 * time.Second
+	// watcherErrorWindow is how long a watcher error degrades the service.
+	watcherErrorWindow = 5 * time.Minute
+	// dbProbeKey is read to check the database, it does not need to exist.
+	dbProbeKey = "health:probe"
+)
+
+// Service checks the liveness and readiness of the whole service.
+type Service struct {
+	watcher   model.WatcherReporter
+	db        model.DB
+	providers model.HealthReporter
+}
+
+// Ensure Service satisfies the ServiceHealthReporter interface
+var _ model.ServiceHealthReporter = (*Service)(nil)
+
+// NewService creates a health check of the watcher, the catalog database and
+// the providers.
+func NewService(watcher model.WatcherReporter, db model.DB, providers model.HealthReporter) *Service {
+	return &Service{watcher: watcher, db: db, providers: providers}
+}
+
+// Liveness checks the watcher and the database. A service that is not alive
+// does not notice or record changes and should be restarted.
+func (s *Service) Liveness(ctx context.Context) model.ServiceHealth {
+	return s.liveness(ctx, s.watcher.WatcherStatus())
+}
+
+func (s *Service) liveness(ctx context.Context, watcher model.WatcherStatus) model.ServiceHealth {
+	return summarize(model.ServiceHealth{Components: map[string]model.ComponentHealth{
+		"watcher":  watcherHealth(watcher),
+		"database": s.databaseHealth(ctx),
+	}})
+}
+
+// Readiness additionally checks that the initial scan finished and every
+// provider is reachable, as of its last health check.
+func (s *Service) Readiness(ctx context.Context) model.ServiceHealth {
+	// One snapshot, so the watcher and scan components agree
+	watcher := s.watcher.WatcherStatus()
+	health := s.liveness(ctx, watcher)
+
+	scan := model.ComponentHealth{Status: model.HealthOK}
+	if !watcher.Scanned {
+		scan = model.ComponentHealth{Status: model.HealthDegraded, Class: "scanning", Error: "initial scan in progress"}
+	}
+	health.Components["initialScan"] = scan
+
+	health.Providers = map[string]model.ComponentHealth{}
+	for name, status := range s.providers.Health() {
+		component := model.ComponentHealth{Status: model.HealthOK, Details: status}
+		if !status.Healthy {
+			component.Status = model.HealthDown
+			component.Class = "unreachable"
+			component.Error = status.Error
+		}
+		health.Providers[name] = component
+	}
+
+	return summarize(health)
+}
+
+func watcherHealth(status model.WatcherStatus) model.ComponentHealth {
+	switch {
+	case !status.Running:
+		return model.ComponentHealth{Status: model.HealthDown, Class: "not-running", Error: "watcher is not running", Details: status}
+	case status.LastErrorTime != nil && time.Since(*status.LastErrorTime) < watcherErrorWindow:
+		return model.ComponentHealth{Status: model.HealthDegraded, Class: "watch-error", Error: status.LastError, Details: status}
+	default:
+		return model.ComponentHealth{Status: model.HealthOK, Details: status}
+	}
+}
+
+// databaseHealth reads a key from the database, which must answer within
+// dbTimeout.
+func (s *Service) databaseHealth(ctx context.Context) model.ComponentHealth {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := s.db.Get(dbProbeKey)
+		result <- err
+	}()
+
+	var err error
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		err = fmt.Errorf("no answer within %s", dbTimeout)
+	}
+
+	if err != nil && !errors.Is(err, model.ErrDBKeyNotFound) {
+		return model.ComponentHealth{Status: model.HealthDown, Class: "unavailable", Error: err.Error()}
+	}
+	return model.ComponentHealth{Status: model.HealthOK}
+}
+
+// summarize sets the status of the service: down if a component is down,
+// degraded if a component is degraded or a provider unreachable.
+func summarize(health model.ServiceHealth) model.ServiceHealth {
+	health.Status = model.HealthOK
+	for _, component := range health.Components {
+		health.Status = worst(health.Status, component.Status)
+	}
+	for _, provider := range health.Providers {
+		if provider.Status != model.HealthOK {
+			health.Status = worst(health.Status, model.HealthDegraded)
+		}
+	}
+	return health
+}
+
+func worst(a, b model.HealthStatus) model.HealthStatus {
+	rank := map[model.HealthStatus]int{model.HealthOK: 0, model.HealthDegraded: 1, model.HealthDown: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,71 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sevigo/shugosha/pkg/db"
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+type testWatcher struct {
+	status model.WatcherStatus
+}
+
+func (w *testWatcher) WatcherStatus() model.WatcherStatus {
+	return w.status
+}
+
+func TestService(t *testing.T) {
+	watcher := &testWatcher{status: model.WatcherStatus{Running: true, Scanned: true}}
+	provider := &flakyProvider{}
+	checker := NewChecker()
+	checker.Add(provider)
+	service := NewService(watcher, db.NewMemoryDB(), checker)
+	ctx := context.Background()
+
+	assert.Equal(t, model.HealthOK, service.Liveness(ctx).Status)
+	assert.Equal(t, model.HealthOK, service.Readiness(ctx).Status)
+
+	// An unreachable provider degrades readiness only
+	provider.setErr(errors.New("connection refused"))
+	checker.Check(ctx, "Flaky")
+	assert.Equal(t, model.HealthOK, service.Liveness(ctx).Status)
+	readiness := service.Readiness(ctx)
+	assert.Equal(t, model.HealthDegraded, readiness.Status)
+	assert.Equal(t, model.HealthDown, readiness.Providers["Flaky"].Status)
+	assert.Equal(t, "connection refused", readiness.Providers["Flaky"].Error)
+
+	// A recent watcher error degrades, a stopped watcher takes the service down
+	now := time.Now()
+	watcher.status.LastError = "queue overflow"
+	watcher.status.LastErrorTime = &now
+	assert.Equal(t, model.HealthDegraded, service.Liveness(ctx).Status)
+	watcher.status.Running = false
+	liveness := service.Liveness(ctx)
+	assert.Equal(t, model.HealthDown, liveness.Status)
+	assert.Equal(t, "watcher is not running", liveness.Components["watcher"].Error)
+}
+
+// countingWatcher reports a finished scan from the second status on.
+type countingWatcher struct {
+	calls int
+}
+
+func (w *countingWatcher) WatcherStatus() model.WatcherStatus {
+	w.calls++
+	return model.WatcherStatus{Running: true, Scanned: w.calls > 1}
+}
+
+func TestReadinessUsesOneWatcherStatus(t *testing.T) {
+	watcher := &countingWatcher{}
+	service := NewService(watcher, db.NewMemoryDB(), NewChecker())
+
+	readiness := service.Readiness(context.Background())
+	assert.Equal(t, 1, watcher.calls)
+	assert.Equal(t, model.HealthDegraded, readiness.Components["initialScan"].Status)
+}
@@ -0,0 +1,108 @@
+// Package jsonschema describes Go types as JSON Schema and validates JSON
+// values against the subset of JSON Schema it produces.
+package jsonschema
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// enums lists the allowed values of string types.
+var enums = map[reflect.Type][]any{
+	reflect.TypeOf(model.SymlinkPolicy("")): {model.SymlinkStore, model.SymlinkFollow, model.SymlinkSkip},
+	reflect.TypeOf(model.QuotaAction("")):   {model.QuotaRefuse, model.QuotaDefer},
+	reflect.TypeOf(model.APIRole("")):       {model.RoleRead, model.RoleAdmin},
+	reflect.TypeOf(model.VerifyMode("")):    {model.VerifyQuick, model.VerifyFull},
+	reflect.TypeOf(model.ActivityType("")):  {model.ActivityDetected, model.ActivityStarted, model.ActivityProgress, model.ActivitySucceeded, model.ActivityFailed, model.ActivityPaused},
+	reflect.TypeOf(model.HealthStatus("")):  {model.HealthOK, model.HealthDegraded, model.HealthDown},
+}
+
+// Of describes the type of value as JSON Schema.
+func Of(value any) map[string]any {
+	return Reflect(reflect.TypeOf(value))
+}
+
+// Reflect describes a Go type as JSON Schema, using the names of its JSON
+// tags. Fields that encoding/json writes as null are nullable.
+func Reflect(t reflect.Type) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if values, ok := enums[t]; ok {
+		return map[string]any{"type": "string", "enum": values}
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": Reflect(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": Reflect(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]any{}
+		addProperties(t, properties)
+		return map[string]any{"type": "object", "properties": properties}
+	default:
+		return map[string]any{}
+	}
+}
+
+// addProperties adds the fields of a struct, including those of embedded
+// structs, which encoding/json flattens.
+func addProperties(t reflect.Type, properties map[string]any) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		fieldType := field.Type
+		if field.Anonymous && name == "" {
+			if fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				addProperties(fieldType, properties)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := Reflect(fieldType)
+		if _, typed := schema["type"]; typed && nullable(fieldType) && !strings.Contains(options, "omitempty") {
+			schema["type"] = []any{schema["type"], "null"}
+		}
+		properties[name] = schema
+	}
+}
+
+func nullable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map:
+		return true
+	default:
+		return false
+	}
+}
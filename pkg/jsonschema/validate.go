@@ -0,0 +1,217 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// Validate checks a decoded JSON value against schema and returns all
+// problems with the path of the invalid value, e.g. "providers[0].name".
+// Numbers must be decoded as json.Number. Supported keywords are type, enum,
+// const, format date-time, minimum, properties, required,
+// additionalProperties, items, allOf and if/then.
+func Validate(schema map[string]any, value any) model.ValidationErrors {
+	var errs model.ValidationErrors
+	validate(schema, value, "", &errs)
+	return errs
+}
+
+func validate(schema map[string]any, value any, path string, errs *model.ValidationErrors) {
+	addError := func(format string, args ...any) {
+		field := path
+		if field == "" {
+			field = "(root)"
+		}
+		*errs = append(*errs, model.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if types, ok := schema["type"]; ok && !matchesType(types, value) {
+		addError("must be of type %s", typeNames(types))
+		return
+	}
+
+	if values, ok := schema["enum"]; ok && !contains(values, value) {
+		addError("must be one of %v", values)
+	}
+	if constant, ok := schema["const"]; ok && !equal(constant, value) {
+		addError("must be %v", constant)
+	}
+
+	switch value := value.(type) {
+	case string:
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339, value); err != nil {
+				addError("must be an RFC 3339 date-time")
+			}
+		}
+
+	case json.Number:
+		if minimum, ok := schema["minimum"]; ok {
+			if number, err := value.Float64(); err == nil && number < toFloat(minimum) {
+				addError("must be at least %v", minimum)
+			}
+		}
+
+	case map[string]any:
+		properties, _ := schema["properties"].(map[string]any)
+		for _, name := range requiredNames(schema["required"]) {
+			if _, ok := value[name]; !ok {
+				addError("%s is required", name)
+			}
+		}
+
+		for _, key := range sortedKeys(value) {
+			field := joinPath(path, key)
+			if property, ok := properties[key].(map[string]any); ok {
+				validate(property, value[key], field, errs)
+				continue
+			}
+
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					*errs = append(*errs, model.FieldError{Field: field, Message: "is not allowed"})
+				}
+			case map[string]any:
+				validate(additional, value[key], field, errs)
+			}
+		}
+
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range value {
+				validate(items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	}
+
+	allOf, _ := schema["allOf"].([]any)
+	for _, condition := range allOf {
+		if condition, ok := condition.(map[string]any); ok {
+			validate(condition, value, path, errs)
+		}
+	}
+
+	if condition, ok := schema["if"].(map[string]any); ok && len(Validate(condition, value)) == 0 {
+		if then, ok := schema["then"].(map[string]any); ok {
+			validate(then, value, path, errs)
+		}
+	}
+}
+
+func matchesType(types, value any) bool {
+	switch types := types.(type) {
+	case string:
+		return isType(types, value)
+	case []string:
+		for _, t := range types {
+			if isType(t, value) {
+				return true
+			}
+		}
+	case []any:
+		for _, t := range types {
+			if t, ok := t.(string); ok && isType(t, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isType(t string, value any) bool {
+	switch value := value.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case string:
+		return t == "string"
+	case json.Number:
+		if t == "number" {
+			return true
+		}
+		_, err := value.Int64()
+		return t == "integer" && (err == nil || !strings.ContainsAny(value.String(), ".eE"))
+	case map[string]any:
+		return t == "object"
+	case []any:
+		return t == "array"
+	default:
+		return false
+	}
+}
+
+func typeNames(types any) string {
+	if t, ok := types.(string); ok {
+		return t
+	}
+	return strings.Trim(fmt.Sprint(types), "[]")
+}
+
+func contains(values, value any) bool {
+	list := reflect.ValueOf(values)
+	if list.Kind() != reflect.Slice {
+		return true
+	}
+	for i := 0; i < list.Len(); i++ {
+		if equal(list.Index(i).Interface(), value) {
+			return true
+		}
+	}
+	return false
+}
+
+// equal compares a schema value, which may be of a named Go type, with a
+// decoded JSON value.
+func equal(expected, value any) bool {
+	if reflect.TypeOf(expected) != nil && reflect.TypeOf(expected).Kind() == reflect.String {
+		s, ok := value.(string)
+		return ok && s == reflect.ValueOf(expected).String()
+	}
+	return reflect.DeepEqual(expected, value)
+}
+
+func requiredNames(required any) []string {
+	switch required := required.(type) {
+	case []string:
+		return required
+	case []any:
+		names := make([]string, 0, len(required))
+		for _, name := range required {
+			if name, ok := name.(string); ok {
+				names = append(names, name)
+			}
+		}
+		return names
+	default:
+		return nil
+	}
+}
+
+func toFloat(value any) float64 {
+	number, _ := json.Number(fmt.Sprint(value)).Float64()
+	return number
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -3,17 +3,68 @@ package logger
 import (
 	"log/slog"
 	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/lmittmann/tint"
 	"github.com/mattn/go-colorable"
 )
 
+// Redacted replaces secrets in log messages and attributes.
+const Redacted = "********"
+
+var (
+	secretsMu sync.RWMutex
+	secrets   []string
+)
+
 func Setup() {
 	w := os.Stderr
 
 	slog.SetDefault(slog.New(tint.NewHandler(colorable.NewColorable(w), &tint.Options{
-		Level:      slog.LevelDebug,
-		TimeFormat: time.DateTime,
+		Level:       slog.LevelDebug,
+		TimeFormat:  time.DateTime,
+		ReplaceAttr: redactAttr,
 	})))
 }
+
+// Redact hides secret in all following log output.
+func Redact(secret string) {
+	if secret == "" {
+		return
+	}
+
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, known := range secrets {
+		if known == secret {
+			return
+		}
+	}
+	secrets = append(secrets, secret)
+}
+
+// RedactString replaces all secrets passed to Redact in s.
+func RedactString(s string) string {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+	return s
+}
+
+func redactAttr(_ []string, attr slog.Attr) slog.Attr {
+	switch value := attr.Value.Any().(type) {
+	case string:
+		if redacted := RedactString(value); redacted != value {
+			return slog.String(attr.Key, redacted)
+		}
+	case error:
+		if message := value.Error(); RedactString(message) != message {
+			return slog.String(attr.Key, RedactString(message))
+		}
+	}
+	return attr
+}
@@ -0,0 +1,142 @@
+// Package metadata captures and reapplies POSIX file metadata.
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// Unix encoding of the special mode bits.
+const (
+	modeSetuid = 0o4000
+	modeSetgid = 0o2000
+	modeSticky = 0o1000
+)
+
+// ApplyOptions controls which parts of the metadata are reapplied.
+type ApplyOptions struct {
+	SkipOwnership bool // Do not change uid/gid, required when not running as root
+}
+
+// Capture reads the metadata of path. The info must be the result of an
+// os.Lstat or os.Stat call on the same path.
+func Capture(path string, info os.FileInfo) (*model.FileMetadata, error) {
+	meta := &model.FileMetadata{
+		Mode:    toUnixMode(info.Mode()),
+		UID:     -1,
+		GID:     -1,
+		ModTime: info.ModTime(),
+	}
+	fillFromSys(meta, info)
+
+	xattrs, err := readXattrs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read extended attributes: %w", err)
+	}
+
+	for name, value := range xattrs {
+		if isACL(name) {
+			if meta.ACLs == nil {
+				meta.ACLs = map[string][]byte{}
+			}
+			meta.ACLs[name] = value
+			continue
+		}
+		if meta.Xattrs == nil {
+			meta.Xattrs = map[string][]byte{}
+		}
+		meta.Xattrs[name] = value
+	}
+
+	return meta, nil
+}
+
+// Apply sets the metadata on path. Every step is attempted and all failures
+// are returned together.
+func Apply(path string, meta *model.FileMetadata, opts ApplyOptions) error {
+	if meta == nil {
+		return nil
+	}
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	isLink := info.Mode()&os.ModeSymlink != 0
+
+	var errs []error
+
+	// Ownership first, changing it may clear the setuid and setgid bits
+	if !opts.SkipOwnership && meta.UID >= 0 && meta.GID >= 0 {
+		if err := os.Lchown(path, meta.UID, meta.GID); err != nil {
+			errs = append(errs, fmt.Errorf("chown: %w", err))
+		}
+	}
+
+	if !isLink {
+		if err := os.Chmod(path, fromUnixMode(meta.Mode)); err != nil {
+			errs = append(errs, fmt.Errorf("chmod: %w", err))
+		}
+	}
+
+	for name, value := range meta.Xattrs {
+		if err := writeXattr(path, name, value); err != nil {
+			errs = append(errs, fmt.Errorf("xattr %s: %w", name, err))
+		}
+	}
+
+	// ACLs go after chmod, which rewrites the ACL mask entry
+	for name, value := range meta.ACLs {
+		if err := writeXattr(path, name, value); err != nil {
+			errs = append(errs, fmt.Errorf("acl %s: %w", name, err))
+		}
+	}
+
+	if !isLink {
+		atime := meta.AccessTime
+		if atime.IsZero() {
+			atime = meta.ModTime
+		}
+		if err := os.Chtimes(path, atime, meta.ModTime); err != nil {
+			errs = append(errs, fmt.Errorf("chtimes: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// isACL reports whether the extended attribute holds a POSIX ACL.
+func isACL(name string) bool {
+	return name == "system.posix_acl_access" || name == "system.posix_acl_default"
+}
+
+func toUnixMode(mode os.FileMode) uint32 {
+	m := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		m |= modeSetuid
+	}
+	if mode&os.ModeSetgid != 0 {
+		m |= modeSetgid
+	}
+	if mode&os.ModeSticky != 0 {
+		m |= modeSticky
+	}
+	return m
+}
+
+func fromUnixMode(m uint32) os.FileMode {
+	mode := os.FileMode(m).Perm()
+	if m&modeSetuid != 0 {
+		mode |= os.ModeSetuid
+	}
+	if m&modeSetgid != 0 {
+		mode |= os.ModeSetgid
+	}
+	if m&modeSticky != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode
+}
@@ -0,0 +1,41 @@
+package metadata
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCaptureAndApply(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	assert.NoError(t, os.WriteFile(src, []byte("content"), 0o640))
+	assert.NoError(t, os.WriteFile(dst, []byte("content"), 0o600))
+
+	modTime := time.Date(2023, 12, 24, 18, 0, 0, 0, time.UTC)
+	assert.NoError(t, os.Chtimes(src, modTime, modTime))
+
+	info, err := os.Lstat(src)
+	assert.NoError(t, err)
+
+	meta, err := Capture(src, info)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0o640), meta.Mode)
+
+	assert.NoError(t, Apply(dst, meta, ApplyOptions{SkipOwnership: true}))
+
+	restored, err := os.Lstat(dst)
+	assert.NoError(t, err)
+	assert.Equal(t, info.Mode(), restored.Mode())
+	assert.True(t, modTime.Equal(restored.ModTime()), "modification time does not match")
+}
+
+func TestUnixModeRoundTrip(t *testing.T) {
+	mode := os.FileMode(0o755) | os.ModeSetuid | os.ModeSticky
+	assert.Equal(t, uint32(0o5755), toUnixMode(mode))
+	assert.Equal(t, mode, fromUnixMode(toUnixMode(mode)))
+}
@@ -0,0 +1,21 @@
+package metadata
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// fillFromSys copies ownership and access time from the system stat data.
+func fillFromSys(meta *model.FileMetadata, info os.FileInfo) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+
+	meta.UID = int(stat.Uid)
+	meta.GID = int(stat.Gid)
+	meta.AccessTime = time.Unix(int64(stat.Atim.Sec), int64(stat.Atim.Nsec))
+}
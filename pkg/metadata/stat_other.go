@@ -0,0 +1,12 @@
+//go:build !linux && !darwin
+
+package metadata
+
+import (
+	"os"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// fillFromSys is a no-op on platforms without POSIX ownership support.
+func fillFromSys(_ *model.FileMetadata, _ os.FileInfo) {}
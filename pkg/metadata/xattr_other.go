@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+
+package metadata
+
+import "errors"
+
+// readXattrs is a no-op on platforms without extended attribute support.
+func readXattrs(_ string) (map[string][]byte, error) {
+	return nil, nil
+}
+
+func writeXattr(_, _ string, _ []byte) error {
+	return errors.New("extended attributes are not supported on this platform")
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+var (
+	queueDepthDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "backup", "queue_depth"),
+		"Detected changes that are not backed up yet, by provider.",
+		[]string{"provider"}, nil,
+	)
+	oldestPendingDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "backup", "oldest_pending_seconds"),
+		"Age of the oldest change that is not backed up yet, 0 if there is none, by provider.",
+		[]string{"provider"}, nil,
+	)
+	dbSizeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db", "size_bytes"),
+		"Size of the catalog database.",
+		nil, nil,
+	)
+)
+
+// Collector reports the backup queue and the database size when the metrics
+// are scraped.
+type Collector struct {
+	queue model.BackupQueue
+	db    model.DB
+}
+
+// Ensure Collector satisfies the prometheus.Collector interface
+var _ prometheus.Collector = (*Collector)(nil)
+
+// NewCollector creates a collector for the pending backups of queue and the
+// size of db, if it can report one.
+func NewCollector(queue model.BackupQueue, db model.DB) *Collector {
+	return &Collector{queue: queue, db: db}
+}
+
+// Describe sends the descriptions of the collected metrics.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- queueDepthDesc
+	ch <- oldestPendingDesc
+	ch <- dbSizeDesc
+}
+
+// Collect sends the current values.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	now := time.Now()
+	for provider, pending := range c.queue.PendingBackups() {
+		age := 0.0
+		if pending.Count > 0 {
+			age = now.Sub(pending.Oldest).Seconds()
+		}
+		ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(pending.Count), provider)
+		ch <- prometheus.MustNewConstMetric(oldestPendingDesc, prometheus.GaugeValue, age, provider)
+	}
+
+	if sized, ok := c.db.(model.SizedDB); ok {
+		size, err := sized.Size()
+		if err != nil {
+			slog.Warn("[metrics] failed to get database size", "error", err)
+			return
+		}
+		ch <- prometheus.MustNewConstMetric(dbSizeDesc, prometheus.GaugeValue, float64(size))
+	}
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sevigo/shugosha/pkg/db"
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+type testQueue map[string]model.PendingBackups
+
+func (q testQueue) PendingBackups() map[string]model.PendingBackups {
+	return q
+}
+
+func TestCollector(t *testing.T) {
+	storage := db.NewMemoryDB()
+	assert.NoError(t, storage.Set("key", []byte("value")))
+
+	collector := NewCollector(testQueue{
+		"Echo": {},
+		"S3":   {Count: 3, Oldest: time.Now().Add(-time.Hour)},
+	}, storage)
+
+	expected := `
+# HELP shugosha_backup_queue_depth Detected changes that are not backed up yet, by provider.
+# TYPE shugosha_backup_queue_depth gauge
+shugosha_backup_queue_depth{provider="Echo"} 0
+shugosha_backup_queue_depth{provider="S3"} 3
+# HELP shugosha_db_size_bytes Size of the catalog database.
+# TYPE shugosha_db_size_bytes gauge
+shugosha_db_size_bytes 8
+`
+	assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected), "shugosha_backup_queue_depth", "shugosha_db_size_bytes"))
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector)
+	families, err := registry.Gather()
+	assert.NoError(t, err)
+
+	ages := map[string]float64{}
+	for _, family := range families {
+		if family.GetName() != "shugosha_backup_oldest_pending_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			ages[metric.GetLabel()[0].GetValue()] = metric.GetGauge().GetValue()
+		}
+	}
+	assert.Equal(t, 0.0, ages["Echo"])
+	assert.InDelta(t, time.Hour.Seconds(), ages["S3"], 5)
+}
@@ -0,0 +1,94 @@
+// Package metrics exposes the Prometheus metrics of the service.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "shugosha"
+
+// Registry holds all metrics of the service, including the Go runtime and
+// process metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// EventsReceived counts the file system events from the watcher per root directory.
+	EventsReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "monitor",
+		Name:      "events_total",
+		Help:      "File system events received from the watcher, by watched root directory.",
+	}, []string{"root"})
+
+	// WatcherErrors counts the errors reported by the watcher.
+	WatcherErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "monitor",
+		Name:      "watcher_errors_total",
+		Help:      "Errors reported by the file system watcher.",
+	})
+
+	// HashDuration observes the time to hash changed files; its count is the
+	// number of hashes computed.
+	HashDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "monitor",
+		Name:      "hash_duration_seconds",
+		Help:      "Time to compute the checksum of a changed file.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 4, 9), // 1ms to about 65s
+	})
+
+	// HashedBytes counts the bytes read to compute checksums.
+	HashedBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "monitor",
+		Name:      "hashed_bytes_total",
+		Help:      "Bytes read to compute the checksums of changed files.",
+	})
+
+	// Backups counts the finished backups per provider and status.
+	Backups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "backups_total",
+		Help:      "Finished backups by provider and status.",
+	}, []string{"provider", "status"})
+
+	// BackupDuration observes the time from starting a backup to its result.
+	BackupDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "backup_duration_seconds",
+		Help:      "Time to back up a file, including quota checks and retries, by provider.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 4, 10), // 10ms to about 45min
+	}, []string{"provider"})
+
+	// UploadedBytes counts the bytes written by the providers.
+	UploadedBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "uploaded_bytes_total",
+		Help:      "Bytes of file content uploaded, by provider.",
+	}, []string{"provider"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		EventsReceived,
+		WatcherErrors,
+		HashDuration,
+		HashedBytes,
+		Backups,
+		BackupDuration,
+		UploadedBytes,
+	)
+}
+
+// Handler returns an HTTP handler serving the metrics in the Prometheus
+// exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
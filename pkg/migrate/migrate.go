@@ -0,0 +1,177 @@
+// Package migrate versions the schema of the catalog database and upgrades
+// older databases at startup.
+package migrate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+const schemaVersionKey = "meta:schemaVersion"
+
+// finalOps is the number of writes committed in one transaction with the
+// new schema version, the writes before them go through a batch that may be
+// too large for a single transaction.
+const finalOps = 1000
+
+// Migration upgrades the database from the previous version to Version.
+// Apply reads the database and adds its writes to the batch, which Run
+// commits together with the new version. Migrations must be safe to apply
+// again, as an interrupted commit is retried on the next start.
+type Migration struct {
+	Version     int
+	Description string
+	Apply       func(storage model.DB, batch *model.Batch) error
+}
+
+// LatestVersion returns the schema version this build writes.
+func LatestVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// Version returns the schema version of the database, 0 for databases
+// created before versioning was introduced.
+func Version(storage model.DB) (int, error) {
+	value, err := storage.Get(schemaVersionKey)
+	if errors.Is(err, model.ErrDBKeyNotFound) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	version, err := strconv.Atoi(string(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid schema version %q: %w", value, err)
+	}
+	return version, nil
+}
+
+// Run applies all pending migrations in order. A database that is not empty
+// is backed up to backupDir first, unless backupDir is empty. Databases with
+// a newer schema than this build knows are refused.
+func Run(storage model.DB, backupDir string) error {
+	current, err := Version(storage)
+	if err != nil {
+		return err
+	}
+
+	latest := LatestVersion()
+	if current > latest {
+		return fmt.Errorf("database schema version %d is newer than the supported version %d, please upgrade Shugosha", current, latest)
+	}
+	if current == latest {
+		return nil
+	}
+
+	empty, err := isEmpty(storage)
+	if err != nil {
+		return err
+	}
+
+	if !empty && backupDir != "" {
+		path, err := Backup(storage, backupDir, current)
+		if err != nil {
+			return fmt.Errorf("failed to back up database before migrating: %w", err)
+		}
+		slog.Info("[migrate] database backed up", "path", path)
+	}
+
+	for _, migration := range migrations {
+		if migration.Version <= current {
+			continue
+		}
+
+		slog.Info("[migrate] applying migration", "version", migration.Version, "description", migration.Description)
+		batch := &model.Batch{}
+		if err := migration.Apply(storage, batch); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Description, err)
+		}
+
+		if err := commit(storage, batch, migration.Version); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Description, err)
+		}
+	}
+
+	return nil
+}
+
+// commit writes the batch of a migration, the version is set in the same
+// transaction as its final writes so it is only recorded once all of them
+// have been applied.
+func commit(storage model.DB, batch *model.Batch, version int) error {
+	ops := batch.Ops
+	if len(ops) > finalOps {
+		if err := storage.WriteBatch(&model.Batch{Ops: ops[:len(ops)-finalOps]}); err != nil {
+			return err
+		}
+		ops = ops[len(ops)-finalOps:]
+	}
+
+	return storage.Update(func(txn model.Txn) error {
+		for _, op := range ops {
+			var err error
+			if op.Value == nil {
+				err = txn.Delete(op.Key)
+			} else {
+				err = txn.Set(op.Key, op.Value)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return txn.Set(schemaVersionKey, []byte(strconv.Itoa(version)))
+	})
+}
+
+// backupEntry is a single key of a database backup.
+type backupEntry struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// Backup writes all keys of the database as JSON lines into a new file in
+// dir and returns its path. dir is created if it does not exist.
+func Backup(storage model.DB, dir string, version int) (string, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("backup-schema-v%d-%s.jsonl", version, time.Now().Format("20060102-150405"))
+	path := filepath.Join(dir, name)
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	err = storage.Iterate("", func(key string, value []byte) error {
+		return encoder.Encode(backupEntry{Key: key, Value: value})
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return path, file.Close()
+}
+
+var errNotEmpty = errors.New("database is not empty")
+
+func isEmpty(storage model.DB) (bool, error) {
+	err := storage.Iterate("", func(string, []byte) error {
+		return errNotEmpty
+	})
+	if errors.Is(err, errNotEmpty) {
+		return false, nil
+	}
+	return err == nil, err
+}
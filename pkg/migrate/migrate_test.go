@@ -0,0 +1,92 @@
+package migrate
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/sevigo/shugosha/pkg/catalog"
+	"github.com/sevigo/shugosha/pkg/db"
+	"github.com/sevigo/shugosha/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunConvertsEventsAndBacksUp(t *testing.T) {
+	storage := db.NewMemoryDB()
+	dir := t.TempDir()
+
+	event := `{"Root":"/data","Path":"/data/a.txt","Checksum":"abc","Size":3}`
+	assert.NoError(t, storage.Set("Echo:/data/a.txt", []byte(event)))
+
+	assert.NoError(t, Run(storage, dir))
+
+	version, err := Version(storage)
+	assert.NoError(t, err)
+	assert.Equal(t, LatestVersion(), version)
+
+	value, err := storage.Get("Echo:/data/a.txt")
+	assert.NoError(t, err)
+	var record model.FileRecord
+	assert.NoError(t, json.Unmarshal(value, &record))
+	assert.Equal(t, "Echo", record.Provider)
+	assert.Equal(t, "/data/a.txt", record.Path)
+	assert.Equal(t, "abc", record.Checksum)
+
+	result, err := catalog.New(storage).SearchFiles(model.FileQuery{Checksum: "abc"})
+	assert.NoError(t, err)
+	assert.Len(t, result.Files, 1)
+
+	backups, _ := filepath.Glob(filepath.Join(dir, "backup-schema-v0-*.jsonl"))
+	assert.Len(t, backups, 1)
+
+	// A second run has nothing to do and writes no further backup.
+	assert.NoError(t, Run(storage, dir))
+	entries, _ := os.ReadDir(dir)
+	assert.Len(t, entries, 1)
+}
+
+func TestRunRefusesNewerSchema(t *testing.T) {
+	storage := db.NewMemoryDB()
+	assert.NoError(t, storage.Set(schemaVersionKey, []byte(strconv.Itoa(LatestVersion()+1))))
+
+	assert.Error(t, Run(storage, ""))
+}
+
+// failingUpdateDB discards every transaction.
+type failingUpdateDB struct {
+	model.DB
+}
+
+func (failingUpdateDB) Update(func(model.Txn) error) error {
+	return errors.New("transaction failed")
+}
+
+func TestCommitSetsVersionWithFinalWrites(t *testing.T) {
+	storage := db.NewMemoryDB()
+
+	batch := &model.Batch{}
+	for i := 0; i < finalOps+10; i++ {
+		batch.Set("key:"+strconv.Itoa(i), []byte("value"))
+	}
+
+	// Without the final transaction the version stays unset, so the
+	// migration is applied again
+	assert.Error(t, commit(failingUpdateDB{storage}, batch, 7))
+	_, err := storage.Get("key:0")
+	assert.NoError(t, err)
+	_, err = storage.Get("key:" + strconv.Itoa(finalOps+9))
+	assert.ErrorIs(t, err, model.ErrDBKeyNotFound)
+	version, err := Version(storage)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, version)
+
+	assert.NoError(t, commit(storage, batch, 7))
+	_, err = storage.Get("key:" + strconv.Itoa(finalOps+9))
+	assert.NoError(t, err)
+	version, err = Version(storage)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, version)
+}
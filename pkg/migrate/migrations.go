@@ -0,0 +1,56 @@
+package migrate
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/sevigo/shugosha/pkg/catalog"
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// migrations lists all schema changes in ascending version order. Released
+// migrations must never be changed, only new ones appended.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "convert stored events to file records",
+		Apply:       eventsToFileRecords,
+	},
+	{
+		Version:     2,
+		Description: "build catalog indexes",
+		Apply:       catalog.ReindexBatch,
+	},
+}
+
+// eventsToFileRecords rewrites records that were stored as model.Event,
+// recognizable by their capitalized field names, as model.FileRecord.
+func eventsToFileRecords(storage model.DB, batch *model.Batch) error {
+	return storage.Iterate("", func(key string, value []byte) error {
+		if !catalog.IsRecordKey(key) {
+			return nil
+		}
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(value, &fields); err != nil {
+			return nil
+		}
+		if _, isEvent := fields["Path"]; !isEvent {
+			return nil
+		}
+
+		var event model.Event
+		if err := json.Unmarshal(value, &event); err != nil {
+			return err
+		}
+
+		providerName := strings.TrimSuffix(key, ":"+event.Path)
+		data, err := json.Marshal(catalog.FromEvent(providerName, event))
+		if err != nil {
+			return err
+		}
+
+		batch.Set(key, data)
+		return nil
+	})
+}
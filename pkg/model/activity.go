@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+// ActivityType names the kind of an ActivityEvent.
+type ActivityType string
+
+const (
+	ActivityDetected  ActivityType = "file-detected"  // A changed file was detected in a watched directory
+	ActivityStarted   ActivityType = "backup-started" // A provider started backing up a file
+	ActivityProgress  ActivityType = "progress"       // Bytes of a file uploaded so far
+	ActivitySucceeded ActivityType = "succeeded"      // The file was backed up or linked
+	ActivityFailed    ActivityType = "failed"         // The backup failed, was deferred or is inconsistent
+	ActivityPaused    ActivityType = "paused"         // The provider is unhealthy, the backup waits for it to recover
+)
+
+// ActivityEvent reports what the backup manager is doing.
+type ActivityEvent struct {
+	ID          uint64       `json:"id"` // Increasing, used to resume a stream
+	Type        ActivityType `json:"type"`
+	Time        time.Time    `json:"time"`
+	Provider    string       `json:"provider"`
+	Root        string       `json:"root,omitempty"`
+	Path        string       `json:"path"`
+	Transferred int64        `json:"transferred,omitempty"` // Bytes uploaded, for progress events
+	Total       int64        `json:"total,omitempty"`       // Size of the file, for progress events
+	Status      string       `json:"status,omitempty"`      // Result status, for succeeded and failed events
+	Error       string       `json:"error,omitempty"`
+}
+
+// ActivityFilter selects the events a client receives. Empty fields match
+// all events.
+type ActivityFilter struct {
+	Providers []string
+	Roots     []string
+	Types     []ActivityType
+}
+
+// ActivityStream delivers activity events to subscribers.
+type ActivityStream interface {
+	// Subscribe returns the buffered events after lastID that match the
+	// filter, and a channel of new matching events. The channel is closed
+	// when the subscriber falls behind or cancel is called.
+	Subscribe(filter ActivityFilter, lastID uint64) (replay []ActivityEvent, events <-chan ActivityEvent, cancel func())
+}
@@ -0,0 +1,31 @@
+package model
+
+// APIRole defines what a client of the HTTP API may do.
+type APIRole string
+
+const (
+	RoleRead  APIRole = "read"  // Only GET requests
+	RoleAdmin APIRole = "admin" // All requests, including configuration changes
+)
+
+// APIConfig secures the HTTP API. Without tokens and users the API is open
+// to everyone who can connect to it.
+type APIConfig struct {
+	Tokens         []APIToken `json:"tokens,omitempty"`         // Sent as "Authorization: Bearer <token>"
+	Users          []APIUser  `json:"users,omitempty"`          // Sent with HTTP basic auth
+	AllowedOrigins []string   `json:"allowedOrigins,omitempty"` // Origins allowed to make cross-origin requests, "*" for all
+}
+
+// APIToken grants a role to clients presenting the token.
+type APIToken struct {
+	Name  string  `json:"name"`
+	Token string  `json:"token"` // The token or a secret reference like "env:NAME"
+	Role  APIRole `json:"role"`
+}
+
+// APIUser grants a role to a user logging in with basic auth.
+type APIUser struct {
+	Username     string  `json:"username"`
+	PasswordHash string  `json:"passwordHash"` // bcrypt hash, e.g. from "htpasswd -nB user"
+	Role         APIRole `json:"role"`
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrInvalidQuery is returned for file queries that cannot be run, such as
+// queries with an unknown sort field or a malformed cursor.
+var ErrInvalidQuery = errors.New("invalid query")
+
+// FileQuery filters, sorts and pages backed up file records. Zero values
+// disable a filter.
+type FileQuery struct {
+	Provider       string    `json:"provider,omitempty"`
+	Root           string    `json:"root,omitempty"`
+	PathPrefix     string    `json:"pathPrefix,omitempty"`
+	Checksum       string    `json:"checksum,omitempty"`
+	Extension      string    `json:"extension,omitempty"` // With or without the leading dot, case-insensitive
+	MinSize        int64     `json:"minSize,omitempty"`
+	MaxSize        int64     `json:"maxSize,omitempty"`
+	ModifiedAfter  time.Time `json:"modifiedAfter,omitempty"`
+	ModifiedBefore time.Time `json:"modifiedBefore,omitempty"`
+	Sort           string    `json:"sort,omitempty"` // "path" (default), "size" or "modified"
+	Descending     bool      `json:"descending,omitempty"`
+	Limit          int       `json:"limit,omitempty"`
+	Cursor         string    `json:"cursor,omitempty"` // NextCursor of the previous page
+}
+
+// FileQueryResult is a page of file records.
+type FileQueryResult struct {
+	Files      []FileRecord `json:"files"`
+	NextCursor string       `json:"nextCursor,omitempty"`
+}
+
+// FileSearcher searches the catalog of backed up files.
+type FileSearcher interface {
+	SearchFiles(query FileQuery) (*FileQueryResult, error)
+}
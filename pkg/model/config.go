@@ -1,13 +1,27 @@
 package model
 
+import (
+	"context"
+	"errors"
+	"time"
+)
+
 // ConfigManager defines the interface for managing configurations.
 type ConfigManager interface {
 	SaveConfig(config *BackupConfig) error
 	LoadConfig() (*BackupConfig, error)
+	// OnChange registers a function called with every newly saved configuration.
+	OnChange(fn func(config *BackupConfig))
+	// Watch reloads the config file on changes until ctx is done, if the
+	// configuration is managed by the file.
+	Watch(ctx context.Context) error
 }
 
 type BackupConfig struct {
-	Providers []ProviderConfig `json:"providers"`
+	Providers   []ProviderConfig            `json:"providers"`
+	Directories map[string]DirectoryOptions `json:"directories,omitempty"` // Per-directory options keyed by root path
+	Replication []ReplicationRule           `json:"replication,omitempty"` // Required number of copies per directory
+	API         *APIConfig                  `json:"api,omitempty"`         // Authentication and CORS of the HTTP API
 }
 
 type ProviderConfig struct {
@@ -15,4 +29,56 @@ type ProviderConfig struct {
 	Type          string            `json:"type"`     // e.g., "Echo", "AWS"
 	Settings      map[string]string `json:"settings"` // Provider-specific settings like access keys
 	DirectoryList []string          `json:"directoryList"`
+	Throttle      *ThrottleConfig   `json:"throttle,omitempty"` // Bandwidth and disk read limits
+	Offsite       bool              `json:"offsite,omitempty"`  // Data is stored away from the machine, counted by replication rules
+	Quota         *QuotaConfig      `json:"quota,omitempty"`    // Storage limits of the provider
+}
+
+// SymlinkPolicy defines how symbolic links inside a watched directory are handled.
+type SymlinkPolicy string
+
+const (
+	SymlinkStore  SymlinkPolicy = "store"  // Back up the link itself and record its target
+	SymlinkFollow SymlinkPolicy = "follow" // Back up what the link points to, with loop detection
+	SymlinkSkip   SymlinkPolicy = "skip"   // Ignore symbolic links
+)
+
+// DirectoryOptions holds settings for a single watched root directory.
+type DirectoryOptions struct {
+	SymlinkPolicy SymlinkPolicy `json:"symlinkPolicy,omitempty"` // Defaults to SymlinkStore
+}
+
+// ErrConfigReadOnly is returned when changing a configuration that is managed
+// by the config file.
+var ErrConfigReadOnly = errors.New("config is managed by the config file")
+
+// ConfigRevision is a configuration as it was saved at some point.
+type ConfigRevision struct {
+	ID      int           `json:"id"`
+	Created time.Time     `json:"created"`
+	Source  string        `json:"source"`            // "file", "api" or "rollback"
+	Comment string        `json:"comment,omitempty"` // e.g. the revision that was rolled back to
+	Config  *BackupConfig `json:"config,omitempty"`
+
+	// RestartRequired lists the changed fields that only take effect after
+	// the service is restarted, such as providers and their directories
+	RestartRequired []string `json:"restartRequired,omitempty"`
+}
+
+// ConfigChange is a single difference between two configurations.
+type ConfigChange struct {
+	Path string `json:"path"` // e.g. "providers[0].settings.path"
+	Old  any    `json:"old,omitempty"`
+	New  any    `json:"new,omitempty"`
+}
+
+// ConfigHistory gives access to earlier revisions of the configuration.
+type ConfigHistory interface {
+	// Revisions lists all stored revisions, newest first, without their configuration.
+	Revisions() ([]ConfigRevision, error)
+	Revision(id int) (*ConfigRevision, error)
+	// DiffRevisions compares two revisions, a revision of 0 is the current configuration.
+	DiffRevisions(from, to int) ([]ConfigChange, error)
+	// Rollback saves the configuration of a revision as a new revision.
+	Rollback(id int) (*ConfigRevision, error)
 }
@@ -2,14 +2,68 @@ package model
 
 import "errors"
 
+// Txn abstracts the reads and writes available both directly on the
+// database and inside a transaction.
+type Txn interface {
+	Get(key string) ([]byte, error)
+	Set(key string, value []byte) error
+	Delete(key string) error
+	// Iterate calls fn for every key with the given prefix in key order.
+	// Returning an error from fn stops the iteration and returns that error.
+	// fn may write through the same Txn or DB it iterates; whether keys
+	// written meanwhile are visited is undefined.
+	Iterate(prefix string, fn func(key string, value []byte) error) error
+	// IterateFrom is like Iterate, but starts at the first key with the
+	// prefix that is not less than start.
+	IterateFrom(prefix, start string, fn func(key string, value []byte) error) error
+}
+
 // DB is an interface for abstracting database operations.
 //
 //go:generate go run github.com/vektra/mockery/v2@v2 --name=DB --filename=db.go --output=../../mocks/
 type DB interface {
-	Get(key string) ([]byte, error)
-	Set(key string, value []byte) error
+	Txn
+	// WriteBatch applies many writes efficiently. Unlike Update, a failed
+	// batch may have been applied partially.
+	WriteBatch(batch *Batch) error
+	// View runs fn in a read-only transaction. fn must only use txn, calling
+	// the DB from inside a transaction may deadlock.
+	View(fn func(txn Txn) error) error
+	// Update runs fn in a read-write transaction that is committed if fn
+	// returns nil and discarded otherwise. Like View, fn must only use txn.
+	Update(fn func(txn Txn) error) error
 	Close() error
 }
 
 // ErrDBKeyNotFound is used when a key is not found in the database.
 var ErrDBKeyNotFound = errors.New("key not found in the database")
+
+// BatchOp is a single write of a Batch, a nil Value deletes the key.
+type BatchOp struct {
+	Key   string
+	Value []byte
+}
+
+// Batch collects writes to be applied by DB.WriteBatch.
+type Batch struct {
+	Ops []BatchOp
+}
+
+// Set adds a write of the key to the batch.
+func (b *Batch) Set(key string, value []byte) {
+	if value == nil {
+		value = []byte{}
+	}
+	b.Ops = append(b.Ops, BatchOp{Key: key, Value: value})
+}
+
+// Delete adds a removal of the key to the batch.
+func (b *Batch) Delete(key string) {
+	b.Ops = append(b.Ops, BatchOp{Key: key})
+}
+
+// SizedDB is implemented by databases that can report their size.
+type SizedDB interface {
+	// Size returns the bytes used by the database, on disk if it is persistent.
+	Size() (int64, error)
+}
@@ -7,12 +7,23 @@ type Subscriber interface {
 	HandleEvent(Event)
 }
 
+// Kinds of file system entries reported in an Event.
+const (
+	KindFile    = "file"
+	KindSymlink = "symlink"
+)
+
 // Event represents a file system event.
 type Event struct {
-	Root      string
-	Path      string    // Path of the file/directory
-	Type      string    // Type of event: "added", "changed", "deleted", "renamed"
-	Timestamp time.Time // Time of the event
-	Checksum  string    // SHA256 checksum of the file
-	Size      int64     // Size of the file in bytes
+	Root         string
+	Path         string        // Path of the file/directory
+	Type         string        // Type of event: "added", "changed", "deleted", "renamed"
+	Timestamp    time.Time     // Time of the event
+	Checksum     string        // SHA256 checksum of the file
+	Size         int64         // Size of the file in bytes
+	Kind         string        // Kind of the entry: "file" or "symlink"
+	LinkTarget   string        // Target of the link when Kind is "symlink"
+	HardlinkOf   string        // Path of an already seen file sharing the same inode
+	Metadata     *FileMetadata // POSIX metadata captured together with the checksum
+	Inconsistent bool          // Set when the file kept changing while it was backed up
 }
@@ -10,5 +10,10 @@ type FileRecord struct {
 	Checksum     string            `json:"checksum"`
 	Provider     string            `json:"provider"`
 	Size         int64             `json:"size"`
+	Kind         string            `json:"kind,omitempty"`
+	LinkTarget   string            `json:"link_target,omitempty"`
+	HardlinkOf   string            `json:"hardlink_of,omitempty"`
+	Metadata     *FileMetadata     `json:"metadata,omitempty"`
+	Inconsistent bool              `json:"inconsistent,omitempty"`
 	ProviderData map[string]string `json:"provider_data"`
 }
@@ -0,0 +1,108 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+// Capabilities describes what a provider supports.
+type Capabilities struct {
+	Delete         bool  `json:"delete"`                  // Stored files can be removed
+	Versioning     bool  `json:"versioning"`              // Older versions of a file are kept
+	ServerChecksum bool  `json:"serverChecksum"`          // Checksums can be read without downloading
+	MaxObjectSize  int64 `json:"maxObjectSize,omitempty"` // Largest file that can be stored, 0 if unlimited
+	FreeSpace      bool  `json:"freeSpace"`               // Free space can be queried
+	Restore        bool  `json:"restore"`                 // Implements RestoreProvider
+	Stream         bool  `json:"stream"`                  // Implements StreamProvider
+	List           bool  `json:"list"`                    // Implements ListProvider
+}
+
+// HealthProvider is implemented by providers that can describe their
+// capabilities and check that they are reachable.
+type HealthProvider interface {
+	Capabilities() Capabilities
+	// Ping checks that the destination is reachable and the credentials valid.
+	Ping(ctx context.Context) error
+}
+
+// SpaceProvider is implemented by providers that know their free space.
+type SpaceProvider interface {
+	FreeSpace() (uint64, error)
+}
+
+// ProviderHealth is the result of the last health check of a provider.
+type ProviderHealth struct {
+	Healthy      bool         `json:"healthy"`
+	Error        string       `json:"error,omitempty"`
+	LastCheck    time.Time    `json:"lastCheck"`
+	Capabilities Capabilities `json:"capabilities"`
+	FreeSpace    *uint64      `json:"freeSpace,omitempty"` // Bytes available, if known
+}
+
+// HealthReporter returns the health of all providers.
+type HealthReporter interface {
+	Health() map[string]ProviderHealth
+}
+
+// WatcherStatus describes the file system watcher.
+type WatcherStatus struct {
+	Running       bool       `json:"running"`
+	Scanned       bool       `json:"scanned"` // The files found in the watched directories were hashed and passed on
+	Directories   int        `json:"directories"`
+	LastError     string     `json:"lastError,omitempty"`
+	LastErrorTime *time.Time `json:"lastErrorTime,omitempty"`
+}
+
+// WatcherReporter returns the status of the file system watcher.
+type WatcherReporter interface {
+	WatcherStatus() WatcherStatus
+}
+
+// HealthStatus is the state of the service or one of its components.
+type HealthStatus string
+
+const (
+	HealthOK       HealthStatus = "ok"
+	HealthDegraded HealthStatus = "degraded" // Working, but not completely, e.g. a provider is unreachable
+	HealthDown     HealthStatus = "down"     // Not working
+)
+
+// ComponentHealth is the state of a component of the service.
+type ComponentHealth struct {
+	Status  HealthStatus `json:"status"`
+	Class   string       `json:"class,omitempty"` // Kind of problem, e.g. "unreachable"
+	Error   string       `json:"error,omitempty"`
+	Details any          `json:"details,omitempty"`
+}
+
+// ServiceHealth is the state of the service, the worst of its components.
+type ServiceHealth struct {
+	Status     HealthStatus               `json:"status"`
+	Components map[string]ComponentHealth `json:"components"`
+	Providers  map[string]ComponentHealth `json:"providers,omitempty"`
+}
+
+// Public returns the health without error messages and details, which may
+// hold local paths and provider errors, for clients without credentials.
+func (h ServiceHealth) Public() ServiceHealth {
+	return ServiceHealth{Status: h.Status, Components: publicComponents(h.Components), Providers: publicComponents(h.Providers)}
+}
+
+func publicComponents(components map[string]ComponentHealth) map[string]ComponentHealth {
+	if components == nil {
+		return nil
+	}
+	public := make(map[string]ComponentHealth, len(components))
+	for name, component := range components {
+		public[name] = ComponentHealth{Status: component.Status, Class: component.Class}
+	}
+	return public
+}
+
+// ServiceHealthReporter checks whether the service is alive, i.e. watching
+// files and storing the catalog, and ready, i.e. also done with the initial
+// scan and able to reach every provider.
+type ServiceHealthReporter interface {
+	Liveness(ctx context.Context) ServiceHealth
+	Readiness(ctx context.Context) ServiceHealth
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"io"
+	"time"
+)
+
+// FileMetadata holds the POSIX metadata of a file captured at backup time.
+type FileMetadata struct {
+	Mode       uint32            `json:"mode"`                  // Permission and special mode bits
+	UID        int               `json:"uid"`                   // Owner user ID, -1 if unknown
+	GID        int               `json:"gid"`                   // Owner group ID, -1 if unknown
+	ModTime    time.Time         `json:"mod_time"`              // Last modification time
+	AccessTime time.Time         `json:"access_time,omitempty"` // Last access time, if known
+	Xattrs     map[string][]byte `json:"xattrs,omitempty"`      // Extended attributes, without ACLs
+	ACLs       map[string][]byte `json:"acls,omitempty"`        // POSIX ACLs in their xattr encoding
+}
+
+// RestoreProvider is implemented by providers that can return backed up data.
+type RestoreProvider interface {
+	// Stat returns the record stored alongside the content of path.
+	Stat(path string) (*FileRecord, error)
+	// Size returns the size of the stored content of path.
+	Size(path string) (int64, error)
+	// Restore writes the stored content of path to w.
+	Restore(path string, w io.Writer) error
+}
+
+// RestoreRequest describes a single file to restore from a provider.
+type RestoreRequest struct {
+	Provider      string `json:"provider"`
+	Path          string `json:"path"`                    // Original path of the file
+	Target        string `json:"target,omitempty"`        // Where to restore, defaults to Path
+	SkipOwnership bool   `json:"skipOwnership,omitempty"` // Do not restore uid/gid, e.g. when not running as root
+}
+
+// RestoreManager restores files from backup providers.
+type RestoreManager interface {
+	Restore(req RestoreRequest) error
+}
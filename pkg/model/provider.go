@@ -1,8 +1,15 @@
 package model
 
+import (
+	"errors"
+	"io"
+)
+
 // Provider defines the interface for backup providers.
 type Provider interface {
-	Backup(event Event) error
+	// Backup stores the file described by event. r streams its content
+	// through the configured limits and is nil for symbolic links.
+	Backup(event Event, r io.Reader) error
 	DirectoryList() []string
 	Name() string
 }
@@ -12,7 +19,37 @@ type ProviderMetaInfo struct {
 	Directories map[string]uint64 `json:"directories"`
 }
 
+// ProviderInfo is the meta info of a provider together with its health.
+type ProviderInfo struct {
+	*ProviderMetaInfo
+	Health *ProviderHealth `json:"health,omitempty"`
+}
+
 type ProviderMetaInfoGetter interface {
 	GetProviders() ([]string, error)
 	GetMetaInfo(providerName string) (*ProviderMetaInfo, error)
 }
+
+// LinkProvider is implemented by providers that can record a hard link to
+// content they already store instead of uploading it a second time.
+type LinkProvider interface {
+	// Link stores the record of event, whose HardlinkOf names the stored
+	// file sharing its content.
+	Link(event Event) error
+}
+
+// ErrUnknownProvider is returned for operations on a provider that is not
+// configured.
+var ErrUnknownProvider = errors.New("unknown provider")
+
+// CatalogExportObject is the object name of the catalog export.
+const CatalogExportObject = "catalog.jsonl.gz"
+
+// ObjectProvider is implemented by providers that can store objects of
+// Shugosha itself, such as the catalog export. Objects are kept apart from
+// the backed up files and are never returned by List.
+type ObjectProvider interface {
+	// PutObject stores the content of r as the object name, replacing an
+	// object of the same name.
+	PutObject(name string, r io.Reader) error
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// PendingBackups describes the changes of a provider that are detected but
+// not backed up yet.
+type PendingBackups struct {
+	Count  int       `json:"count"`
+	Oldest time.Time `json:"oldest,omitempty"` // Detection time of the oldest pending change
+}
+
+// BackupQueue reports the pending backups of all providers.
+type BackupQueue interface {
+	PendingBackups() map[string]PendingBackups
+}
@@ -0,0 +1,18 @@
+package model
+
+// QuotaAction defines what happens to a backup that would exceed a quota.
+type QuotaAction string
+
+const (
+	QuotaRefuse QuotaAction = "refuse" // Fail the backup
+	QuotaDefer  QuotaAction = "defer"  // Retry the backup later, e.g. after space was freed
+)
+
+// QuotaConfig limits how much a provider may store.
+type QuotaConfig struct {
+	MaxBytes     uint64            `json:"maxBytes,omitempty"`     // Limit over all directories, 0 for none
+	Directories  map[string]uint64 `json:"directories,omitempty"`  // Limits per root directory
+	MinFreeBytes uint64            `json:"minFreeBytes,omitempty"` // Free space to keep at the destination
+	WarnAt       []float64         `json:"warnAt,omitempty"`       // Usage in percent that raises a warning, defaults to 80, 90 and 95
+	Action       QuotaAction       `json:"action,omitempty"`       // Defaults to QuotaRefuse
+}
@@ -0,0 +1,21 @@
+package model
+
+// ListProvider is implemented by providers that can enumerate the files they
+// store, which allows rebuilding the catalog without a surviving database.
+type ListProvider interface {
+	// List calls fn with the stored record of every backed up file.
+	List(fn func(record *FileRecord) error) error
+}
+
+// RebuildResult summarizes a catalog rebuild of a single provider.
+type RebuildResult struct {
+	Provider string `json:"provider"`
+	Files    int    `json:"files"`   // Number of records restored
+	Removed  int    `json:"removed"` // Records that were no longer stored by the provider
+	Size     uint64 `json:"size"`    // Total size of all restored files
+}
+
+// CatalogRebuilder reconstructs the catalog from the data stored by a provider.
+type CatalogRebuilder interface {
+	RebuildCatalog(providerName string) (*RebuildResult, error)
+}
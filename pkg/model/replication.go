@@ -0,0 +1,50 @@
+package model
+
+// ReplicationRule requires every file under Path to be backed up by a number
+// of providers, e.g. two copies with one offsite for a 3-2-1 strategy.
+type ReplicationRule struct {
+	Path       string `json:"path"`                 // Directory the rule applies to, the most specific rule wins
+	MinCopies  int    `json:"minCopies"`            // Providers that must hold the current version
+	MinOffsite int    `json:"minOffsite,omitempty"` // How many of them must be offsite
+}
+
+// ReplicaStatus lists the providers holding a file.
+type ReplicaStatus struct {
+	Path     string   `json:"path"`
+	Checksum string   `json:"checksum"`        // Checksum of the newest backed up version
+	Current  []string `json:"current"`         // Providers holding the newest version
+	Stale    []string `json:"stale,omitempty"` // Providers holding an older or inconsistent version
+}
+
+// ReplicaTracker reports which providers hold each backed up file.
+type ReplicaTracker interface {
+	Replicas() ([]ReplicaStatus, error)
+}
+
+// RuleStatus tells whether the configured providers can satisfy a rule.
+type RuleStatus struct {
+	Rule        ReplicationRule `json:"rule"`
+	Providers   []string        `json:"providers"` // Providers backing up the whole directory
+	Offsite     []string        `json:"offsite"`   // Offsite providers among them
+	Satisfiable bool            `json:"satisfiable"`
+}
+
+// ComplianceIssue is a file that does not meet its replication rule.
+type ComplianceIssue struct {
+	ReplicaStatus
+	Rule    string `json:"rule"`    // Path of the rule that applies
+	Copies  int    `json:"copies"`  // Current copies on configured providers
+	Offsite int    `json:"offsite"` // Current copies on offsite providers
+}
+
+// ComplianceReport lists the files that do not meet the replication rules.
+type ComplianceReport struct {
+	Rules   []RuleStatus      `json:"rules"`
+	Checked int               `json:"checked"` // Files covered by a rule
+	Issues  []ComplianceIssue `json:"issues"`
+}
+
+// ComplianceChecker checks the backed up files against the replication rules.
+type ComplianceChecker interface {
+	Compliance() (*ComplianceReport, error)
+}
@@ -0,0 +1,14 @@
+package model
+
+// SecretStore holds named secrets that provider settings can refer to as
+// "keystore:NAME". Secret values are never returned by the API.
+type SecretStore interface {
+	Names() []string
+	Set(name, secret string) error
+	Delete(name string) error
+}
+
+// SecretValue is the request body of storing a secret.
+type SecretValue struct {
+	Value string `json:"value"`
+}
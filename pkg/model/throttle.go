@@ -0,0 +1,32 @@
+package model
+
+// ThrottleConfig limits how fast a provider uploads and reads from disk.
+// A limit of 0 means unlimited.
+type ThrottleConfig struct {
+	UploadBytesPerSec int64            `json:"uploadBytesPerSec,omitempty"`
+	ReadBytesPerSec   int64            `json:"readBytesPerSec,omitempty"`
+	Schedule          []ThrottleWindow `json:"schedule,omitempty"` // Time windows overriding the limits above
+}
+
+// ThrottleWindow sets different limits for a time of day, e.g. 09:00-18:00.
+// Windows where End is before Start wrap around midnight. A limit of 0 keeps
+// the limit of the ThrottleConfig.
+type ThrottleWindow struct {
+	Start             string `json:"start"` // Local time as "15:04"
+	End               string `json:"end"`   // Local time as "15:04"
+	UploadBytesPerSec int64  `json:"uploadBytesPerSec,omitempty"`
+	ReadBytesPerSec   int64  `json:"readBytesPerSec,omitempty"`
+}
+
+// ThrottleStatus reports the configured and currently effective limits.
+type ThrottleStatus struct {
+	Config            ThrottleConfig `json:"config"`
+	UploadBytesPerSec int64          `json:"uploadBytesPerSec"`
+	ReadBytesPerSec   int64          `json:"readBytesPerSec"`
+}
+
+// ThrottleController allows inspecting and changing limits at runtime.
+type ThrottleController interface {
+	Throttles() map[string]ThrottleStatus
+	SetThrottle(provider string, cfg ThrottleConfig) error
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// ProgressFunc is called with the number of bytes of a file transferred so far.
+type ProgressFunc func(provider, path string, transferred, total int64)
+
+// UploadSession holds the state of a resumable upload.
+type UploadSession struct {
+	Provider string            `json:"provider"`
+	Path     string            `json:"path"`
+	Checksum string            `json:"checksum"` // Checksum of the file when the upload started
+	Size     int64             `json:"size"`
+	ModTime  time.Time         `json:"mod_time"`
+	Offset   int64             `json:"offset"` // Bytes durably stored by the provider
+	Started  time.Time         `json:"started"`
+	Data     map[string]string `json:"data,omitempty"` // Provider-specific state, e.g. a remote upload ID
+}
+
+// UploadCheckpoint persists the session after the provider stored more data.
+type UploadCheckpoint func(session *UploadSession) error
+
+// StreamProvider is implemented by providers that accept file content as a
+// stream and can resume interrupted uploads.
+type StreamProvider interface {
+	Provider
+	// UploadOffset returns how many bytes of the session the provider still
+	// holds; 0 means the upload has to start over.
+	UploadOffset(session *UploadSession) (int64, error)
+	// BackupStream stores the content read from r, which starts at
+	// session.Offset. The provider advances session.Offset and calls
+	// checkpoint whenever data has been durably stored.
+	BackupStream(ctx context.Context, event Event, session *UploadSession, r io.Reader, checkpoint UploadCheckpoint) error
+}
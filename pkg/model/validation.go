@@ -0,0 +1,47 @@
+package model
+
+import "strings"
+
+// FieldError describes an invalid configuration value.
+type FieldError struct {
+	Field   string `json:"field"` // Path of the value, e.g. "providers[0].settings.path"
+	Message string `json:"message"`
+}
+
+// ValidationErrors lists all problems found in a configuration.
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldError := range e {
+		messages[i] = fieldError.Field + ": " + fieldError.Message
+	}
+	return "invalid config: " + strings.Join(messages, "; ")
+}
+
+// SettingSpec describes a single setting of a provider type.
+type SettingSpec struct {
+	Description string `json:"description"`
+	Required    bool   `json:"required,omitempty"`
+	Secret      bool   `json:"secret,omitempty"` // Redacted in API responses and logs
+}
+
+// SettingsSchema lists the settings a provider type accepts.
+type SettingsSchema map[string]SettingSpec
+
+// ConfigValidator checks configurations before they are used.
+type ConfigValidator interface {
+	// ValidateConfig returns ValidationErrors if the configuration is invalid.
+	ValidateConfig(config *BackupConfig) error
+	// ConfigSchema returns the JSON Schema of the configuration.
+	ConfigSchema() map[string]any
+}
+
+// ConfigRedactor hides credentials in configurations returned by the API.
+type ConfigRedactor interface {
+	RedactConfig(config *BackupConfig) *BackupConfig
+	RedactRevision(revision *ConfigRevision) *ConfigRevision
+	RedactChanges(changes []ConfigChange) []ConfigChange
+	// RestoreRedacted replaces redacted values in config with those of current.
+	RestoreRedacted(config, current *BackupConfig)
+}
@@ -0,0 +1,62 @@
+package model
+
+import "time"
+
+// VerifyMode selects how thoroughly stored files are verified.
+type VerifyMode string
+
+const (
+	// VerifyQuick compares the size and checksum stored by the provider and
+	// the size of the stored content.
+	VerifyQuick VerifyMode = "quick"
+	// VerifyFull downloads the content and hashes it again.
+	VerifyFull VerifyMode = "full"
+)
+
+// Problems found by a verification.
+const (
+	VerifyMissing  = "missing"  // The provider does not have the file
+	VerifySize     = "size"     // The stored size differs from the catalog
+	VerifyChecksum = "checksum" // The stored content differs from the catalog
+	VerifyError    = "error"    // The file could not be checked
+)
+
+// VerifyRequest starts the verification of the files stored by a provider.
+type VerifyRequest struct {
+	Provider string     `json:"provider"`
+	Mode     VerifyMode `json:"mode,omitempty"`    // Defaults to quick
+	Percent  float64    `json:"percent,omitempty"` // Share of files downloaded in full mode, defaults to 100
+	Repair   bool       `json:"repair,omitempty"`  // Upload the local file again if it still matches the catalog
+}
+
+// VerifyIssue is a file whose stored copy does not match the catalog.
+type VerifyIssue struct {
+	Path     string `json:"path"`
+	Problem  string `json:"problem"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Repaired bool   `json:"repaired,omitempty"`
+}
+
+// VerifyReport is the state and result of a verification.
+type VerifyReport struct {
+	Provider string        `json:"provider"`
+	Mode     VerifyMode    `json:"mode"`
+	Percent  float64       `json:"percent"`
+	Running  bool          `json:"running"`
+	Started  time.Time     `json:"started"`
+	Finished time.Time     `json:"finished,omitempty"`
+	Checked  int           `json:"checked"`
+	Skipped  int           `json:"skipped"` // Files not sampled in full mode, only checked quickly
+	Issues   []VerifyIssue `json:"issues"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Verifier checks that backed up files are still intact at the provider.
+type Verifier interface {
+	// StartVerify starts a verification in the background.
+	StartVerify(req VerifyRequest) (*VerifyReport, error)
+	// VerifyReports returns the latest verification of every provider.
+	VerifyReports() map[string]VerifyReport
+}
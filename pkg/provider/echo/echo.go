@@ -1,31 +1,57 @@
 package echo
 
 import (
+	"context"
 	"fmt"
+	"io"
 
 	"github.com/sevigo/shugosha/pkg/model"
 )
 
+// Settings is empty, Echo has nothing to configure.
+var Settings = model.SettingsSchema{}
+
 // Provider is a simple backup provider that logs file changes.
 type provider struct {
+	name          string
 	directoryList []string
 }
 
-// NewEchoProvider creates a new EchoProvider.
+// NewEchoProvider creates a new EchoProvider named like its configuration.
 func NewEchoProvider(providerConfig *model.ProviderConfig) (model.Provider, error) {
+	name := providerConfig.Name
+	if name == "" {
+		name = "Echo"
+	}
+
 	return &provider{
+		name:          name,
 		directoryList: providerConfig.DirectoryList,
 	}, nil
 }
 
 // Backup logs the file change event.
-func (p *provider) Backup(event model.Event) error {
-	fmt.Printf("[Echo] Backing up - %q\n", event.Path)
+func (p *provider) Backup(event model.Event, _ io.Reader) error {
+	if event.Kind == model.KindSymlink {
+		fmt.Printf("[%s] Backing up link - %q -> %q\n", p.name, event.Path, event.LinkTarget)
+		return nil
+	}
+	fmt.Printf("[%s] Backing up - %q\n", p.name, event.Path)
+	return nil
+}
+
+// Capabilities returns no capabilities, Echo does not store anything.
+func (p *provider) Capabilities() model.Capabilities {
+	return model.Capabilities{}
+}
+
+// Ping always succeeds.
+func (p *provider) Ping(ctx context.Context) error {
 	return nil
 }
 
 func (p *provider) Name() string {
-	return "Echo"
+	return p.name
 }
 
 func (p *provider) DirectoryList() []string {
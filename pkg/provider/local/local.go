@@ -0,0 +1,336 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sevigo/shugosha/pkg/catalog"
+	"github.com/sevigo/shugosha/pkg/metrics"
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+const (
+	dataDir   = "data"    // Holds the file contents, mirroring the original paths
+	metaDir   = "meta"    // Holds a JSON file record for every stored file
+	objectDir = "objects" // Holds the objects of Shugosha itself
+	tmpDir    = "tmp"     // Holds files being written, before they are moved into place
+
+	partialSuffix = ".partial" // Suffix of files with an upload in progress
+	chunkSize     = 8 << 20    // Bytes written between two checkpoints
+)
+
+// Settings describes the settings of the local provider.
+var Settings = model.SettingsSchema{
+	"path": {Description: "Directory the backups are written to", Required: true},
+}
+
+// provider stores backups in a directory on a local or mounted file system.
+type provider struct {
+	name          string
+	path          string
+	directoryList []string
+}
+
+// Ensure provider supports restoring files and resumable uploads
+var (
+	_ model.RestoreProvider = (*provider)(nil)
+	_ model.StreamProvider  = (*provider)(nil)
+	_ model.ListProvider    = (*provider)(nil)
+	_ model.HealthProvider  = (*provider)(nil)
+	_ model.SpaceProvider   = (*provider)(nil)
+	_ model.LinkProvider    = (*provider)(nil)
+	_ model.ObjectProvider  = (*provider)(nil)
+)
+
+// NewLocalProvider creates a new provider writing to the directory given by
+// the "path" setting.
+func NewLocalProvider(providerConfig *model.ProviderConfig) (model.Provider, error) {
+	path := providerConfig.Settings["path"]
+	if path == "" {
+		return nil, fmt.Errorf("local provider requires the %q setting", "path")
+	}
+
+	name := providerConfig.Name
+	if name == "" {
+		name = "Local"
+	}
+
+	return &provider{
+		name:          name,
+		path:          path,
+		directoryList: providerConfig.DirectoryList,
+	}, nil
+}
+
+// Backup copies the file content and its metadata into the destination
+// directory.
+func (p *provider) Backup(event model.Event, r io.Reader) error {
+	if event.Kind != model.KindSymlink {
+		if err := p.copyFile(event.Path, r); err != nil {
+			return err
+		}
+	}
+
+	return p.writeRecord(catalog.FromEvent(p.name, event))
+}
+
+// Link stores only the record of a hard link; its content is restored from
+// the file named by HardlinkOf.
+func (p *provider) Link(event model.Event) error {
+	return p.writeRecord(catalog.FromEvent(p.name, event))
+}
+
+// PutObject stores the object in the objects directory, which List does not
+// read.
+func (p *provider) PutObject(name string, r io.Reader) error {
+	if name != filepath.Base(name) {
+		return fmt.Errorf("invalid object name %q", name)
+	}
+
+	counter := &countingReader{r: r}
+	err := p.writeObject(filepath.Join(p.path, objectDir, name), counter)
+	metrics.UploadedBytes.WithLabelValues(p.name).Add(float64(counter.n))
+	return err
+}
+
+// UploadOffset returns the size of the partial file of the session.
+func (p *provider) UploadOffset(session *model.UploadSession) (int64, error) {
+	info, err := os.Stat(p.objectPath(dataDir, session.Path) + partialSuffix)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// BackupStream appends r to the partial file of the session, syncing and
+// checkpointing after every chunk, and moves it into place once complete.
+func (p *provider) BackupStream(ctx context.Context, event model.Event, session *model.UploadSession, r io.Reader, checkpoint model.UploadCheckpoint) error {
+	dest := p.objectPath(dataDir, event.Path)
+	if err := os.MkdirAll(filepath.Dir(dest), 0o700); err != nil {
+		return err
+	}
+
+	partial, err := os.OpenFile(dest+partialSuffix, os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer partial.Close()
+
+	// Drop anything written after the last checkpoint
+	if err := partial.Truncate(session.Offset); err != nil {
+		return err
+	}
+	if _, err := partial.Seek(session.Offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n, err := io.CopyN(partial, r, chunkSize)
+		metrics.UploadedBytes.WithLabelValues(p.name).Add(float64(n))
+		if n > 0 {
+			if err := partial.Sync(); err != nil {
+				return err
+			}
+			session.Offset += n
+			if err := checkpoint(session); err != nil {
+				return fmt.Errorf("failed to save upload checkpoint: %w", err)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := partial.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(dest+partialSuffix, dest); err != nil {
+		return err
+	}
+
+	return p.writeRecord(catalog.FromEvent(p.name, event))
+}
+
+// Stat returns the file record stored with the backed up file.
+func (p *provider) Stat(path string) (*model.FileRecord, error) {
+	data, err := os.ReadFile(p.objectPath(metaDir, path) + ".json")
+	if err != nil {
+		return nil, err
+	}
+
+	var record model.FileRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal file record: %w", err)
+	}
+
+	return &record, nil
+}
+
+// Size returns the size of the stored content of path.
+func (p *provider) Size(path string) (int64, error) {
+	info, err := os.Stat(p.objectPath(dataDir, path))
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// Restore writes the backed up content of path to w.
+func (p *provider) Restore(path string, w io.Writer) error {
+	file, err := os.Open(p.objectPath(dataDir, path))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(w, file)
+	return err
+}
+
+// List reads the records of all stored files from the meta directory.
+// Unreadable records are logged and skipped.
+func (p *provider) List(fn func(record *model.FileRecord) error) error {
+	root := filepath.Join(p.path, metaDir)
+
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && path == root {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(path, ".json") {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		var record model.FileRecord
+		if err := json.Unmarshal(data, &record); err != nil || record.Path == "" {
+			slog.Warn("[Local] skipping unreadable file record", "file", path, "error", err)
+			return nil
+		}
+
+		record.Provider = p.name
+		return fn(&record)
+	})
+}
+
+// Capabilities describes the local provider; stored records hold the checksum.
+func (p *provider) Capabilities() model.Capabilities {
+	return model.Capabilities{
+		ServerChecksum: true,
+		FreeSpace:      true,
+	}
+}
+
+// Ping checks that the destination directory exists or can be created and
+// is writable.
+func (p *provider) Ping(ctx context.Context) error {
+	if err := os.MkdirAll(p.path, 0o700); err != nil {
+		return err
+	}
+
+	probe, err := os.CreateTemp(p.path, ".shugosha-ping-*")
+	if err != nil {
+		return fmt.Errorf("destination is not writable: %w", err)
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+func (p *provider) Name() string {
+	return p.name
+}
+
+func (p *provider) DirectoryList() []string {
+	return p.directoryList
+}
+
+// copyFile copies the content of path into the data directory through a
+// temporary file, so an interrupted copy never replaces a complete one.
+func (p *provider) copyFile(path string, r io.Reader) error {
+	counter := &countingReader{r: r}
+	err := p.writeObject(p.objectPath(dataDir, path), counter)
+	metrics.UploadedBytes.WithLabelValues(p.name).Add(float64(counter.n))
+	return err
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.n += int64(n)
+	return n, err
+}
+
+func (p *provider) writeRecord(record *model.FileRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal file record: %w", err)
+	}
+
+	return p.writeObject(p.objectPath(metaDir, record.Path)+".json", bytes.NewReader(data))
+}
+
+// writeObject writes r to dest through a temporary file in the tmp directory,
+// so no other directory ever holds a partly written file.
+func (p *provider) writeObject(dest string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o700); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(p.path, tmpDir), 0o700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Join(p.path, tmpDir), "write-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), dest)
+}
+
+// objectPath maps an original file path into the given area of the destination.
+func (p *provider) objectPath(area, path string) string {
+	volume := filepath.VolumeName(path)
+	rest := path[len(volume):]
+	return filepath.Join(p.path, area, sanitizeVolume(volume), rest)
+}
+
+// sanitizeVolume turns a volume name such as "C:" or "\\server\share" into a
+// plain directory name.
+func sanitizeVolume(volume string) string {
+	return strings.NewReplacer(":", "", `\`, "_", "/", "_").Replace(volume)
+}
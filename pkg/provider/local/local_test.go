@@ -0,0 +1,140 @@
+package local
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sevigo/shugosha/pkg/metadata"
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+func newTestProvider(t *testing.T) *provider {
+	t.Helper()
+
+	p, err := NewLocalProvider(&model.ProviderConfig{Name: "Local", Settings: map[string]string{"path": t.TempDir()}})
+	assert.NoError(t, err)
+	return p.(*provider)
+}
+
+// backupFile backs up path with the metadata the monitor would capture.
+func backupFile(t *testing.T, p *provider, path string) model.Event {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	info, err := os.Lstat(path)
+	assert.NoError(t, err)
+	meta, err := metadata.Capture(path, info)
+	assert.NoError(t, err)
+
+	event := model.Event{
+		Root:     filepath.Dir(path),
+		Path:     path,
+		Kind:     model.KindFile,
+		Checksum: fmt.Sprintf("%x", sha256.Sum256(data)),
+		Size:     int64(len(data)),
+		Metadata: meta,
+	}
+	assert.NoError(t, p.Backup(event, bytes.NewReader(data)))
+	return event
+}
+
+// restoreFile restores path from p into a new file and applies its metadata.
+func restoreFile(t *testing.T, p *provider, path string, opts metadata.ApplyOptions) string {
+	t.Helper()
+
+	record, err := p.Stat(path)
+	assert.NoError(t, err)
+
+	var content bytes.Buffer
+	assert.NoError(t, p.Restore(path, &content))
+	assert.Equal(t, record.Checksum, fmt.Sprintf("%x", sha256.Sum256(content.Bytes())))
+
+	target := filepath.Join(t.TempDir(), filepath.Base(path))
+	assert.NoError(t, os.WriteFile(target, content.Bytes(), 0o600))
+	assert.NoError(t, metadata.Apply(target, record.Metadata, opts))
+	return target
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	p := newTestProvider(t)
+	path := filepath.Join(t.TempDir(), "file.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("content"), 0o640))
+	modTime := time.Date(2023, 12, 24, 18, 0, 0, 0, time.UTC)
+	assert.NoError(t, os.Chtimes(path, modTime, modTime))
+
+	event := backupFile(t, p, path)
+
+	record, err := p.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "Local", record.Provider)
+	assert.Equal(t, event.Checksum, record.Checksum)
+	assert.Equal(t, event.Size, record.Size)
+	assert.Equal(t, event.Metadata.Mode, record.Metadata.Mode)
+
+	target := restoreFile(t, p, path, metadata.ApplyOptions{SkipOwnership: true})
+	data, err := os.ReadFile(target)
+	assert.NoError(t, err)
+	assert.Equal(t, "content", string(data))
+
+	info, err := os.Stat(target)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+	assert.True(t, modTime.Equal(info.ModTime()), "modification time does not match")
+}
+
+func TestBackupReplacesContent(t *testing.T) {
+	p := newTestProvider(t)
+	path := filepath.Join(t.TempDir(), "file.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("first version"), 0o600))
+	backupFile(t, p, path)
+
+	assert.NoError(t, os.WriteFile(path, []byte("second"), 0o600))
+	backupFile(t, p, path)
+
+	var content bytes.Buffer
+	assert.NoError(t, p.Restore(path, &content))
+	assert.Equal(t, "second", content.String())
+}
+
+func TestListAndLink(t *testing.T) {
+	p := newTestProvider(t)
+	dir := t.TempDir()
+	origin := filepath.Join(dir, "origin")
+	assert.NoError(t, os.WriteFile(origin, []byte("shared"), 0o600))
+	event := backupFile(t, p, origin)
+
+	link := event
+	link.Path = filepath.Join(dir, "link")
+	link.HardlinkOf = origin
+	assert.NoError(t, p.Link(link))
+
+	symlink := model.Event{Root: dir, Path: filepath.Join(dir, "symlink"), Kind: model.KindSymlink, LinkTarget: "origin"}
+	assert.NoError(t, p.Backup(symlink, nil))
+
+	// Names like the temporary files of older versions are ordinary files
+	named := filepath.Join(dir, ".shugosha-notes")
+	assert.NoError(t, os.WriteFile(named, []byte("notes"), 0o600))
+	backupFile(t, p, named)
+
+	records := map[string]*model.FileRecord{}
+	assert.NoError(t, p.List(func(record *model.FileRecord) error {
+		records[record.Path] = record
+		return nil
+	}))
+	assert.Len(t, records, 4)
+	assert.Contains(t, records, named)
+	assert.Equal(t, origin, records[link.Path].HardlinkOf)
+	assert.Equal(t, "origin", records[symlink.Path].LinkTarget)
+
+	// Only the origin has stored content
+	_, err := os.Stat(p.objectPath(dataDir, link.Path))
+	assert.True(t, os.IsNotExist(err))
+}
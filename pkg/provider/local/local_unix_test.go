@@ -0,0 +1,56 @@
+//go:build linux || darwin
+
+package local
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+
+	"github.com/sevigo/shugosha/pkg/metadata"
+)
+
+func TestRestoreXattrs(t *testing.T) {
+	p := newTestProvider(t)
+	path := filepath.Join(t.TempDir(), "file.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("content"), 0o600))
+
+	err := unix.Setxattr(path, "user.shugosha.test", []byte("value"), 0)
+	if errors.Is(err, unix.ENOTSUP) || errors.Is(err, unix.EPERM) {
+		t.Skip("extended attributes are not supported here")
+	}
+	assert.NoError(t, err)
+
+	backupFile(t, p, path)
+	target := restoreFile(t, p, path, metadata.ApplyOptions{SkipOwnership: true})
+
+	value := make([]byte, 64)
+	n, err := unix.Getxattr(target, "user.shugosha.test", value)
+	assert.NoError(t, err)
+	assert.Equal(t, "value", string(value[:n]))
+}
+
+func TestRestoreOwnership(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing ownership requires root")
+	}
+
+	p := newTestProvider(t)
+	path := filepath.Join(t.TempDir(), "file.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("content"), 0o600))
+	assert.NoError(t, os.Chown(path, 1234, 5678))
+
+	backupFile(t, p, path)
+	target := restoreFile(t, p, path, metadata.ApplyOptions{})
+
+	info, err := os.Stat(target)
+	assert.NoError(t, err)
+	stat := info.Sys().(*syscall.Stat_t)
+	assert.Equal(t, uint32(1234), uint32(stat.Uid))
+	assert.Equal(t, uint32(5678), uint32(stat.Gid))
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package local
+
+import "golang.org/x/sys/unix"
+
+// FreeSpace returns the bytes available to unprivileged users at the destination.
+func (p *provider) FreeSpace() (uint64, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(p.path, &stat); err != nil {
+		return 0, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
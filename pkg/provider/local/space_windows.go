@@ -0,0 +1,19 @@
+//go:build windows
+
+package local
+
+import "golang.org/x/sys/windows"
+
+// FreeSpace returns the bytes available to the current user at the destination.
+func (p *provider) FreeSpace() (uint64, error) {
+	path, err := windows.UTF16PtrFromString(p.path)
+	if err != nil {
+		return 0, err
+	}
+
+	var free uint64
+	if err := windows.GetDiskFreeSpaceEx(path, &free, nil, nil); err != nil {
+		return 0, err
+	}
+	return free, nil
+}
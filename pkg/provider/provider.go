@@ -5,30 +5,62 @@ import (
 	"log/slog"
 
 	"github.com/sevigo/shugosha/pkg/fsmonitor"
+	"github.com/sevigo/shugosha/pkg/health"
 	"github.com/sevigo/shugosha/pkg/model"
 	"github.com/sevigo/shugosha/pkg/provider/echo"
+	"github.com/sevigo/shugosha/pkg/provider/local"
+	"github.com/sevigo/shugosha/pkg/secrets"
 )
 
-// NewProvider creates a new provider based on the given config.
-func NewProvider(providerConf *model.ProviderConfig) (model.Provider, error) {
-	switch providerConf.Type {
-	case "Echo":
-		return echo.NewEchoProvider(providerConf)
+// providerType creates providers of one type and describes their settings.
+type providerType struct {
+	create   func(*model.ProviderConfig) (model.Provider, error)
+	settings model.SettingsSchema
+}
 
-	case "AWS":
-		return nil, fmt.Errorf("not implemented now")
+// types holds all provider types that can be configured.
+var types = map[string]providerType{
+	"Echo":  {create: echo.NewEchoProvider, settings: echo.Settings},
+	"Local": {create: local.NewLocalProvider, settings: local.Settings},
+}
 
-	default:
+// NewProvider creates a new provider based on the given config. Secret
+// references in the settings are resolved by resolver first, the config
+// itself keeps the references.
+func NewProvider(providerConf *model.ProviderConfig, resolver *secrets.Resolver) (model.Provider, error) {
+	providerType, ok := types[providerConf.Type]
+	if !ok {
 		slog.Info("Unknown provider", "type", providerConf.Type)
 		return nil, fmt.Errorf("unknown provider")
 	}
+
+	settings, err := resolver.ResolveSettings(providerConf.Settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve secrets: %w", err)
+	}
+
+	resolved := *providerConf
+	resolved.Settings = settings
+	return providerType.create(&resolved)
+}
+
+// SettingsSchemas returns the settings of every provider type by type name.
+func SettingsSchemas() map[string]model.SettingsSchema {
+	schemas := make(map[string]model.SettingsSchema, len(types))
+	for name, providerType := range types {
+		schemas[name] = providerType.settings
+	}
+	return schemas
 }
 
-func InitializeProviders(backupConfig *model.BackupConfig, monitor *fsmonitor.Monitor) map[string]model.Provider {
+// InitializeProviders creates the configured providers, subscribes their
+// directories and registers them with the health checker, which checks them
+// right away.
+func InitializeProviders(backupConfig *model.BackupConfig, monitor *fsmonitor.Monitor, checker *health.Checker, resolver *secrets.Resolver) map[string]model.Provider {
 	providers := make(map[string]model.Provider)
 
 	for _, providerConfig := range backupConfig.Providers {
-		provider, err := NewProvider(&providerConfig)
+		provider, err := NewProvider(&providerConfig, resolver)
 		if err != nil {
 			slog.Error("Error initializing provider", "error", err, "provider", providerConfig.Name)
 			continue
@@ -36,7 +68,16 @@ func InitializeProviders(backupConfig *model.BackupConfig, monitor *fsmonitor.Mo
 
 		// add the subscription
 		for _, dir := range provider.DirectoryList() {
-			monitor.Add(dir)
+			if err := monitor.AddWithOptions(dir, backupConfig.Directories[dir]); err != nil {
+				slog.Error("Error adding directory to monitor", "error", err, "dir", dir)
+			}
+		}
+
+		status := checker.Add(provider)
+		if status.Healthy {
+			slog.Info("Provider ready", "provider", provider.Name(), "capabilities", status.Capabilities)
+		} else {
+			slog.Warn("Provider unhealthy, backups are paused until it recovers", "provider", provider.Name(), "error", status.Error)
 		}
 
 		providers[provider.Name()] = provider
@@ -0,0 +1,183 @@
+// Package quota limits how much data each provider may store.
+package quota
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// ErrExceeded is returned when a backup would exceed a quota or the free
+// space of the destination.
+var ErrExceeded = errors.New("quota exceeded")
+
+var defaultWarnAt = []float64{80, 90, 95}
+
+// Manager holds the quotas of all providers.
+type Manager struct {
+	mu     sync.Mutex
+	quotas map[string]model.QuotaConfig
+	warned map[string]float64 // Highest threshold already warned about per provider or directory
+}
+
+// NewManager reads the quotas of the providers.
+func NewManager(backupConfig *model.BackupConfig) (*Manager, error) {
+	m := &Manager{quotas: map[string]model.QuotaConfig{}, warned: map[string]float64{}}
+
+	for _, providerConfig := range backupConfig.Providers {
+		if providerConfig.Quota == nil {
+			continue
+		}
+		if err := Validate(*providerConfig.Quota); err != nil {
+			return nil, fmt.Errorf("invalid quota for provider %q: %w", providerConfig.Name, err)
+		}
+		m.quotas[providerConfig.Name] = *providerConfig.Quota
+	}
+
+	return m, nil
+}
+
+// ApplyConfig replaces the quotas with those of a changed configuration.
+func (m *Manager) ApplyConfig(backupConfig *model.BackupConfig) {
+	quotas := map[string]model.QuotaConfig{}
+	for _, providerConfig := range backupConfig.Providers {
+		if providerConfig.Quota == nil {
+			continue
+		}
+		if err := Validate(*providerConfig.Quota); err != nil {
+			slog.Error("[quota] ignoring invalid quota", "providerName", providerConfig.Name, "error", err)
+			continue
+		}
+		quotas[providerConfig.Name] = *providerConfig.Quota
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.quotas = quotas
+}
+
+// Validate checks a quota configuration.
+func Validate(cfg model.QuotaConfig) error {
+	for _, threshold := range cfg.WarnAt {
+		if threshold <= 0 || threshold > 100 {
+			return fmt.Errorf("warning threshold %v must be between 0 and 100", threshold)
+		}
+	}
+
+	switch cfg.Action {
+	case "", model.QuotaRefuse, model.QuotaDefer:
+		return nil
+	default:
+		return fmt.Errorf("unknown quota action %q", cfg.Action)
+	}
+}
+
+// Check returns an error wrapping ErrExceeded if storing size more bytes in
+// root would exceed a quota of the provider or leave less than the reserved
+// free space. usage and free may be nil if unknown. A nil Manager only
+// checks the free space.
+func (m *Manager) Check(provider, root string, usage *model.ProviderMetaInfo, size uint64, free *uint64) error {
+	cfg := m.quota(provider)
+
+	if usage != nil {
+		if cfg.MaxBytes > 0 {
+			if total := totalUsage(usage); total+size > cfg.MaxBytes {
+				return fmt.Errorf("%w: provider %q would store %d of %d bytes", ErrExceeded, provider, total+size, cfg.MaxBytes)
+			}
+		}
+
+		if limit, ok := cfg.Directories[root]; ok && usage.Directories[root]+size > limit {
+			return fmt.Errorf("%w: %s on provider %q would use %d of %d bytes", ErrExceeded, root, provider, usage.Directories[root]+size, limit)
+		}
+	}
+
+	if free != nil && size+cfg.MinFreeBytes > *free {
+		return fmt.Errorf("%w: provider %q has %d bytes free, %d needed and %d reserved", ErrExceeded, provider, *free, size, cfg.MinFreeBytes)
+	}
+
+	return nil
+}
+
+// Action returns what to do with backups of the provider that exceed a quota.
+func (m *Manager) Action(provider string) model.QuotaAction {
+	if action := m.quota(provider).Action; action != "" {
+		return action
+	}
+	return model.QuotaRefuse
+}
+
+// Warn logs a warning once the usage of the provider or one of its
+// directories crosses a threshold, and again for every higher threshold.
+func (m *Manager) Warn(provider string, usage *model.ProviderMetaInfo) {
+	if m == nil || usage == nil {
+		return
+	}
+
+	cfg := m.quota(provider)
+	thresholds := cfg.WarnAt
+	if len(thresholds) == 0 {
+		thresholds = defaultWarnAt
+	}
+
+	if cfg.MaxBytes > 0 {
+		m.warn(provider, "", totalUsage(usage), cfg.MaxBytes, thresholds)
+	}
+	for root, limit := range cfg.Directories {
+		m.warn(provider, root, usage.Directories[root], limit, thresholds)
+	}
+}
+
+func (m *Manager) warn(provider, root string, used, limit uint64, thresholds []float64) {
+	if limit == 0 {
+		return
+	}
+
+	percent := float64(used) / float64(limit) * 100
+	reached := 0.0
+	for _, threshold := range thresholds {
+		if percent >= threshold && threshold > reached {
+			reached = threshold
+		}
+	}
+
+	key := provider + ":" + root
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if reached <= m.warned[key] {
+		// Usage dropped below the threshold, e.g. after raising the limit
+		if reached < m.warned[key] {
+			m.warned[key] = reached
+		}
+		return
+	}
+	m.warned[key] = reached
+
+	if root == "" {
+		slog.Warn("[quota] provider quota almost used up", "providerName", provider, "percent", percent, "used", used, "limit", limit)
+	} else {
+		slog.Warn("[quota] directory quota almost used up", "providerName", provider, "root", root, "percent", percent, "used", used, "limit", limit)
+	}
+}
+
+func (m *Manager) quota(provider string) model.QuotaConfig {
+	if m == nil {
+		return model.QuotaConfig{}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.quotas[provider]
+}
+
+func totalUsage(usage *model.ProviderMetaInfo) uint64 {
+	var total uint64
+	for _, size := range usage.Directories {
+		total += size
+	}
+	return total
+}
@@ -0,0 +1,64 @@
+package quota
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+func TestCheck(t *testing.T) {
+	m, err := NewManager(&model.BackupConfig{Providers: []model.ProviderConfig{{
+		Name: "Local",
+		Quota: &model.QuotaConfig{
+			MaxBytes:     1000,
+			Directories:  map[string]uint64{"/docs": 100},
+			MinFreeBytes: 50,
+			Action:       model.QuotaDefer,
+		},
+	}}})
+	assert.NoError(t, err)
+
+	usage := &model.ProviderMetaInfo{Directories: map[string]uint64{"/docs": 90, "/photos": 800}}
+	free := uint64(200)
+
+	assert.NoError(t, m.Check("Local", "/photos", usage, 100, &free))
+	assert.ErrorIs(t, m.Check("Local", "/photos", usage, 200, &free), ErrExceeded)
+	assert.ErrorIs(t, m.Check("Local", "/docs", usage, 20, &free), ErrExceeded)
+	assert.ErrorIs(t, m.Check("Local", "/photos", usage, 100, ptr(100)), ErrExceeded)
+
+	// Providers without quota are only limited by their free space
+	assert.NoError(t, m.Check("Other", "/photos", usage, 1<<40, nil))
+	assert.ErrorIs(t, m.Check("Other", "/photos", usage, 300, &free), ErrExceeded)
+
+	assert.Equal(t, model.QuotaDefer, m.Action("Local"))
+	assert.Equal(t, model.QuotaRefuse, m.Action("Other"))
+}
+
+func TestWarnOncePerThreshold(t *testing.T) {
+	m, err := NewManager(&model.BackupConfig{Providers: []model.ProviderConfig{{
+		Name:  "Local",
+		Quota: &model.QuotaConfig{MaxBytes: 100, WarnAt: []float64{50, 90}},
+	}}})
+	assert.NoError(t, err)
+
+	m.Warn("Local", &model.ProviderMetaInfo{Directories: map[string]uint64{"/": 60}})
+	assert.Equal(t, 50.0, m.warned["Local:"])
+
+	m.Warn("Local", &model.ProviderMetaInfo{Directories: map[string]uint64{"/": 95}})
+	assert.Equal(t, 90.0, m.warned["Local:"])
+
+	m.Warn("Local", &model.ProviderMetaInfo{Directories: map[string]uint64{"/": 10}})
+	assert.Equal(t, 0.0, m.warned["Local:"])
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(model.QuotaConfig{WarnAt: []float64{80}, Action: model.QuotaDefer}))
+	assert.Error(t, Validate(model.QuotaConfig{WarnAt: []float64{120}}))
+	assert.Error(t, Validate(model.QuotaConfig{Action: "drop"}))
+}
+
+func ptr(v uint64) *uint64 {
+	return &v
+}
@@ -0,0 +1,207 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/sevigo/shugosha/pkg/logger"
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+const keystoreVersion = 1
+
+// scrypt parameters recommended for interactive logins
+const (
+	scryptN  = 1 << 15
+	scryptR  = 8
+	scryptP  = 1
+	keySize  = 32
+	saltSize = 16
+)
+
+var (
+	// ErrWrongPassphrase is returned when the keystore cannot be decrypted.
+	ErrWrongPassphrase = errors.New("wrong keystore passphrase")
+	// ErrNotFound is returned for secrets that are not in the keystore.
+	ErrNotFound = errors.New("secret not found")
+)
+
+// Ensure Keystore satisfies the SecretStore interface
+var _ model.SecretStore = (*Keystore)(nil)
+
+// keystoreFile is the encrypted form of the keystore on disk.
+type keystoreFile struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"` // AES-256-GCM encrypted JSON object of the secrets
+}
+
+// Keystore holds named secrets in a file encrypted with a key derived from
+// the master passphrase.
+type Keystore struct {
+	mu      sync.Mutex
+	path    string
+	salt    []byte
+	aead    cipher.AEAD
+	secrets map[string]string
+}
+
+// OpenKeystore decrypts the keystore at path, creating an empty one if the
+// file does not exist yet.
+func OpenKeystore(path, passphrase string) (*Keystore, error) {
+	if passphrase == "" {
+		return nil, ErrLocked
+	}
+
+	var file keystoreFile
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		file.Salt = make([]byte, saltSize)
+		if _, err := rand.Read(file.Salt); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, fmt.Errorf("failed to read keystore: %w", err)
+	default:
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse keystore: %w", err)
+		}
+		if file.Version > keystoreVersion {
+			return nil, fmt.Errorf("keystore version %d is newer than the supported version %d", file.Version, keystoreVersion)
+		}
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), file.Salt, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	k := &Keystore{path: path, salt: file.Salt, aead: aead, secrets: map[string]string{}}
+	if file.Data == nil {
+		return k, nil
+	}
+
+	plain, err := aead.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	if err := json.Unmarshal(plain, &k.secrets); err != nil {
+		return nil, fmt.Errorf("failed to parse keystore secrets: %w", err)
+	}
+	return k, nil
+}
+
+// Get returns a secret by name.
+func (k *Keystore) Get(name string) (string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	secret, ok := k.secrets[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrNotFound, name)
+	}
+	return secret, nil
+}
+
+// Names lists the names of all secrets in the keystore.
+func (k *Keystore) Names() []string {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	names := make([]string, 0, len(k.secrets))
+	for name := range k.secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Set stores a secret and writes the keystore.
+func (k *Keystore) Set(name, secret string) error {
+	if name == "" {
+		return fmt.Errorf("secret name is required")
+	}
+
+	logger.Redact(secret)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.secrets[name] = secret
+	return k.save()
+}
+
+// Delete removes a secret and writes the keystore.
+func (k *Keystore) Delete(name string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if _, ok := k.secrets[name]; !ok {
+		return fmt.Errorf("%w: %q", ErrNotFound, name)
+	}
+	delete(k.secrets, name)
+	return k.save()
+}
+
+// save encrypts the secrets with a new nonce and replaces the keystore file,
+// which is only readable by the owner.
+func (k *Keystore) save() error {
+	plain, err := json.Marshal(k.secrets)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, k.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(keystoreFile{
+		Version: keystoreVersion,
+		Salt:    k.salt,
+		Nonce:   nonce,
+		Data:    k.aead.Seal(nil, nonce, plain, nil),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(k.path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(k.path), ".shugosha-keystore-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), k.path)
+}
@@ -0,0 +1,200 @@
+package secrets
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sevigo/shugosha/pkg/logger"
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// Redacted replaces secret values in API responses. Saving a configuration
+// with this value keeps the stored secret.
+const Redacted = logger.Redacted
+
+// sensitiveName matches setting names that hold credentials, for settings
+// not declared as secret by their provider type.
+var sensitiveName = regexp.MustCompile(`(?i)(password|passphrase|secret|token|credential|private|access_?key|api_?key)`)
+
+// Ensure Redactor satisfies the ConfigRedactor interface
+var _ model.ConfigRedactor = (*Redactor)(nil)
+
+// Redactor hides the credentials in provider settings and in the api section.
+type Redactor struct {
+	types map[string]model.SettingsSchema
+}
+
+// NewRedactor creates a redactor for the settings of the given provider types.
+func NewRedactor(types map[string]model.SettingsSchema) *Redactor {
+	return &Redactor{types: types}
+}
+
+// Sensitive reports whether a setting of a provider type holds a credential.
+func (r *Redactor) Sensitive(providerType, key string) bool {
+	if r.types[providerType][key].Secret {
+		return true
+	}
+	return sensitiveName.MatchString(key)
+}
+
+// RedactConfig returns a copy of config with the values of all sensitive
+// settings replaced by Redacted. References to secrets are kept, they do not
+// reveal the secret.
+func (r *Redactor) RedactConfig(config *model.BackupConfig) *model.BackupConfig {
+	if config == nil {
+		return nil
+	}
+
+	redacted := *config
+	redacted.Providers = make([]model.ProviderConfig, len(config.Providers))
+	for i, provider := range config.Providers {
+		if provider.Settings != nil {
+			settings := make(map[string]string, len(provider.Settings))
+			for key, value := range provider.Settings {
+				if r.Sensitive(provider.Type, key) {
+					value = redactString(value)
+				}
+				settings[key] = value
+			}
+			provider.Settings = settings
+		}
+		redacted.Providers[i] = provider
+	}
+
+	if config.API != nil {
+		api := *config.API
+		api.Tokens = make([]model.APIToken, len(config.API.Tokens))
+		for i, token := range config.API.Tokens {
+			token.Token = redactString(token.Token)
+			api.Tokens[i] = token
+		}
+		api.Users = make([]model.APIUser, len(config.API.Users))
+		for i, user := range config.API.Users {
+			user.PasswordHash = redactString(user.PasswordHash)
+			api.Users[i] = user
+		}
+		redacted.API = &api
+	}
+	return &redacted
+}
+
+// RedactRevision returns a copy of revision with a redacted configuration.
+func (r *Redactor) RedactRevision(revision *model.ConfigRevision) *model.ConfigRevision {
+	redacted := *revision
+	redacted.Config = r.RedactConfig(revision.Config)
+	return &redacted
+}
+
+// RedactChanges redacts the old and new values of changed settings that are
+// sensitive for any provider type.
+func (r *Redactor) RedactChanges(changes []model.ConfigChange) []model.ConfigChange {
+	redacted := make([]model.ConfigChange, len(changes))
+	for i, change := range changes {
+		change.Old = r.redactPath(change.Path, change.Old)
+		change.New = r.redactPath(change.Path, change.New)
+		redacted[i] = change
+	}
+	return redacted
+}
+
+// RestoreRedacted replaces Redacted setting values in config with the values
+// stored in current for the provider of the same name, so that a redacted
+// configuration read from the API can be saved again.
+func (r *Redactor) RestoreRedacted(config, current *model.BackupConfig) {
+	if current == nil {
+		return
+	}
+
+	stored := make(map[string]map[string]string, len(current.Providers))
+	for _, provider := range current.Providers {
+		stored[provider.Name] = provider.Settings
+	}
+
+	for _, provider := range config.Providers {
+		for key, value := range provider.Settings {
+			if value == Redacted {
+				provider.Settings[key] = stored[provider.Name][key]
+			}
+		}
+	}
+
+	if config.API == nil || current.API == nil {
+		return
+	}
+	for i, token := range config.API.Tokens {
+		for _, storedToken := range current.API.Tokens {
+			if token.Token == Redacted && token.Name == storedToken.Name {
+				config.API.Tokens[i].Token = storedToken.Token
+			}
+		}
+	}
+	for i, user := range config.API.Users {
+		for _, storedUser := range current.API.Users {
+			if user.PasswordHash == Redacted && user.Username == storedUser.Username {
+				config.API.Users[i].PasswordHash = storedUser.PasswordHash
+			}
+		}
+	}
+}
+
+func (r *Redactor) sensitiveForAny(key string) bool {
+	for providerType := range r.types {
+		if r.Sensitive(providerType, key) {
+			return true
+		}
+	}
+	return sensitiveName.MatchString(key)
+}
+
+// redactPath redacts a value of the generic JSON form of a configuration,
+// found at path, including all settings nested in it.
+func (r *Redactor) redactPath(path string, value any) any {
+	if key, ok := settingKey(path); ok {
+		if r.sensitiveForAny(key) {
+			return redactValue(value)
+		}
+		return value
+	}
+	if s, ok := value.(string); ok && strings.HasPrefix(path, "api.") && sensitiveName.MatchString(path[strings.LastIndex(path, ".")+1:]) {
+		return redactString(s)
+	}
+
+	switch value := value.(type) {
+	case map[string]any:
+		redacted := make(map[string]any, len(value))
+		for key, item := range value {
+			redacted[key] = r.redactPath(path+"."+key, item)
+		}
+		return redacted
+	case []any:
+		redacted := make([]any, len(value))
+		for i, item := range value {
+			redacted[i] = r.redactPath(fmt.Sprintf("%s[%d]", path, i), item)
+		}
+		return redacted
+	default:
+		return value
+	}
+}
+
+func redactValue(value any) any {
+	if s, ok := value.(string); ok {
+		return redactString(s)
+	}
+	return value
+}
+
+// redactString hides a literal secret, keeping empty values and references.
+func redactString(s string) string {
+	if s != "" && !IsReference(s) {
+		return Redacted
+	}
+	return s
+}
+
+// settingKey returns the setting name of a path like providers[0].settings.key.
+func settingKey(path string) (string, bool) {
+	_, key, ok := strings.Cut(path, ".settings.")
+	return key, ok && strings.HasPrefix(path, "providers[")
+}
@@ -0,0 +1,125 @@
+// Package secrets resolves references to credentials kept outside the
+// configuration and hides credentials in API responses and logs.
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sevigo/shugosha/pkg/logger"
+)
+
+// Prefixes of secret references in provider settings.
+const (
+	EnvPrefix      = "env:"      // env:NAME reads the environment variable NAME
+	FilePrefix     = "file:"     // file:/path reads the file, without a trailing newline
+	KeystorePrefix = "keystore:" // keystore:NAME reads NAME from the local keystore
+)
+
+// ErrLocked is returned when reading the keystore without a passphrase.
+var ErrLocked = errors.New("keystore is locked, set the master passphrase")
+
+// IsReference reports whether a setting value refers to a secret, either by
+// one of the prefixes or by ${NAME} environment variables in the value.
+func IsReference(value string) bool {
+	return HasVariables(value) ||
+		strings.HasPrefix(value, EnvPrefix) ||
+		strings.HasPrefix(value, FilePrefix) ||
+		strings.HasPrefix(value, KeystorePrefix)
+}
+
+// ValidateReference checks the syntax of a secret reference.
+func ValidateReference(value string) error {
+	if HasVariables(value) {
+		return nil
+	}
+
+	prefix, name, _ := strings.Cut(value, ":")
+	if name == "" {
+		return fmt.Errorf("%s reference without a name", prefix)
+	}
+	if prefix == strings.TrimSuffix(FilePrefix, ":") && !isAbs(name) {
+		return fmt.Errorf("file reference %q must be an absolute path", name)
+	}
+	return nil
+}
+
+// Resolver replaces secret references with the secrets they refer to.
+type Resolver struct {
+	keystore *Keystore
+}
+
+// NewResolver creates a resolver reading keystore references from keystore,
+// which may be nil if there is no keystore.
+func NewResolver(keystore *Keystore) *Resolver {
+	return &Resolver{keystore: keystore}
+}
+
+// Resolve returns the secret a reference refers to, and any other value
+// unchanged. Resolved secrets are redacted from the log.
+func (r *Resolver) Resolve(value string) (string, error) {
+	var secret string
+
+	switch {
+	case HasVariables(value):
+		// Only the variables are secret, not the text around them
+		resolved, err := expandVariables(value, logger.Redact)
+		if err != nil {
+			return "", err
+		}
+		return resolved, nil
+
+	case strings.HasPrefix(value, EnvPrefix):
+		name := strings.TrimPrefix(value, EnvPrefix)
+		env, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %q is not set", name)
+		}
+		secret = env
+
+	case strings.HasPrefix(value, FilePrefix):
+		data, err := os.ReadFile(strings.TrimPrefix(value, FilePrefix))
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		secret = strings.TrimRight(string(data), "\r\n")
+
+	case strings.HasPrefix(value, KeystorePrefix):
+		name := strings.TrimPrefix(value, KeystorePrefix)
+		if r == nil || r.keystore == nil {
+			return "", ErrLocked
+		}
+		stored, err := r.keystore.Get(name)
+		if err != nil {
+			return "", err
+		}
+		secret = stored
+
+	default:
+		return value, nil
+	}
+
+	logger.Redact(secret)
+	return secret, nil
+}
+
+// ResolveSettings returns a copy of the settings with all references resolved.
+func (r *Resolver) ResolveSettings(settings map[string]string) (map[string]string, error) {
+	resolved := make(map[string]string, len(settings))
+	for key, value := range settings {
+		secret, err := r.Resolve(value)
+		if err != nil {
+			return nil, fmt.Errorf("setting %q: %w", key, err)
+		}
+		resolved[key] = secret
+	}
+	return resolved, nil
+}
+
+// isAbs accepts absolute paths of any platform, the config may be written on
+// another system than the one running the service.
+func isAbs(path string) bool {
+	return strings.HasPrefix(path, "/") || strings.HasPrefix(path, `\`) || (len(path) > 2 && path[1] == ':')
+}
@@ -0,0 +1,122 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sevigo/shugosha/pkg/logger"
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "secret")
+	assert.NoError(t, os.WriteFile(secretFile, []byte("from-file\n"), 0o600))
+	t.Setenv("SHUGOSHA_TEST_SECRET", "from-env")
+
+	keystore, err := OpenKeystore(filepath.Join(dir, "keystore.json"), "passphrase")
+	assert.NoError(t, err)
+	assert.NoError(t, keystore.Set("s3", "from-keystore"))
+
+	resolver := NewResolver(keystore)
+	settings, err := resolver.ResolveSettings(map[string]string{
+		"env":      "env:SHUGOSHA_TEST_SECRET",
+		"file":     "file:" + secretFile,
+		"keystore": "keystore:s3",
+		"variable": "user:${SHUGOSHA_TEST_SECRET}",
+		"plain":    "/backup",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"env":      "from-env",
+		"file":     "from-file",
+		"keystore": "from-keystore",
+		"variable": "user:from-env",
+		"plain":    "/backup",
+	}, settings)
+	assert.Equal(t, "key is "+logger.Redacted, logger.RedactString("key is from-env"))
+
+	_, err = resolver.Resolve("env:SHUGOSHA_TEST_UNSET")
+	assert.Error(t, err)
+	_, err = resolver.Resolve("${SHUGOSHA_TEST_UNSET}")
+	assert.Error(t, err)
+	_, err = resolver.Resolve("keystore:missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = NewResolver(nil).Resolve("keystore:s3")
+	assert.ErrorIs(t, err, ErrLocked)
+}
+
+func TestKeystore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keystore.json")
+
+	keystore, err := OpenKeystore(path, "passphrase")
+	assert.NoError(t, err)
+	assert.NoError(t, keystore.Set("a", "secret-a"))
+	assert.NoError(t, keystore.Set("b", "secret-b"))
+	assert.NoError(t, keystore.Delete("b"))
+	assert.ErrorIs(t, keystore.Delete("b"), ErrNotFound)
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "secret-a")
+
+	reopened, err := OpenKeystore(path, "passphrase")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a"}, reopened.Names())
+	secret, err := reopened.Get("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret-a", secret)
+
+	_, err = OpenKeystore(path, "wrong")
+	assert.ErrorIs(t, err, ErrWrongPassphrase)
+	_, err = OpenKeystore(path, "")
+	assert.ErrorIs(t, err, ErrLocked)
+}
+
+func TestRedactor(t *testing.T) {
+	redactor := NewRedactor(map[string]model.SettingsSchema{
+		"S3": {"bucket": {}, "key": {Secret: true}},
+	})
+
+	config := &model.BackupConfig{Providers: []model.ProviderConfig{{
+		Name: "S3",
+		Type: "S3",
+		Settings: map[string]string{
+			"bucket":    "backups",
+			"key":       "literal",
+			"password":  "env:S3_PASSWORD",
+			"apiToken":  "literal-token",
+			"emptyKey":  "",
+			"something": "visible",
+		},
+	}}}
+
+	redacted := redactor.RedactConfig(config)
+	assert.Equal(t, map[string]string{
+		"bucket":    "backups",
+		"key":       Redacted,
+		"password":  "env:S3_PASSWORD",
+		"apiToken":  Redacted,
+		"emptyKey":  "",
+		"something": "visible",
+	}, redacted.Providers[0].Settings)
+	assert.Equal(t, "literal", config.Providers[0].Settings["key"], "original config is unchanged")
+
+	// Saving the redacted config keeps the stored secrets
+	redactor.RestoreRedacted(redacted, config)
+	assert.Equal(t, config, redacted)
+
+	changes := redactor.RedactChanges([]model.ConfigChange{
+		{Path: "providers[0].settings.key", Old: "old", New: "new"},
+		{Path: "providers[0].settings.bucket", Old: "old", New: "new"},
+		{Path: "providers[1]", New: map[string]any{"name": "B", "settings": map[string]any{"key": "added"}}},
+	})
+	assert.Equal(t, []model.ConfigChange{
+		{Path: "providers[0].settings.key", Old: Redacted, New: Redacted},
+		{Path: "providers[0].settings.bucket", Old: "old", New: "new"},
+		{Path: "providers[1]", New: map[string]any{"name": "B", "settings": map[string]any{"key": Redacted}}},
+	}, changes)
+}
@@ -0,0 +1,51 @@
+package secrets
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// variablePattern matches ${NAME} and ${NAME:-default}, optionally escaped
+// as $${...}.
+var variablePattern = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// HasVariables reports whether s refers to environment variables as ${NAME}.
+func HasVariables(s string) bool {
+	return variablePattern.MatchString(s)
+}
+
+// ExpandVariables replaces ${NAME} with the value of the environment
+// variable, or the default given as ${NAME:-default}. Undefined variables
+// without a default are an error, so that missing credentials are noticed
+// early. $${NAME} is kept as the literal ${NAME}.
+func ExpandVariables(s string) (string, error) {
+	return expandVariables(s, func(string) {})
+}
+
+// expandVariables is ExpandVariables calling found with the value of every
+// variable it replaced.
+func expandVariables(s string, found func(value string)) (string, error) {
+	var err error
+	result := variablePattern.ReplaceAllStringFunc(s, func(match string) string {
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+
+		groups := variablePattern.FindStringSubmatch(match)
+		if value, ok := os.LookupEnv(groups[1]); ok {
+			found(value)
+			return value
+		}
+		if groups[2] != "" {
+			return groups[3]
+		}
+
+		if err == nil {
+			err = fmt.Errorf("environment variable %s is not defined", groups[1])
+		}
+		return match
+	})
+	return result, err
+}
@@ -0,0 +1,292 @@
+// Package throttle limits upload bandwidth and disk reads per provider.
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+// minBurst is the smallest burst, so that a single read is never larger
+// than what the limiter can grant at once.
+const minBurst = 64 << 10
+
+// Ensure Manager satisfies the ThrottleController interface
+var _ model.ThrottleController = (*Manager)(nil)
+
+// Manager holds the limiters of all providers.
+type Manager struct {
+	mu       sync.Mutex
+	limiters map[string]*Limiter
+}
+
+// NewManager creates limiters from the throttle settings of the providers.
+func NewManager(backupConfig *model.BackupConfig) (*Manager, error) {
+	m := &Manager{limiters: map[string]*Limiter{}}
+
+	for _, providerConfig := range backupConfig.Providers {
+		if providerConfig.Throttle == nil {
+			continue
+		}
+		if err := m.SetThrottle(providerConfig.Name, *providerConfig.Throttle); err != nil {
+			return nil, fmt.Errorf("invalid throttle for provider %q: %w", providerConfig.Name, err)
+		}
+	}
+
+	return m, nil
+}
+
+// ApplyConfig applies the throttle settings of a changed configuration.
+// Providers without throttle settings become unlimited.
+func (m *Manager) ApplyConfig(backupConfig *model.BackupConfig) {
+	configured := map[string]model.ThrottleConfig{}
+	for _, providerConfig := range backupConfig.Providers {
+		if providerConfig.Throttle != nil {
+			configured[providerConfig.Name] = *providerConfig.Throttle
+		}
+	}
+
+	m.mu.Lock()
+	names := make([]string, 0, len(m.limiters))
+	for name := range m.limiters {
+		names = append(names, name)
+	}
+	m.mu.Unlock()
+
+	for _, name := range names {
+		if _, ok := configured[name]; !ok {
+			m.Limiter(name).setConfig(model.ThrottleConfig{})
+		}
+	}
+	for name, cfg := range configured {
+		if err := m.SetThrottle(name, cfg); err != nil {
+			slog.Error("[throttle] ignoring invalid throttle", "providerName", name, "error", err)
+		}
+	}
+}
+
+// Limiter returns the limiter of the provider; providers without limits, or
+// a nil Manager, get an unlimited one.
+func (m *Manager) Limiter(provider string) *Limiter {
+	if m == nil {
+		return newLimiter(model.ThrottleConfig{})
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	limiter, ok := m.limiters[provider]
+	if !ok {
+		limiter = newLimiter(model.ThrottleConfig{})
+		m.limiters[provider] = limiter
+	}
+	return limiter
+}
+
+// SetThrottle replaces the limits of the provider, taking effect immediately.
+func (m *Manager) SetThrottle(provider string, cfg model.ThrottleConfig) error {
+	if err := Validate(cfg); err != nil {
+		return err
+	}
+
+	m.Limiter(provider).setConfig(cfg)
+	return nil
+}
+
+// Throttles returns the configured and effective limits of all providers.
+func (m *Manager) Throttles() map[string]model.ThrottleStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	status := make(map[string]model.ThrottleStatus, len(m.limiters))
+	for name, limiter := range m.limiters {
+		status[name] = limiter.status(now)
+	}
+	return status
+}
+
+// Validate checks the limits and the time windows of a throttle config.
+func Validate(cfg model.ThrottleConfig) error {
+	if cfg.UploadBytesPerSec < 0 || cfg.ReadBytesPerSec < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+
+	for i, window := range cfg.Schedule {
+		if _, err := time.Parse("15:04", window.Start); err != nil {
+			return fmt.Errorf("schedule[%d]: invalid start %q", i, window.Start)
+		}
+		if _, err := time.Parse("15:04", window.End); err != nil {
+			return fmt.Errorf("schedule[%d]: invalid end %q", i, window.End)
+		}
+		if window.UploadBytesPerSec < 0 || window.ReadBytesPerSec < 0 {
+			return fmt.Errorf("schedule[%d]: limits must not be negative", i)
+		}
+	}
+
+	return nil
+}
+
+// Limiter enforces the upload and read limits of a single provider.
+type Limiter struct {
+	mu     sync.Mutex
+	config model.ThrottleConfig
+	upload *rate.Limiter
+	read   *rate.Limiter
+}
+
+func newLimiter(cfg model.ThrottleConfig) *Limiter {
+	return &Limiter{
+		config: cfg,
+		upload: rate.NewLimiter(rate.Inf, minBurst),
+		read:   rate.NewLimiter(rate.Inf, minBurst),
+	}
+}
+
+func (l *Limiter) setConfig(cfg model.ThrottleConfig) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.config = cfg
+}
+
+// effective returns the limits that apply at the given time.
+func (l *Limiter) effective(now time.Time) (upload, read int64) {
+	upload, read = l.config.UploadBytesPerSec, l.config.ReadBytesPerSec
+
+	minutes := now.Hour()*60 + now.Minute()
+	for _, window := range l.config.Schedule {
+		if !inWindow(minutes, window) {
+			continue
+		}
+		// Limits the window leaves unset keep the limits of the config
+		if window.UploadBytesPerSec > 0 {
+			upload = window.UploadBytesPerSec
+		}
+		if window.ReadBytesPerSec > 0 {
+			read = window.ReadBytesPerSec
+		}
+		return upload, read
+	}
+
+	return upload, read
+}
+
+func (l *Limiter) status(now time.Time) model.ThrottleStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	upload, read := l.effective(now)
+	return model.ThrottleStatus{Config: l.config, UploadBytesPerSec: upload, ReadBytesPerSec: read}
+}
+
+// refresh applies the limits of the current time window to the rate limiters.
+func (l *Limiter) refresh() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	upload, read := l.effective(time.Now())
+	setRate(l.upload, upload)
+	setRate(l.read, read)
+}
+
+func setRate(limiter *rate.Limiter, bytesPerSec int64) {
+	limit, burst := rate.Inf, minBurst
+	if bytesPerSec > 0 {
+		limit, burst = rate.Limit(bytesPerSec), max(int(bytesPerSec), minBurst)
+	}
+
+	if limiter.Limit() != limit || limiter.Burst() != burst {
+		limiter.SetLimit(limit)
+		limiter.SetBurst(burst)
+	}
+}
+
+// Reader wraps a file reader whose content is uploaded with the read and
+// upload limits.
+func (l *Limiter) Reader(ctx context.Context, r io.Reader) io.Reader {
+	return &reader{ctx: ctx, r: r, limiter: l, upload: true}
+}
+
+// FileReader wraps a file reader with the read limit only, for reads that
+// are not uploaded such as hashing. A nil Limiter does not limit.
+func (l *Limiter) FileReader(ctx context.Context, r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+	return &reader{ctx: ctx, r: r, limiter: l}
+}
+
+func waitN(ctx context.Context, limiter *rate.Limiter, n int64) error {
+	if limiter.Limit() == rate.Inf {
+		return nil
+	}
+
+	for n > 0 {
+		chunk := min(n, int64(limiter.Burst()))
+		if err := limiter.WaitN(ctx, int(chunk)); err != nil {
+			return err
+		}
+		n -= chunk
+	}
+	return nil
+}
+
+type reader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *Limiter
+	upload  bool // Whether the upload limit applies as well
+}
+
+func (r *reader) Read(b []byte) (int, error) {
+	r.limiter.refresh()
+
+	limiters := []*rate.Limiter{r.limiter.read}
+	if r.upload {
+		limiters = append(limiters, r.limiter.upload)
+	}
+
+	// Never read more than the limiters can grant at once
+	for _, limiter := range limiters {
+		if limiter.Limit() != rate.Inf && len(b) > limiter.Burst() {
+			b = b[:limiter.Burst()]
+		}
+	}
+
+	n, err := r.r.Read(b)
+	if n > 0 {
+		for _, limiter := range limiters {
+			if waitErr := waitN(r.ctx, limiter, int64(n)); waitErr != nil {
+				return n, waitErr
+			}
+		}
+	}
+	return n, err
+}
+
+// inWindow reports whether the minute of the day falls into the window.
+func inWindow(minutes int, window model.ThrottleWindow) bool {
+	start, err := time.Parse("15:04", window.Start)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse("15:04", window.End)
+	if err != nil {
+		return false
+	}
+
+	from := start.Hour()*60 + start.Minute()
+	to := end.Hour()*60 + end.Minute()
+	if from <= to {
+		return minutes >= from && minutes < to
+	}
+	return minutes >= from || minutes < to
+}
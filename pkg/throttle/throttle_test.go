@@ -0,0 +1,68 @@
+package throttle
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sevigo/shugosha/pkg/model"
+)
+
+func TestEffectiveLimitsFollowSchedule(t *testing.T) {
+	limiter := newLimiter(model.ThrottleConfig{
+		UploadBytesPerSec: 0,
+		Schedule: []model.ThrottleWindow{
+			{Start: "09:00", End: "18:00", UploadBytesPerSec: 1 << 20},
+			{Start: "22:00", End: "02:00", ReadBytesPerSec: 512},
+		},
+	})
+
+	day := time.Date(2024, 1, 1, 10, 30, 0, 0, time.Local)
+	upload, read := limiter.effective(day)
+	assert.Equal(t, int64(1<<20), upload)
+	assert.Equal(t, int64(0), read)
+
+	night := time.Date(2024, 1, 1, 1, 0, 0, 0, time.Local)
+	upload, read = limiter.effective(night)
+	assert.Equal(t, int64(0), upload)
+	assert.Equal(t, int64(512), read)
+
+	evening := time.Date(2024, 1, 1, 19, 0, 0, 0, time.Local)
+	upload, _ = limiter.effective(evening)
+	assert.Equal(t, int64(0), upload)
+}
+
+func TestScheduleKeepsUnsetLimits(t *testing.T) {
+	limiter := newLimiter(model.ThrottleConfig{
+		UploadBytesPerSec: 1 << 20,
+		ReadBytesPerSec:   4 << 20,
+		Schedule:          []model.ThrottleWindow{{Start: "09:00", End: "18:00", UploadBytesPerSec: 256 << 10}},
+	})
+
+	upload, read := limiter.effective(time.Date(2024, 1, 1, 10, 30, 0, 0, time.Local))
+	assert.Equal(t, int64(256<<10), upload)
+	assert.Equal(t, int64(4<<20), read)
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(model.ThrottleConfig{UploadBytesPerSec: 10}))
+	assert.Error(t, Validate(model.ThrottleConfig{UploadBytesPerSec: -1}))
+	assert.Error(t, Validate(model.ThrottleConfig{Schedule: []model.ThrottleWindow{{Start: "9am", End: "18:00"}}}))
+}
+
+func TestReaderIsThrottled(t *testing.T) {
+	m := &Manager{limiters: map[string]*Limiter{}}
+	assert.NoError(t, m.SetThrottle("Test", model.ThrottleConfig{ReadBytesPerSec: minBurst * 4}))
+
+	// The first burst is free, the rest has to wait
+	data := make([]byte, minBurst*6)
+	start := time.Now()
+	n, err := io.Copy(io.Discard, m.Limiter("Test").Reader(context.Background(), bytes.NewReader(data)))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(data)), n)
+	assert.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+}